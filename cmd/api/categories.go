@@ -0,0 +1,159 @@
+package main
+
+import (
+	"ikm/models"
+	"ikm/utils"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// AdminCategories lists the gallery categories with forms to edit them
+func (app *Application) AdminCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := app.CategoryModel.GetAll()
+	if err != nil {
+		log.Printf("❌ Error fetching categories: %v", err)
+		http.Error(w, "Error fetching categories", http.StatusInternalServerError)
+		return
+	}
+
+	app.render(w, r, "admin/categories.html", map[string]interface{}{
+		"Title":      "Categories",
+		"Categories": categories,
+		"ActiveLink": "galleries",
+	})
+}
+
+func (app *Application) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSpace(r.FormValue("name"))
+	description := strings.TrimSpace(r.FormValue("description"))
+
+	if err := app.CategoryModel.Create(name, utils.Slugify(name), description); err != nil {
+		log.Printf("❌ Error creating category: %v", err)
+		http.Error(w, "Error creating category (is the name already in use?)", http.StatusBadRequest)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/categories", http.StatusSeeOther)
+}
+
+func (app *Application) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(r.FormValue("name"))
+	slug := utils.Slugify(r.FormValue("slug"))
+	if slug == "" {
+		slug = utils.Slugify(name)
+	}
+	position, _ := strconv.Atoi(r.FormValue("position"))
+
+	err = app.CategoryModel.Update(id, name, slug, strings.TrimSpace(r.FormValue("description")), position)
+	if err != nil {
+		log.Printf("❌ Error updating category %d: %v", id, err)
+		http.Error(w, "Error updating category (is the slug already in use?)", http.StatusBadRequest)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/categories", http.StatusSeeOther)
+}
+
+func (app *Application) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := app.CategoryModel.Delete(id); err != nil {
+		log.Printf("❌ Error deleting category %d: %v", id, err)
+		http.Error(w, "Error deleting category", http.StatusInternalServerError)
+		return
+	}
+
+	// HTMX: Remove the row without reloading
+	w.WriteHeader(http.StatusOK)
+}
+
+// GalleryCategories renders the category checkboxes of a gallery
+func (app *Application) GalleryCategories(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid gallery ID", http.StatusBadRequest)
+		return
+	}
+	app.renderGalleryCategories(w, id)
+}
+
+// SetGalleryCategories saves which categories a gallery is in
+func (app *Application) SetGalleryCategories(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid gallery ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	var categoryIDs []int
+	for _, v := range r.Form["category_id"] {
+		if cid, err := strconv.Atoi(v); err == nil {
+			categoryIDs = append(categoryIDs, cid)
+		}
+	}
+
+	if err := app.CategoryModel.SetGalleryCategories(id, categoryIDs); err != nil {
+		log.Printf("❌ Error setting categories for gallery %d: %v", id, err)
+		http.Error(w, "Error saving categories", http.StatusInternalServerError)
+		return
+	}
+
+	app.renderGalleryCategories(w, id)
+}
+
+func (app *Application) renderGalleryCategories(w http.ResponseWriter, galleryID int) {
+	categories, err := app.CategoryModel.GetAll()
+	if err != nil {
+		log.Printf("❌ Error fetching categories: %v", err)
+		http.Error(w, "Error fetching categories", http.StatusInternalServerError)
+		return
+	}
+
+	assigned, err := app.CategoryModel.GetForGallery(galleryID)
+	if err != nil {
+		log.Printf("❌ Error fetching gallery categories: %v", err)
+		http.Error(w, "Error fetching categories", http.StatusInternalServerError)
+		return
+	}
+
+	selected := make(map[int]bool, len(assigned))
+	for _, c := range assigned {
+		selected[c.ID] = true
+	}
+
+	app.renderPartialHTMX(w, "partials/gallery_categories.html", map[string]interface{}{
+		"GalleryID":  galleryID,
+		"Categories": categories,
+		"Selected":   selected,
+	})
+}
+
+// publicCategories keeps the categories that have something to show
+func publicCategories(categories []*models.Category) []*models.Category {
+	var visible []*models.Category
+	for _, c := range categories {
+		if c.PublicCount > 0 {
+			visible = append(visible, c)
+		}
+	}
+	return visible
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"ikm/models"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// GalleryParent renders the collection picker on the gallery edit page
+func (app *Application) GalleryParent(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid gallery ID", http.StatusBadRequest)
+		return
+	}
+	app.renderGalleryParent(w, id, "")
+}
+
+// SetGalleryParent moves a gallery into a collection, or out of one when no
+// parent is picked
+func (app *Application) SetGalleryParent(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid gallery ID", http.StatusBadRequest)
+		return
+	}
+
+	var parentID *int
+	if v := r.FormValue("parent_id"); v != "" {
+		pid, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid parent gallery ID", http.StatusBadRequest)
+			return
+		}
+		parentID = &pid
+	}
+
+	errMsg := ""
+	if err := app.GalleryModel.SetParent(id, parentID); errors.Is(err, models.ErrGalleryCycle) {
+		errMsg = "A gallery can't go inside itself or one of its own sub-galleries."
+	} else if err != nil {
+		log.Printf("❌ Error setting parent of gallery %d: %v", id, err)
+		errMsg = "Could not move the gallery."
+	}
+
+	app.renderGalleryParent(w, id, errMsg)
+}
+
+func (app *Application) renderGalleryParent(w http.ResponseWriter, galleryID int, errMsg string) {
+	gallery, err := app.GalleryModel.GetByID(galleryID)
+	if err != nil {
+		http.Error(w, "Gallery not found", http.StatusNotFound)
+		return
+	}
+
+	options, err := app.GalleryModel.GetParentOptions(galleryID)
+	if err != nil {
+		log.Printf("❌ Error fetching parent options: %v", err)
+		http.Error(w, "Error fetching galleries", http.StatusInternalServerError)
+		return
+	}
+
+	parentID := 0
+	if gallery.ParentID != nil {
+		parentID = *gallery.ParentID
+	}
+
+	app.renderPartialHTMX(w, "partials/gallery_parent.html", map[string]interface{}{
+		"GalleryID": galleryID,
+		"ParentID":  parentID,
+		"Options":   options,
+		"Error":     errMsg,
+	})
+}
+
+// collectionTrail turns a gallery's collections into breadcrumb entries.
+// Unpublished collections show as plain text since their pages 404.
+func collectionTrail(ancestors []*models.GalleryLink) []map[string]string {
+	trail := make([]map[string]string, 0, len(ancestors))
+	for _, a := range ancestors {
+		crumb := map[string]string{"Title": a.Title}
+		if a.Published {
+			crumb["URL"] = "/gallery/" + a.Slug
+		}
+		trail = append(trail, crumb)
+	}
+	return trail
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"errors"
+	"ikm/models"
+	"ikm/utils"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// An address gets at most autoReplyLimit auto-replies per autoReplyWindow,
+// so the contact form can't be used to flood someone else's inbox
+const (
+	autoReplyLimit  = 1
+	autoReplyWindow = 24 * time.Hour
+)
+
+// AdminContacts lists the contact inbox. ?status= narrows it to one status;
+// without it the list shows everything not archived or marked spam.
+func (app *Application) AdminContacts(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status != "" && !models.ValidContactStatus(status) {
+		status = ""
+	}
+
+	contacts, err := app.ContactModel.GetInbox(status)
+	if err != nil {
+		log.Printf("❌ Error fetching contacts: %v", err)
+		http.Error(w, "Error fetching contacts", http.StatusInternalServerError)
+		return
+	}
+
+	counts, err := app.ContactModel.StatusCounts()
+	if err != nil {
+		log.Printf("❌ Error counting contacts: %v", err)
+		http.Error(w, "Error fetching contacts", http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Title":      "Manage Contacts",
+		"Contacts":   contacts,
+		"Status":     status,
+		"Statuses":   models.ContactStatuses,
+		"Counts":     counts,
+		"ActiveLink": "contacts",
+	}
+
+	app.render(w, r, "admin/contacts.html", data)
+}
+
+// AdminContactView shows one contact with its replies, marking it read
+func (app *Application) AdminContactView(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid contact ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := app.ContactModel.MarkRead(id); err != nil {
+		log.Printf("❌ Error marking contact %d read: %v", id, err)
+	}
+
+	data, err := app.contactPanelData(id, "")
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	data["Title"] = "Contact"
+	data["ActiveLink"] = "contacts"
+
+	app.render(w, r, "admin/contact.html", data)
+}
+
+func (app *Application) SetContactStatus(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid contact ID", http.StatusBadRequest)
+		return
+	}
+
+	errMsg := ""
+	if err := app.ContactModel.SetStatus(id, r.FormValue("status")); err != nil {
+		log.Printf("❌ Error setting status of contact %d: %v", id, err)
+		errMsg = "Could not change the status."
+	}
+	app.renderContactPanel(w, r, id, errMsg)
+}
+
+func (app *Application) AssignContact(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid contact ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := strconv.Atoi(r.FormValue("user_id"))
+	errMsg := ""
+	if err := app.ContactModel.Assign(id, userID); err != nil {
+		log.Printf("❌ Error assigning contact %d: %v", id, err)
+		errMsg = "Could not assign the contact."
+	}
+	app.renderContactPanel(w, r, id, errMsg)
+}
+
+func (app *Application) UpdateContactNotes(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid contact ID", http.StatusBadRequest)
+		return
+	}
+
+	errMsg := ""
+	if err := app.ContactModel.SetNotes(id, strings.TrimSpace(r.FormValue("notes"))); err != nil {
+		log.Printf("❌ Error saving notes on contact %d: %v", id, err)
+		errMsg = "Could not save the notes."
+	}
+	app.renderContactPanel(w, r, id, errMsg)
+}
+
+// ReplyContact queues a reply email to the enquirer and threads it under
+// the contact. The thread shows whether the email has gone out yet.
+func (app *Application) ReplyContact(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid contact ID", http.StatusBadRequest)
+		return
+	}
+
+	contact, err := app.ContactModel.GetByID(id)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	subject := strings.TrimSpace(r.FormValue("subject"))
+	if subject == "" {
+		subject = replySubject(contact)
+	}
+	body := strings.TrimSpace(r.FormValue("body"))
+	if body == "" {
+		app.renderContactPanel(w, r, id, "Write a reply before sending.")
+		return
+	}
+
+	emailID, err := app.queueEmail(
+		os.Getenv("CONTACT_EMAIL"),
+		contact.Email,
+		subject,
+		"contact_reply",
+		map[string]interface{}{"Contact": contact, "Body": body},
+	)
+	if err != nil {
+		log.Printf("❌ Error queueing reply to contact %d: %v", id, err)
+		app.renderContactPanel(w, r, id, "The reply could not be sent. Please try again.")
+		return
+	}
+
+	userID, _ := GetSession(r)
+	if _, err := app.ContactModel.AddReply(id, userID, emailID, subject, body); err != nil {
+		log.Printf("❌ Error recording reply to contact %d: %v", id, err)
+		app.renderContactPanel(w, r, id, "The reply was queued but could not be saved.")
+		return
+	}
+
+	log.Printf("✅ Reply to contact %d (%s) queued", id, contact.Email)
+	app.renderContactPanel(w, r, id, "")
+}
+
+// queueAutoReply queues the auto-reply confirmation to someone who just
+// sent the contact form. Failures are only logged; the enquiry is saved.
+func (app *Application) queueAutoReply(form models.ContactForm, settings map[string]string) {
+	reply := utils.AutoReplyFromSettings(settings)
+	if reply == nil {
+		return
+	}
+
+	subject, body := reply.Fill(form.FirstName, form.Subject)
+	html, text, err := app.Emails.Render("auto_reply", map[string]string{"Body": body})
+	if err != nil {
+		log.Printf("❌ Error rendering auto-reply: %v", err)
+		return
+	}
+
+	_, err = app.OutboxModel.EnqueueLimited("auto_reply", os.Getenv("CONTACT_EMAIL"), form.Email,
+		subject, html, text, autoReplyLimit, autoReplyWindow)
+	if errors.Is(err, models.ErrRateLimited) {
+		log.Printf("⚠️ Skipped auto-reply to %s: already sent one recently", form.Email)
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Error queueing auto-reply to %s: %v", form.Email, err)
+		return
+	}
+	app.wakeOutbox()
+}
+
+func (app *Application) renderContactPanel(w http.ResponseWriter, r *http.Request, id int, errMsg string) {
+	data, err := app.contactPanelData(id, errMsg)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	app.renderPartialHTMX(w, "partials/contact_panel.html", data)
+}
+
+func (app *Application) contactPanelData(id int, errMsg string) (map[string]interface{}, error) {
+	contact, err := app.ContactModel.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := app.ContactModel.GetMessages(id)
+	if err != nil {
+		log.Printf("❌ Error fetching replies to contact %d: %v", id, err)
+	}
+	users, err := app.UserModel.GetAll()
+	if err != nil {
+		log.Printf("❌ Error fetching users: %v", err)
+	}
+
+	return map[string]interface{}{
+		"Contact":      contact,
+		"Messages":     messages,
+		"Users":        users,
+		"Statuses":     models.ContactStatuses,
+		"ReplySubject": replySubject(contact),
+		"Error":        errMsg,
+	}, nil
+}
+
+func replySubject(c *models.Contact) string {
+	if c.Subject == "" {
+		return "Re: your enquiry"
+	}
+	if strings.HasPrefix(strings.ToLower(c.Subject), "re:") {
+		return c.Subject
+	}
+	return "Re: " + c.Subject
+}
@@ -8,11 +8,9 @@ import (
 	"fmt"
 	"ikm/models"
 	"ikm/utils"
-	"io"
 	"log"
 	"math"
 	"net/http"
-	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -20,25 +18,33 @@ import (
 
 	"github.com/disintegration/imaging"
 	"github.com/go-chi/chi/v5"
-	"github.com/jackc/pgx/v5"
 	"github.com/minio/minio-go/v7"
 )
 
 // Home Page Handler
 
 func (app *Application) Home(w http.ResponseWriter, r *http.Request) {
-	gallery, media, err := app.GalleryModel.GetByTitle("Home")
-
-	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
-		log.Printf("❌ Error fetching featured gallery: %v", err)
+	sections, err := app.SettingsModel.GetHomeSections()
+	if err != nil {
+		log.Printf("❌ Error loading home sections, using defaults: %v", err)
+		sections = models.DefaultHomeSections()
 	}
 
+	blocks := app.homeBlocks(sections)
+
 	data := map[string]interface{}{
-		"Title":       "Home",
-		"Gallery":     gallery,
-		"Media":       media,
-		"ActiveLink":  "home",
-		"Description": gallery.Description,
+		"Title":      "Home",
+		"Sections":   blocks,
+		"ActiveLink": "home",
+	}
+
+	// The hero gallery describes the page for search and sharing
+	for _, b := range blocks {
+		if gallery, ok := b["Gallery"].(*models.Gallery); ok {
+			data["Description"] = gallery.Description
+			data["OGImage"] = gallery.CoverImageURL
+			break
+		}
 	}
 
 	app.render(w, r, "index.html", data)
@@ -315,17 +321,61 @@ func (app *Application) UpdateSettings(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	// Handle optional watermark logo upload. The logo is kept private; it is
+	// only ever read back by the upload pipeline.
+	logoFile, logoHeader, err := r.FormFile("watermark_logo")
+	if err == nil {
+		defer logoFile.Close()
+
+		if _, err := imaging.Decode(logoFile); err != nil {
+			log.Printf("❌ Failed to decode watermark logo: %v", err)
+			http.Error(w, "Invalid image format", http.StatusBadRequest)
+			return
+		}
+		logoFile.Seek(0, 0)
+
+		objectName := fmt.Sprintf("settings/watermark_logo_%d_%s", time.Now().UnixNano(), logoHeader.Filename)
+		_, err = app.S3Client.PutObject(r.Context(), app.S3Bucket, objectName, logoFile, logoHeader.Size, minio.PutObjectOptions{
+			ContentType: logoHeader.Header.Get("Content-Type"),
+		})
+		if err != nil {
+			log.Printf("❌ Failed to upload watermark logo to S3: %v", err)
+			http.Error(w, "S3 upload failed", http.StatusInternalServerError)
+			return
+		}
+
+		if err := app.SettingsModel.Set("watermark_logo_key", objectName); err != nil {
+			log.Printf("❌ Failed to save setting: %v", err)
+			http.Error(w, "Error saving setting", http.StatusInternalServerError)
+			return
+		}
+	}
+
 	http.Redirect(w, r, "/admin/settings", http.StatusSeeOther)
 }
 
 func (app *Application) PublicProjectsList(w http.ResponseWriter, r *http.Request) {
-	projects, err := app.ProjectModel.GetAllPublic()
+	q := r.URL.Query()
+	filter := models.ProjectFilter{
+		Client:  q.Get("client"),
+		Service: q.Get("service"),
+	}
+	filter.Year, _ = strconv.Atoi(q.Get("year"))
+
+	projects, err := app.ProjectModel.GetPublicFiltered(filter)
 	if err != nil {
 		log.Printf("❌ Error fetching public projects: %v", err)
 		http.Error(w, "Unable to load projects", http.StatusInternalServerError)
 		return
 	}
 
+	filters, err := app.ProjectModel.GetFilterOptions()
+	if err != nil {
+		log.Printf("❌ Error fetching project filters: %v", err)
+		http.Error(w, "Unable to load projects", http.StatusInternalServerError)
+		return
+	}
+
 	log.Printf("Project Publics: %v", projects)
 
 	description := "Browse a curated selection of creative projects focused on commercial branding, fashion campaigns, and portrait storytelling. Based in Canberra, each project blends artistic direction with strategic visual identity—crafted for brands, designers, and individuals seeking bold, polished imagery."
@@ -333,6 +383,8 @@ func (app *Application) PublicProjectsList(w http.ResponseWriter, r *http.Reques
 	data := map[string]interface{}{
 		"Title":        "Projects",
 		"Projects":     projects,
+		"Filter":       filter,
+		"Filters":      filters,
 		"CanonicalURL": utils.BuildCanonicalURL(r, "/projects"),
 		"ActiveLink":   "projects",
 		"Description":  description,
@@ -364,11 +416,21 @@ func (app *Application) PublicProjectView(w http.ResponseWriter, r *http.Request
 
 	project, err := app.ProjectModel.GetBySlug(slug)
 	if err != nil {
+		if current, err := app.ProjectModel.CurrentSlug(slug); err == nil {
+			redirectSlug(w, r, "/project/"+current)
+			return
+		}
 		log.Printf("❌ Project not found: %v", err)
 		http.Error(w, "Project not found", http.StatusNotFound)
 		return
 	}
 
+	// Drafts are only visible to admins and through share links
+	if !project.Published && !canPreview(w, r, "project", project.ID, app.ShareLinkModel) {
+		http.Error(w, "Project not found", http.StatusNotFound)
+		return
+	}
+
 	media, err := app.ProjectModel.GetMediaPaginated(project.ID, 100, 0)
 	if err != nil {
 		log.Printf("❌ Failed to get media for project %d: %v", project.ID, err)
@@ -378,6 +440,20 @@ func (app *Application) PublicProjectView(w http.ResponseWriter, r *http.Request
 
 	log.Printf("✅ Project %d media count: %d", project.ID, len(media))
 
+	galleries, err := app.ProjectModel.GetGalleries(project.ID)
+	if err != nil {
+		log.Printf("❌ Failed to get galleries of project %d: %v", project.ID, err)
+		http.Error(w, "Unable to load project", http.StatusInternalServerError)
+		return
+	}
+
+	related, err := app.ProjectModel.GetRelated(project.ID, relatedProjectsLimit)
+	if err != nil {
+		log.Printf("❌ Failed to get related projects of project %d: %v", project.ID, err)
+		http.Error(w, "Unable to load project", http.StatusInternalServerError)
+		return
+	}
+
 	var heroMedia []*models.Media
 	var restMedia []*models.Media
 
@@ -400,9 +476,12 @@ func (app *Application) PublicProjectView(w http.ResponseWriter, r *http.Request
 		"OGImage":      project.CoverImageURL,
 		"HeroMedia":    heroMedia,
 		"Media":        restMedia, // remaining media
+		"Galleries":    galleries,
+		"Related":      related,
 		"ParentTitle":  "Projects",
 		"ParentURL":    "/projects",
 		"CurrentLabel": project.Title,
+		"NoIndex":      !project.Published,
 	}
 
 	log.Printf("Project Media Count: %d", len(media))
@@ -555,38 +634,39 @@ func (app *Application) DeleteGallery(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
-func (app *Application) AdminUsers(w http.ResponseWriter, r *http.Request) {
-	users, err := app.UserModel.GetAll()
+// DuplicateGallery copies a gallery into a new draft and opens it for editing
+func (app *Application) DuplicateGallery(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		log.Printf("❌ Error fetching users: %v", err)
-		http.Error(w, "Error fetching users", http.StatusInternalServerError)
+		http.Error(w, "Invalid gallery ID", http.StatusBadRequest)
 		return
 	}
 
-	data := map[string]interface{}{
-		"Title":      "Manage Users",
-		"Users":      users,
-		"ActiveLink": "users",
+	newID, err := app.GalleryModel.Duplicate(id)
+	if err != nil {
+		log.Printf("❌ Error duplicating gallery %d: %v", id, err)
+		http.Error(w, "Error duplicating gallery", http.StatusInternalServerError)
+		return
 	}
 
-	app.render(w, r, "admin/users.html", data)
+	http.Redirect(w, r, fmt.Sprintf("/admin/gallery/edit/%d", newID), http.StatusSeeOther)
 }
 
-func (app *Application) AdminContacts(w http.ResponseWriter, r *http.Request) {
-	contacts, err := app.ContactModel.GetAll()
+func (app *Application) AdminUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := app.UserModel.GetAll()
 	if err != nil {
-		log.Printf("❌ Error fetching contacts: %v", err)
-		http.Error(w, "Error fetching contacts", http.StatusInternalServerError)
+		log.Printf("❌ Error fetching users: %v", err)
+		http.Error(w, "Error fetching users", http.StatusInternalServerError)
 		return
 	}
 
 	data := map[string]interface{}{
-		"Title":      "Manage Contacts",
-		"Contacts":   contacts,
-		"ActiveLink": "contacts",
+		"Title":      "Manage Users",
+		"Users":      users,
+		"ActiveLink": "users",
 	}
 
-	app.render(w, r, "admin/contacts.html", data)
+	app.render(w, r, "admin/users.html", data)
 }
 
 func (app *Application) AdminProjects(w http.ResponseWriter, r *http.Request) {
@@ -643,6 +723,24 @@ func (app *Application) DeleteProject(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// DuplicateProject copies a project into a new draft and opens it for editing
+func (app *Application) DuplicateProject(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	newID, err := app.ProjectModel.Duplicate(id)
+	if err != nil {
+		log.Printf("❌ Error duplicating project %d: %v", id, err)
+		http.Error(w, "Error duplicating project", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/project/edit/%d", newID), http.StatusSeeOther)
+}
+
 func (app *Application) EditProjectForm(w http.ResponseWriter, r *http.Request) {
 	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
 
@@ -881,23 +979,17 @@ func (app *Application) UploadMedia(w http.ResponseWriter, r *http.Request) {
 	projectID, _ := strconv.Atoi(r.FormValue("project_id"))
 	galleryID, _ := strconv.Atoi(r.FormValue("gallery_id"))
 
-	isProject := projectID > 0
 	isGallery := galleryID > 0
 
-	var position int
-	var errPos error
-
-	if isProject {
-		position, errPos = app.MediaModel.GetNextProjectPosition(projectID)
-	} else if isGallery {
-		position, errPos = app.GalleryModel.GetNextPosition(galleryID)
-	}
-
-	if errPos != nil && (isProject || isGallery) {
-		log.Printf("❌ Error getting next position: %v", errPos)
-		http.Error(w, "Error getting next position", http.StatusInternalServerError)
+	wm, err := app.loadWatermark(ctx)
+	if err != nil {
+		log.Printf("❌ Error loading watermark: %v", err)
+		http.Error(w, "Error loading watermark settings", http.StatusInternalServerError)
 		return
 	}
+	if isGallery {
+		wm = app.watermarkForGallery(galleryID, wm)
+	}
 
 	for _, fileHeader := range files {
 		file, err := fileHeader.Open()
@@ -907,80 +999,18 @@ func (app *Application) UploadMedia(w http.ResponseWriter, r *http.Request) {
 		}
 		defer file.Close()
 
-		base := fmt.Sprintf("%d_%s", time.Now().UnixNano(), fileHeader.Filename)
-		fileName := base
-		thumbName := "thumb_" + base
-
-		fileKey := "Uploads/" + fileName
-		thumbKey := "Uploads/" + thumbName
-
-		img, err := imaging.Decode(file, imaging.AutoOrientation(true))
-		if err != nil {
-			log.Printf("❌ Error decoding image: %v", err)
-			continue
-		}
-
-		thumbnailImg := imaging.Resize(img, 500, 0, imaging.Lanczos)
-		file.Seek(0, 0)
-
-		fileSize := fileHeader.Size
-		contentType := fileHeader.Header.Get("Content-Type")
-
-		// Upload original
-		_, err = app.S3Client.PutObject(ctx, app.S3Bucket, fileKey, io.LimitReader(file, fileSize), fileSize, minio.PutObjectOptions{
-			ContentType:  contentType,
-			UserMetadata: map[string]string{"x-amz-acl": "public-read"},
-		})
+		media, err := app.processUpload(ctx, file, fileHeader.Size, fileHeader.Header.Get("Content-Type"), fileHeader.Filename, wm)
 		if err != nil {
-			log.Printf("❌ Error uploading original: %v", err)
+			log.Printf("❌ Error processing upload %s: %v", fileHeader.Filename, err)
 			continue
 		}
 
-		// Upload thumbnail
-		var thumbBuf bytes.Buffer
-		if err := imaging.Encode(&thumbBuf, thumbnailImg, imaging.JPEG); err == nil {
-			app.S3Client.PutObject(ctx, app.S3Bucket, thumbKey, bytes.NewReader(thumbBuf.Bytes()), int64(thumbBuf.Len()), minio.PutObjectOptions{
-				ContentType:  "image/jpeg",
-				UserMetadata: map[string]string{"x-amz-acl": "public-read"},
-			})
-		}
-
-		// Create URLs
-		fullURL := "https://" + os.Getenv("VULTR_S3_ENDPOINT") + "/" + app.S3Bucket + "/" + fileKey
-		thumbURL := "https://" + os.Getenv("VULTR_S3_ENDPOINT") + "/" + app.S3Bucket + "/" + thumbKey
-
-		// Insert into media table
-		mediaID, err := app.MediaModel.InsertAndReturnID(fileName, fullURL, thumbURL)
-		if err != nil {
-			log.Printf("❌ DB insert failed: %v", err)
+		if err := app.saveUploadedMedia(media, projectID, galleryID); err != nil {
+			log.Printf("❌ Failed to save upload %s: %v", fileHeader.Filename, err)
 			continue
 		}
 
-		// Attach to project or gallery if needed
-		if isProject {
-
-			err = app.MediaModel.AttachToProject(projectID, mediaID, position)
-			if err != nil {
-				log.Printf("❌ Failed to attach to project: %v", err)
-				continue
-			}
-			position++
-		} else if isGallery {
-			err = app.GalleryModel.AttachMedia(galleryID, mediaID, position)
-			if err != nil {
-				log.Printf("❌ Failed to attach to gallery: %v", err)
-				continue
-			}
-			position++
-		}
-
 		// Render media item partial
-		media := &models.Media{
-			ID:           mediaID,
-			FileName:     fileName,
-			ThumbnailURL: thumbURL,
-			FullURL:      fullURL,
-		}
 		fmt.Printf("Rendering media item: %+v\n", media)
 
 		app.renderPartialHTMX(w, "partials/media_item.html", map[string]any{
@@ -1021,6 +1051,8 @@ func (app *Application) Contact(w http.ResponseWriter, r *http.Request) {
 			"Title":       "Contact",
 			"ActiveLink":  "contact",
 			"Description": pageDescription,
+			"Captcha":     app.Captcha,
+			"FormToken":   issueFormToken(),
 		})
 		return
 	}
@@ -1034,12 +1066,14 @@ func (app *Application) Contact(w http.ResponseWriter, r *http.Request) {
 
 	// 3. Populate ContactForm struct
 	form := models.ContactForm{
-		FirstName:      r.FormValue("first_name"),
-		LastName:       r.FormValue("last_name"),
-		Email:          r.FormValue("email"),
-		Subject:        r.FormValue("subject"),
-		Message:        r.FormValue("message"),
-		RecaptchaToken: r.FormValue("g-recaptcha-response"),
+		FirstName: r.FormValue("first_name"),
+		LastName:  r.FormValue("last_name"),
+		Email:     r.FormValue("email"),
+		Subject:   r.FormValue("subject"),
+		Message:   r.FormValue("message"),
+	}
+	if field := app.Captcha.TokenField(); field != "" {
+		form.CaptchaToken = r.FormValue(field)
 	}
 	// log.Printf("🔍 Form submission: %+v", form)
 
@@ -1049,69 +1083,134 @@ func (app *Application) Contact(w http.ResponseWriter, r *http.Request) {
 		log.Printf("❌ Contact form validation failed: %v", err)
 		w.WriteHeader(http.StatusBadRequest)
 		app.renderPartialHTMX(w, "partials/contact_form.html", map[string]interface{}{
-			"Form":   form,
-			"Errors": err,
+			"Form":      form,
+			"Errors":    err,
+			"FormToken": issueFormToken(),
 		})
 		return
 	}
 
-	// 5. Verify reCAPTCHA
-	if !verifyRecaptcha(form.RecaptchaToken, os.Getenv("RECAPTCHA_SECRET")) {
-		log.Printf("❌ reCAPTCHA verification failed for email: %s", form.Email)
-		http.Error(w, "reCAPTCHA verification failed", http.StatusForbidden)
+	// 5. Verify the captcha
+	if err := app.Captcha.Verify(r.Context(), form.CaptchaToken, clientIP(r)); err != nil {
+		log.Printf("❌ Captcha verification failed for email %s: %v", form.Email, err)
+		http.Error(w, "Captcha verification failed", http.StatusForbidden)
 		return
 	}
 
-	// 6. Save contact info to DB
+	settings, err := app.SettingsModel.GetAll()
+	if err != nil {
+		log.Printf("❌ Error loading settings for the contact form: %v", err)
+		settings = map[string]string{}
+	}
+
+	// 6. Score it for spam. Spam is kept for the admin to review, but
+	// nobody is emailed about it, and the sender sees the usual success.
+	fillTime, fillTimeKnown := formFillTime(r.FormValue(formTokenField))
+	verdict := utils.SpamFilterFromSettings(settings).Check(utils.Submission{
+		Name:          form.FirstName + " " + form.LastName,
+		Email:         form.Email,
+		Subject:       form.Subject,
+		Message:       form.Message,
+		Honeypot:      r.FormValue(honeypotField),
+		FillTime:      fillTime,
+		FillTimeKnown: fillTimeKnown,
+	})
+	if verdict.Spam {
+		err := app.ContactModel.InsertSpam(form.FirstName, form.LastName, form.Email, form.Subject, form.Message, verdict.Reason())
+		if err != nil {
+			log.Printf("❌ Error saving spam contact form submission: %v", err)
+		}
+		log.Printf("⚠️ Contact form submission from %s marked spam (score %d: %s)", form.Email, verdict.Score, verdict.Reason())
+		contactSubmitted(w)
+		return
+	}
+
+	// 7. Save contact info to DB
 	if err := app.ContactModel.Insert(form.FirstName, form.LastName, form.Email, form.Subject, form.Message); err != nil {
 		log.Printf("❌ Error saving contact form submission: %v", err)
 		http.Error(w, "Failed to save message", http.StatusInternalServerError)
 		return
 	}
-	fmt.Println("Insert success. starting SendEmail method")
-
-	// log.Printf("SMTP_USER: %s | CONTACT_EMAIL: %s", os.Getenv("SMTP_USER"), os.Getenv("CONTACT_EMAIL"))
+	log.Printf("✅ Saved contact form submission from %s", form.Email)
 
-	// 7. Send email with Gomail via utils.SendEmail
-	err := utils.SendEmail(
-		os.Getenv("CONTACT_EMAIL"),            // From
-		os.Getenv("CONTACT_EMAIL"),            // To
-		form.Subject,                          // Subject
-		"templates/emails/contact_email.html", // Template path
-		form,                                  // Data for template
+	// 8. Queue the notification email. The enquiry is already saved, so a
+	// failure here is logged rather than shown to the visitor.
+	_, err = app.queueEmail(
+		os.Getenv("CONTACT_EMAIL"), // From
+		os.Getenv("CONTACT_EMAIL"), // To
+		form.Subject,               // Subject
+		"contact_notification",     // Email template
+		form,                       // Data for template
 	)
 	if err != nil {
-		log.Printf("❌ Email sending failed: %v", err)
-		http.Error(w, "Failed to send email notification", http.StatusInternalServerError)
-		return
+		log.Printf("❌ Error queueing contact notification email: %v", err)
 	}
 
-	// 8. Everything succeeded
+	// 9. Confirm receipt to the enquirer, if auto-replies are switched on
+	app.queueAutoReply(form, settings)
+
+	// 10. Everything succeeded
 	log.Printf("✅ Contact form submitted successfully by %s %s (%s)", form.FirstName, form.LastName, form.Email)
 
 	// app.render(w, r, "partials/contact_success_modal.html", nil) // ✅ Correct
 
+	contactSubmitted(w)
+}
+
+// contactSubmitted tells the contact page the message went through
+func contactSubmitted(w http.ResponseWriter) {
 	w.Header().Set("HX-Trigger", "form-submitted")
 	w.Header().Set("HX-Trigger-After-Settle", "show-toast-contact")
 	w.WriteHeader(http.StatusOK)
-
 }
 
-// Get All Galleries
+// Get All Galleries, or those in the category at {category}
 func (app *Application) PublicGalleriesList(w http.ResponseWriter, r *http.Request) {
-	galleries, err := app.GalleryModel.GetAllPublic()
-	log.Printf("Galleries: %v", galleries)
-
+	title := "Galleries"
 	description := "Explore bold and expressive portrait, branding, and fashion photography by a Canberra-based photographer. Each gallery showcases carefully curated visual stories designed to elevate personal identities and brand presence through powerful, intentional imagery."
+	path := "/galleries"
+
+	var category *models.Category
+	if slug := chi.URLParam(r, "category"); slug != "" {
+		var err error
+		category, err = app.CategoryModel.GetBySlug(slug)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		title = category.Name
+		if category.Description != "" {
+			description = category.Description
+		}
+		path = "/galleries/" + category.Slug
+	}
 
+	var galleries []map[string]interface{}
+	var err error
+	if category != nil {
+		galleries, err = app.GalleryModel.GetPublicByCategory(category.ID)
+	} else {
+		galleries, err = app.GalleryModel.GetAllPublic()
+	}
 	if err != nil {
+		log.Printf("❌ Error fetching galleries: %v", err)
 		http.Error(w, "Error fetching galleries", http.StatusInternalServerError)
 		return
 	}
+
+	categories, err := app.CategoryModel.GetAll()
+	if err != nil {
+		log.Printf("❌ Error fetching categories: %v", err)
+		http.Error(w, "Error fetching categories", http.StatusInternalServerError)
+		return
+	}
+
 	app.render(w, r, "galleries.html", map[string]interface{}{
-		"Title":        "Galleries",
+		"Title":        title,
 		"Galleries":    galleries,
-		"CanonicalURL": utils.BuildCanonicalURL(r, "/galleries"),
+		"Categories":   publicCategories(categories),
+		"Category":     category,
+		"CanonicalURL": utils.BuildCanonicalURL(r, path),
 		"Description":  description,
 		"ActiveLink":   "galleries",
 	})
@@ -1123,9 +1222,35 @@ func (app *Application) GalleryView(w http.ResponseWriter, r *http.Request) {
 	slug := chi.URLParam(r, "slug")
 
 	gallery, err := app.GalleryModel.GetBySlug(slug)
+	if err != nil {
+		// Renamed galleries keep answering at their old slugs
+		if current, err := app.GalleryModel.CurrentSlug(slug); err == nil {
+			redirectSlug(w, r, "/gallery/"+current)
+			return
+		}
+		http.NotFound(w, r)
+		return
+	}
 
 	log.Printf("✅ GalleryView requested for ID: %d", gallery.ID)
 
+	// Client galleries open through their share link, or ask for their
+	// password
+	if gallery.ClientAccess {
+		if !app.hasClientAccess(w, r, gallery) {
+			app.renderGalleryPassword(w, r, gallery, "")
+			return
+		}
+		app.renderProofingGallery(w, r, gallery)
+		return
+	}
+
+	// Drafts are only visible to admins and through share links
+	if !gallery.Published && !canPreview(w, r, "gallery", gallery.ID, app.ShareLinkModel) {
+		http.NotFound(w, r)
+		return
+	}
+
 	// Fetch media
 	media, err := app.GalleryModel.GetMediaPaginated(gallery.ID, 25, 0)
 	if err != nil {
@@ -1136,6 +1261,28 @@ func (app *Application) GalleryView(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("✅ Fetched %d media items for Gallery ID: %d", len(media), gallery.ID)
 
+	// Collections list their sub-galleries above their own media
+	children, err := app.GalleryModel.GetChildren(gallery.ID)
+	if err != nil {
+		log.Printf("❌ Error fetching sub-galleries: %v", err)
+		http.Error(w, "Error retrieving gallery", http.StatusInternalServerError)
+		return
+	}
+
+	ancestors, err := app.GalleryModel.GetAncestors(gallery.ID)
+	if err != nil {
+		log.Printf("❌ Error fetching gallery collections: %v", err)
+		http.Error(w, "Error retrieving gallery", http.StatusInternalServerError)
+		return
+	}
+
+	projects, err := app.GalleryModel.GetProjects(gallery.ID)
+	if err != nil {
+		log.Printf("❌ Error fetching gallery projects: %v", err)
+		http.Error(w, "Error retrieving gallery", http.StatusInternalServerError)
+		return
+	}
+
 	// Canonical URL for SEO
 	canonical := utils.BuildCanonicalURL(r, fmt.Sprintf("/gallery/%s", gallery.Slug))
 
@@ -1156,9 +1303,51 @@ func (app *Application) GalleryView(w http.ResponseWriter, r *http.Request) {
 		"ActiveLink":   "galleries",
 		"Gallery":      gallery,
 		"Media":        media,
+		"Children":     children,
+		"Projects":     projects,
 		"ParentURL":    "/galleries",
 		"CurrentLabel": gallery.Title,
 		"ParentTitle":  "Galleries",
+		"Trail":        collectionTrail(ancestors),
+		"NoIndex":      !gallery.Published,
+	})
+}
+
+// redirectSlug permanently redirects an old slug to path, keeping the query
+// string so share-link tokens survive the hop
+func redirectSlug(w http.ResponseWriter, r *http.Request, path string) {
+	if r.URL.RawQuery != "" {
+		path += "?" + r.URL.RawQuery
+	}
+	http.Redirect(w, r, path, http.StatusMovedPermanently)
+}
+
+// GalleryUnlock checks the password of a client gallery and, when it
+// matches, grants the visitor access with a signed cookie
+func (app *Application) GalleryUnlock(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+
+	gallery, err := app.GalleryModel.GetBySlug(slug)
+	if err != nil || !gallery.ClientAccess {
+		http.NotFound(w, r)
+		return
+	}
+
+	if !gallery.CheckPassword(r.FormValue("password")) {
+		log.Printf("⚠️ Wrong password for client gallery %d", gallery.ID)
+		app.renderGalleryPassword(w, r, gallery, "That password isn't right. Please try again.")
+		return
+	}
+
+	SetGalleryAccess(gallery, w)
+	http.Redirect(w, r, "/gallery/"+gallery.Slug, http.StatusSeeOther)
+}
+
+func (app *Application) renderGalleryPassword(w http.ResponseWriter, r *http.Request, gallery *models.Gallery, errMsg string) {
+	app.render(w, r, "gallery_password.html", map[string]interface{}{
+		"Title":   gallery.Title,
+		"Gallery": gallery,
+		"Error":   errMsg,
 	})
 }
 
@@ -1251,6 +1440,104 @@ func (app *Application) SetGalleryVisibility(w http.ResponseWriter, r *http.Requ
 	w.WriteHeader(http.StatusOK)
 }
 
+// SetGalleryClientAccess sets or changes the password of a client gallery,
+// or turns client access off when action is "disable"
+func (app *Application) SetGalleryClientAccess(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Printf("❌ Invalid gallery ID: %v", err)
+		http.Error(w, "Invalid gallery ID", http.StatusBadRequest)
+		return
+	}
+
+	if r.FormValue("action") == "disable" {
+		err = app.GalleryModel.ClearClientAccess(id)
+	} else {
+		password := r.FormValue("password")
+		if len(password) < 6 {
+			http.Error(w, "Password must be at least 6 characters", http.StatusBadRequest)
+			return
+		}
+		err = app.GalleryModel.SetClientPassword(id, password)
+	}
+	if err != nil {
+		log.Printf("❌ Error updating client access: %v", err)
+		http.Error(w, "Error updating client access", http.StatusInternalServerError)
+		return
+	}
+
+	gallery, err := app.GalleryModel.GetByID(id)
+	if err != nil {
+		http.Error(w, "Gallery not found", http.StatusNotFound)
+		return
+	}
+
+	app.renderPartialHTMX(w, "partials/gallery_info_static.html", map[string]interface{}{
+		"Gallery": gallery,
+	})
+}
+
+func (app *Application) SetGalleryWatermark(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Printf("❌ Invalid gallery ID: %v", err)
+		http.Error(w, "Invalid gallery ID", http.StatusBadRequest)
+		return
+	}
+
+	// The checkbox sends "on" when the gallery opts out of watermarking
+	optOut := r.FormValue("watermark_opt_out") == "on"
+
+	err = app.GalleryModel.SetWatermarkOptOut(id, optOut)
+	if err != nil {
+		log.Printf("❌ Error updating gallery watermark: %v", err)
+		http.Error(w, "Error updating gallery watermark", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// RegenerateGalleryMedia re-renders the public derivatives of every image in
+// a gallery from their private originals, applying the current watermark
+// settings. Media shared with other galleries is re-rendered for all of them.
+func (app *Application) RegenerateGalleryMedia(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid gallery ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	wm, err := app.loadWatermark(ctx)
+	if err != nil {
+		log.Printf("❌ Error loading watermark: %v", err)
+		http.Error(w, "Error loading watermark settings", http.StatusInternalServerError)
+		return
+	}
+	wm = app.watermarkForGallery(id, wm)
+
+	media, err := app.MediaModel.GetByGalleryID(id)
+	if err != nil {
+		log.Printf("❌ Error fetching gallery media: %v", err)
+		http.Error(w, "Error fetching gallery media", http.StatusInternalServerError)
+		return
+	}
+
+	failed := 0
+	for _, m := range media {
+		if err := app.regenerateDerivatives(ctx, m, wm); err != nil {
+			log.Printf("❌ Failed to regenerate media %d: %v", m.ID, err)
+			failed++
+		}
+	}
+
+	log.Printf("✅ Regenerated %d/%d media items for gallery %d", len(media)-failed, len(media), id)
+
+	w.Header().Set("HX-Trigger-After-Settle", "show-toast-regenerated")
+	w.WriteHeader(http.StatusOK)
+}
+
 func (app *Application) UpdateProjectMediaOrder(w http.ResponseWriter, r *http.Request) {
 	var payload struct {
 		ProjectID int   `json:"project_id"`
@@ -1263,7 +1550,7 @@ func (app *Application) UpdateProjectMediaOrder(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	err := app.MediaModel.UpdatePositionsForProject(payload.ProjectID, payload.Order)
+	err := app.ProjectModel.Media().Reorder(payload.ProjectID, payload.Order)
 	if err != nil {
 		log.Printf("❌ Failed to update project media order: %v", err)
 		http.Error(w, "Failed to update media order", http.StatusInternalServerError)
@@ -1292,9 +1579,9 @@ func (app *Application) UpdateMediaOrderBulk(w http.ResponseWriter, r *http.Requ
 	log.Printf("🧪 GalleryID: %d | ProjectID: %d | Order: %v", payload.GalleryID, payload.ProjectID, payload.Order)
 
 	if payload.GalleryID != 0 {
-		err = app.MediaModel.UpdatePositionsForGallery(payload.GalleryID, payload.Order)
+		err = app.GalleryModel.Media().Reorder(payload.GalleryID, payload.Order)
 	} else if payload.ProjectID != 0 {
-		err = app.MediaModel.UpdatePositionsForProject(payload.ProjectID, payload.Order)
+		err = app.ProjectModel.Media().Reorder(payload.ProjectID, payload.Order)
 	}
 
 	if err != nil {
@@ -1308,51 +1595,48 @@ func (app *Application) UpdateMediaOrderBulk(w http.ResponseWriter, r *http.Requ
 	w.WriteHeader(http.StatusOK)
 }
 
-// Helper function to verify recaptcha
-
-func verifyRecaptcha(token, secret string) bool {
-	resp, err := http.PostForm("https://www.google.com/recaptcha/api/siteverify",
-		url.Values{
-			"secret":   {secret},
-			"response": {token},
-		},
-	)
-
-	if err != nil {
-		log.Printf("❌ reCAPTCHA request failed: %v", err)
-		return false
+// UpdateGalleryOrder saves the order of the galleries list, dragged into
+// place on the admin galleries page
+func (app *Application) UpdateGalleryOrder(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Order []int `json:"order"`
 	}
-	defer resp.Body.Close()
 
-	var result struct {
-		Success    bool     `json:"success"`
-		Score      float64  `json:"score"`
-		Action     string   `json:"action"`
-		Hostname   string   `json:"hostname"`
-		ErrorCodes []string `json:"error-codes"`
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		log.Printf("❌ Invalid JSON: %v", err)
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		log.Printf("❌ Failed to parse reCAPTCHA response: %v", err)
-		return false
+	if err := app.GalleryModel.UpdatePositions(payload.Order); err != nil {
+		log.Printf("❌ Failed to update gallery order: %v", err)
+		http.Error(w, "Failed to update positions", http.StatusInternalServerError)
+		return
 	}
 
-	// Log the full reCAPTCHA response for debugging
-	log.Printf("🔍 reCAPTCHA Response: %+v", result)
+	w.WriteHeader(http.StatusOK)
+}
+
+// UpdateProjectOrder saves the order of the projects list, dragged into
+// place on the admin projects page
+func (app *Application) UpdateProjectOrder(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Order []int `json:"order"`
+	}
 
-	// If verification failed, log errors
-	if !result.Success {
-		log.Printf("❌ reCAPTCHA verification failed: %v", result.ErrorCodes)
-		return false
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		log.Printf("❌ Invalid JSON: %v", err)
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
 	}
 
-	// Require a minimum score to prevent spam bots
-	if result.Score < 0.5 {
-		log.Printf("⚠️ Low reCAPTCHA score (%f) - possible bot", result.Score)
-		return false
+	if err := app.ProjectModel.UpdatePositions(payload.Order); err != nil {
+		log.Printf("❌ Failed to update project order: %v", err)
+		http.Error(w, "Failed to update positions", http.StatusInternalServerError)
+		return
 	}
 
-	return true
+	w.WriteHeader(http.StatusOK)
 }
 
 func (app *Application) GetAboutMeImageModal(w http.ResponseWriter, r *http.Request) {
@@ -1531,8 +1815,29 @@ func (app *Application) ProjectInfoUpdate(w http.ResponseWriter, r *http.Request
 	title := r.FormValue("title")
 	description := r.FormValue("description")
 
-	err := app.ProjectModel.UpdateBasicInfo(id, title, description, utils.Slugify(title))
-	if err != nil {
+	meta := models.ProjectMeta{
+		Client:   strings.TrimSpace(r.FormValue("client")),
+		Location: strings.TrimSpace(r.FormValue("location")),
+		Services: models.ParseServices(r.FormValue("services")),
+		Credits:  models.ParseCredits(r.FormValue("credits")),
+		Links:    models.ParseLinks(r.FormValue("links")),
+	}
+	if v := r.FormValue("shoot_date"); v != "" {
+		date, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "Invalid shoot date", http.StatusBadRequest)
+			return
+		}
+		meta.ShootDate = &date
+	}
+
+	// Everything is saved together, so a bad link leaves the whole form unsaved
+	err := app.ProjectModel.SaveInfo(id, title, description, utils.Slugify(title), meta, r.FormValue("body"))
+	if errors.Is(err, models.ErrInvalidLink) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		log.Printf("❌ Error saving project %d: %v", id, err)
 		http.Error(w, "Update failed", http.StatusInternalServerError)
 		return
 	}
@@ -1547,6 +1852,21 @@ func (app *Application) ProjectInfoUpdate(w http.ResponseWriter, r *http.Request
 	app.renderPartialHTMX(w, "partials/project_info_view.html", data)
 }
 
+// ProjectBodyPreview renders the Markdown body being edited, as it would
+// appear on the project page
+func (app *Application) ProjectBodyPreview(w http.ResponseWriter, r *http.Request) {
+	body, err := app.ProjectModel.RenderBody(r.FormValue("body"))
+	if err != nil {
+		log.Printf("❌ Error rendering project body preview: %v", err)
+		http.Error(w, "Preview failed", http.StatusInternalServerError)
+		return
+	}
+
+	app.renderPartialHTMX(w, "partials/project_body_preview.html", map[string]interface{}{
+		"Body": body,
+	})
+}
+
 func (app *Application) AttachMediaToItem(w http.ResponseWriter, r *http.Request) {
 	err := r.ParseForm()
 	if err != nil {
@@ -1568,7 +1888,7 @@ func (app *Application) AttachMediaToItem(w http.ResponseWriter, r *http.Request
 			return
 		}
 
-		err = app.MediaModel.InsertProjectMedia(projectID, mediaID)
+		err = app.ProjectModel.Media().Append(projectID, mediaID)
 		if err != nil {
 			log.Printf("❌ Failed to link media %d to project %d: %v", mediaID, projectID, err)
 			http.Error(w, "Failed to attach media to project", http.StatusInternalServerError)
@@ -1605,7 +1925,7 @@ func (app *Application) AttachMediaToItem(w http.ResponseWriter, r *http.Request
 
 		log.Printf("📎 Linking media_id=%d to gallery_id=%d", mediaID, galleryID)
 
-		err = app.MediaModel.InsertGalleryMedia(galleryID, mediaID)
+		err = app.GalleryModel.Media().Append(galleryID, mediaID)
 		if err != nil {
 			log.Printf("❌ Failed to link media %d to gallery %d: %v", mediaID, galleryID, err)
 			http.Error(w, "Failed to attach media to gallery", http.StatusInternalServerError)
@@ -1652,7 +1972,7 @@ func (app *Application) UnlinkMediaFromItem(w http.ResponseWriter, r *http.Reque
 	// Project unlink
 	if projectIDStr := r.FormValue("project_id"); projectIDStr != "" {
 		projectID, _ := strconv.Atoi(projectIDStr)
-		err := app.MediaModel.UnlinkMediaFromProject(projectID, mediaID)
+		err := app.ProjectModel.Media().Remove(projectID, mediaID)
 		if err != nil {
 			http.Error(w, "Failed to unlink media from project", http.StatusInternalServerError)
 			return
@@ -1667,7 +1987,7 @@ func (app *Application) UnlinkMediaFromItem(w http.ResponseWriter, r *http.Reque
 	// Gallery unlink
 	if galleryIDStr := r.FormValue("gallery_id"); galleryIDStr != "" {
 		galleryID, _ := strconv.Atoi(galleryIDStr)
-		err := app.MediaModel.UnlinkMediaFromGallery(galleryID, mediaID)
+		err := app.GalleryModel.Media().Remove(galleryID, mediaID)
 		if err != nil {
 			http.Error(w, "Failed to unlink media from gallery", http.StatusInternalServerError)
 			return
@@ -1736,22 +2056,144 @@ func (app *Application) DeleteMedia(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := app.MediaModel.Delete(mediaID); err != nil {
-		log.Printf("❌ Failed to delete media from DB: %v", err)
+	// Trashed media keeps its files until it is purged
+	if err := app.MediaModel.Delete(media.ID); err != nil {
+		log.Printf("❌ Failed to move media to trash: %v", err)
 		http.Error(w, "Failed to delete media", http.StatusInternalServerError)
 		return
 	}
 
-	// Delete thumbnail (same folder, with "thumb_" prefix)
-	thumbKey := "Uploads/thumb_" + media.FileName
+	w.WriteHeader(http.StatusOK)
+}
 
-	// 🧹 Delete from S3 (MinIO)
-	if err := app.deleteFromS3("Uploads/" + media.FileName); err != nil {
+// deleteMediaObjects removes the public renditions and private original of
+// a media file from S3. Failures are logged, not returned, as the DB row is
+// already gone by the time this runs.
+func (app *Application) deleteMediaObjects(fileName, originalKey string) {
+	if err := app.deleteFromS3(utils.UploadsPrefix + fileName); err != nil {
 		log.Printf("⚠️ Failed to delete full image: %v", err)
 	}
-	if err := app.deleteFromS3(thumbKey); err != nil {
+	// Thumbnail lives in the same folder, with "thumb_" prefix
+	if err := app.deleteFromS3(utils.UploadsPrefix + "thumb_" + fileName); err != nil {
 		log.Printf("⚠️ Failed to delete thumbnail: %v", err)
 	}
+	if originalKey != "" {
+		if err := app.deleteFromS3(originalKey); err != nil {
+			log.Printf("⚠️ Failed to delete original: %v", err)
+		}
+	}
+}
+
+// ReplaceMedia uploads a new file for an existing media item. The ID, and so
+// every gallery/project link, position and cover reference, is unchanged;
+// the previous file is kept as a version for rollback.
+func (app *Application) ReplaceMedia(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid media ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := app.MediaModel.GetByID(id); err != nil {
+		log.Printf("❌ Media not found: %v", err)
+		http.Error(w, "Media not found", http.StatusNotFound)
+		return
+	}
+
+	if err := r.ParseMultipartForm(50 << 20); err != nil {
+		http.Error(w, "Error parsing form", http.StatusBadRequest)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Missing file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	ctx := r.Context()
+	wm, err := app.loadWatermark(ctx)
+	if err != nil {
+		log.Printf("❌ Error loading watermark: %v", err)
+		http.Error(w, "Error loading watermark settings", http.StatusInternalServerError)
+		return
+	}
+	if galleryID, _ := strconv.Atoi(r.FormValue("gallery_id")); galleryID > 0 {
+		wm = app.watermarkForGallery(galleryID, wm)
+	}
+
+	processed, err := app.processUpload(ctx, file, header.Size, header.Header.Get("Content-Type"), header.Filename, wm)
+	if err != nil {
+		log.Printf("❌ Error processing replacement for media %d: %v", id, err)
+		http.Error(w, "Error processing file", http.StatusBadRequest)
+		return
+	}
+
+	err = app.MediaModel.ReplaceFile(id, processed.FileName, processed.FullURL, processed.ThumbnailURL,
+		processed.OriginalKey, processed.BlurHash, processed.DominantColor)
+	if err != nil {
+		log.Printf("❌ Failed to replace media %d: %v", id, err)
+		app.deleteMediaObjects(processed.FileName, processed.OriginalKey)
+		http.Error(w, "Failed to replace media", http.StatusInternalServerError)
+		return
+	}
+
+	media, err := app.MediaModel.GetByID(id)
+	if err != nil {
+		http.Error(w, "Failed to load media", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("✅ Replaced file for media %d with %s", id, media.FileName)
+	app.renderPartialHTMX(w, "partials/media_library_item.html", media)
+}
+
+func (app *Application) MediaVersions(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid media ID", http.StatusBadRequest)
+		return
+	}
+
+	media, err := app.MediaModel.GetByID(id)
+	if err != nil {
+		http.Error(w, "Media not found", http.StatusNotFound)
+		return
+	}
+
+	versions, err := app.MediaModel.GetVersions(id)
+	if err != nil {
+		log.Printf("❌ Failed to load versions for media %d: %v", id, err)
+		http.Error(w, "Error loading versions", http.StatusInternalServerError)
+		return
+	}
+
+	app.renderPartialHTMX(w, "partials/media_versions_modal.html", map[string]interface{}{
+		"Media":    media,
+		"Versions": versions,
+	})
+}
+
+func (app *Application) RestoreMediaVersion(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid media ID", http.StatusBadRequest)
+		return
+	}
+	versionID, err := strconv.Atoi(chi.URLParam(r, "versionID"))
+	if err != nil {
+		http.Error(w, "Invalid version ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := app.MediaModel.RestoreVersion(id, versionID); err != nil {
+		log.Printf("❌ Failed to restore version %d of media %d: %v", versionID, id, err)
+		http.Error(w, "Failed to restore version", http.StatusInternalServerError)
+		return
+	}
 
+	// Reload so every grid showing this media picks up the restored file
+	w.Header().Set("HX-Refresh", "true")
 	w.WriteHeader(http.StatusOK)
 }
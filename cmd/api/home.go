@@ -0,0 +1,288 @@
+package main
+
+import (
+	"errors"
+	"ikm/models"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+// heroSlideLimit caps how many images the hero slideshow cycles through
+const heroSlideLimit = 20
+
+// homeBlocks loads what each home page section shows. A section that fails
+// to load is logged and left out so the rest of the page still renders.
+func (app *Application) homeBlocks(sections []models.HomeSection) []map[string]interface{} {
+	var blocks []map[string]interface{}
+	for _, s := range sections {
+		block := map[string]interface{}{"Type": s.Type, "Section": s}
+
+		switch s.Type {
+		case models.SectionHero:
+			gallery, slides, err := app.heroGallery(s.GalleryID)
+			if err != nil {
+				log.Printf("❌ Error loading home hero: %v", err)
+				continue
+			}
+			if gallery == nil || len(slides) == 0 {
+				continue
+			}
+			block["Gallery"] = gallery
+			block["Slides"] = slides
+
+		case models.SectionFeatured:
+			galleries, err := app.GalleryModel.GetFeatured(sectionLimit(s))
+			if err != nil {
+				log.Printf("❌ Error loading featured galleries: %v", err)
+				continue
+			}
+			if len(galleries) == 0 {
+				continue
+			}
+			block["Galleries"] = galleries
+
+		case models.SectionLatestProjects:
+			projects, err := app.ProjectModel.GetLatest(sectionLimit(s))
+			if err != nil {
+				log.Printf("❌ Error loading latest projects: %v", err)
+				continue
+			}
+			if len(projects) == 0 {
+				continue
+			}
+			block["Projects"] = projects
+
+		case models.SectionTestimonial:
+			if s.Quote == "" {
+				continue
+			}
+		}
+
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+// heroGallery returns the hero gallery and its images. With no gallery
+// chosen it falls back to the featured gallery, then to the gallery titled
+// "Home" that fed the home page before sections existed; with none of them
+// it returns nil. Only public galleries are shown, except that the "Home"
+// gallery was never meant to be listed, so it only has to not be a client
+// gallery.
+func (app *Application) heroGallery(id int) (*models.Gallery, []*models.Media, error) {
+	legacy := false
+	if id == 0 {
+		featured, err := app.GalleryModel.GetFeatured(1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(featured) > 0 {
+			id = featured[0].ID
+		} else {
+			home, _, err := app.GalleryModel.GetByTitle("Home")
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, nil, nil
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			id, legacy = home.ID, true
+		}
+	}
+
+	gallery, err := app.GalleryModel.GetByID(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if gallery.ClientAccess || (!gallery.Published && !legacy) {
+		log.Printf("⚠️ Not showing private gallery %d in the home hero", gallery.ID)
+		return nil, nil, nil
+	}
+
+	media, err := app.GalleryModel.GetMediaPaginated(id, heroSlideLimit, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Videos and embeds don't belong in a slideshow
+	var slides []*models.Media
+	for _, m := range media {
+		if m.EmbedURL != nil && *m.EmbedURL != "" {
+			continue
+		}
+		if m.MimeType != nil && strings.HasPrefix(*m.MimeType, "video/") {
+			continue
+		}
+		slides = append(slides, m)
+	}
+	return gallery, slides, nil
+}
+
+func sectionLimit(s models.HomeSection) int {
+	if s.Limit > 0 {
+		return s.Limit
+	}
+	return 3
+}
+
+// AdminHome shows the home page builder
+func (app *Application) AdminHome(w http.ResponseWriter, r *http.Request) {
+	app.render(w, r, "admin/home.html", map[string]interface{}{
+		"Title":      "Home Page",
+		"ActiveLink": "home_page",
+	})
+}
+
+// HomeSections renders the editable list of home page sections
+func (app *Application) HomeSections(w http.ResponseWriter, r *http.Request) {
+	app.renderHomeSections(w, "")
+}
+
+// AddHomeSection appends a new section of the chosen type
+func (app *Application) AddHomeSection(w http.ResponseWriter, r *http.Request) {
+	sections, ok := app.loadHomeSections(w)
+	if !ok {
+		return
+	}
+
+	section, err := models.NewHomeSection(r.FormValue("type"))
+	if err != nil {
+		app.renderHomeSections(w, "Pick a section type to add.")
+		return
+	}
+
+	app.saveHomeSections(w, append(sections, section))
+}
+
+// UpdateHomeSection saves the fields of one section
+func (app *Application) UpdateHomeSection(w http.ResponseWriter, r *http.Request) {
+	sections, ok := app.loadHomeSections(w)
+	if !ok {
+		return
+	}
+	i, ok := sectionIndex(r, sections)
+	if !ok {
+		http.Error(w, "Invalid section", http.StatusBadRequest)
+		return
+	}
+
+	s := &sections[i]
+	s.GalleryID, _ = strconv.Atoi(r.FormValue("gallery_id"))
+	if s.Type == models.SectionHero && s.GalleryID > 0 {
+		if gallery, err := app.GalleryModel.GetByID(s.GalleryID); err != nil || !gallery.IsPublic() {
+			app.renderHomeSections(w, "The hero can only show a published gallery.")
+			return
+		}
+	}
+	s.Limit, _ = strconv.Atoi(r.FormValue("limit"))
+	s.Heading = strings.TrimSpace(r.FormValue("heading"))
+	s.Text = strings.TrimSpace(r.FormValue("text"))
+	s.Quote = strings.TrimSpace(r.FormValue("quote"))
+	s.Author = strings.TrimSpace(r.FormValue("author"))
+	s.ButtonLabel = strings.TrimSpace(r.FormValue("button_label"))
+	s.ButtonURL = strings.TrimSpace(r.FormValue("button_url"))
+
+	if err := s.Validate(); err != nil {
+		app.renderHomeSections(w, "Could not save the "+strings.ToLower(s.Label())+" section: "+err.Error()+".")
+		return
+	}
+
+	app.saveHomeSections(w, sections)
+}
+
+// MoveHomeSection swaps a section with its neighbour above or below
+func (app *Application) MoveHomeSection(w http.ResponseWriter, r *http.Request) {
+	sections, ok := app.loadHomeSections(w)
+	if !ok {
+		return
+	}
+	i, ok := sectionIndex(r, sections)
+	if !ok {
+		http.Error(w, "Invalid section", http.StatusBadRequest)
+		return
+	}
+
+	j := i + 1
+	if r.FormValue("direction") == "up" {
+		j = i - 1
+	}
+	if j >= 0 && j < len(sections) {
+		sections[i], sections[j] = sections[j], sections[i]
+	}
+
+	app.saveHomeSections(w, sections)
+}
+
+func (app *Application) DeleteHomeSection(w http.ResponseWriter, r *http.Request) {
+	sections, ok := app.loadHomeSections(w)
+	if !ok {
+		return
+	}
+	i, ok := sectionIndex(r, sections)
+	if !ok {
+		http.Error(w, "Invalid section", http.StatusBadRequest)
+		return
+	}
+
+	app.saveHomeSections(w, append(sections[:i], sections[i+1:]...))
+}
+
+func (app *Application) loadHomeSections(w http.ResponseWriter) ([]models.HomeSection, bool) {
+	sections, err := app.SettingsModel.GetHomeSections()
+	if err != nil {
+		log.Printf("❌ Error loading home sections: %v", err)
+		http.Error(w, "Error loading home page sections", http.StatusInternalServerError)
+		return nil, false
+	}
+	return sections, true
+}
+
+func (app *Application) saveHomeSections(w http.ResponseWriter, sections []models.HomeSection) {
+	if err := app.SettingsModel.SetHomeSections(sections); err != nil {
+		log.Printf("❌ Error saving home sections: %v", err)
+		app.renderHomeSections(w, "Could not save the home page.")
+		return
+	}
+	app.renderHomeSections(w, "")
+}
+
+func (app *Application) renderHomeSections(w http.ResponseWriter, errMsg string) {
+	sections, ok := app.loadHomeSections(w)
+	if !ok {
+		return
+	}
+
+	// Only public galleries can go on the home page
+	galleries, err := app.GalleryModel.GetPublished()
+	if err != nil {
+		log.Printf("❌ Error fetching galleries: %v", err)
+		http.Error(w, "Error fetching galleries", http.StatusInternalServerError)
+		return
+	}
+
+	types := make([]models.HomeSection, len(models.HomeSectionTypes))
+	for i, t := range models.HomeSectionTypes {
+		types[i] = models.HomeSection{Type: t}
+	}
+
+	app.renderPartialHTMX(w, "partials/home_sections.html", map[string]interface{}{
+		"Sections":  sections,
+		"Types":     types,
+		"Galleries": galleries,
+		"Error":     errMsg,
+	})
+}
+
+// sectionIndex reads the {index} URL parameter and checks it's in range
+func sectionIndex(r *http.Request, sections []models.HomeSection) (int, bool) {
+	i, err := strconv.Atoi(chi.URLParam(r, "index"))
+	if err != nil || i < 0 || i >= len(sections) {
+		return 0, false
+	}
+	return i, true
+}
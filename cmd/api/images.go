@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"ikm/models"
+	"ikm/utils"
+	"io"
+	"os"
+	"time"
+
+	"github.com/disintegration/imaging"
+)
+
+// images returns the store media files are kept in
+func (app *Application) images() *utils.ImageStore {
+	return &utils.ImageStore{
+		Client:   app.S3Client,
+		Bucket:   app.S3Bucket,
+		Endpoint: os.Getenv("VULTR_S3_ENDPOINT"),
+	}
+}
+
+// loadWatermark reads the watermark configuration from settings, fetching
+// the logo from S3 when one has been uploaded. A nil watermark means
+// watermarking is switched off.
+func (app *Application) loadWatermark(ctx context.Context) (*utils.Watermark, error) {
+	settings, err := app.SettingsModel.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return app.images().LoadWatermark(ctx, settings)
+}
+
+// watermarkForGallery returns wm unless the gallery has opted out
+func (app *Application) watermarkForGallery(galleryID int, wm *utils.Watermark) *utils.Watermark {
+	if wm == nil || galleryID <= 0 {
+		return wm
+	}
+	gallery, err := app.GalleryModel.GetByID(galleryID)
+	if err == nil && gallery.WatermarkOptOut {
+		return nil
+	}
+	return wm
+}
+
+// processUpload runs a freshly uploaded file through the media pipeline:
+// the original is stored privately, public renditions are published and a
+// placeholder is computed. The returned Media has not been saved yet.
+func (app *Application) processUpload(ctx context.Context, file io.ReadSeeker, size int64, contentType, originalName string, wm *utils.Watermark) (*models.Media, error) {
+	fileName := fmt.Sprintf("%d_%s", time.Now().UnixNano(), originalName)
+
+	img, err := imaging.Decode(file, imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrUnreadableImage, err)
+	}
+	images := app.images()
+
+	// Keep the original private
+	file.Seek(0, io.SeekStart)
+	originalKey, err := images.StoreOriginal(ctx, io.LimitReader(file, size), size, contentType, fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Publish the renditions visitors actually see
+	var fullURL, thumbURL string
+	if utils.IsAnimatedFormat(fileName) {
+		file.Seek(0, io.SeekStart)
+		fullURL, thumbURL, err = images.PublishAnimated(ctx, io.LimitReader(file, size), img, fileName, wm)
+	} else {
+		fullURL, thumbURL, err = images.PublishDerivatives(ctx, img, fileName, wm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	media := &models.Media{
+		FileName:     fileName,
+		FullURL:      fullURL,
+		ThumbnailURL: thumbURL,
+		OriginalKey:  originalKey,
+	}
+
+	// Placeholder shown by public pages while the image loads
+	if hash, err := utils.BlurHash(img, 4, 3); err == nil {
+		media.BlurHash = hash
+		media.DominantColor = utils.DominantColor(img)
+	}
+
+	return media, nil
+}
+
+// saveUploadedMedia inserts a processed upload and, when a project or
+// gallery ID is given, adds it to the end in the same transaction. If either
+// step fails nothing is saved, and the files processUpload stored are removed
+// so a retry doesn't leave them behind. media.ID is set on success.
+func (app *Application) saveUploadedMedia(media *models.Media, projectID, galleryID int) error {
+	var err error
+	switch {
+	case projectID > 0:
+		err = app.ProjectModel.Media().AppendNew(projectID, media)
+	case galleryID > 0:
+		err = app.GalleryModel.Media().AppendNew(galleryID, media)
+	default:
+		err = app.MediaModel.Insert(media)
+	}
+	if err != nil {
+		app.deleteMediaObjects(media.FileName, media.OriginalKey)
+		return fmt.Errorf("saving media: %w", err)
+	}
+	return nil
+}
+
+// regenerateDerivatives re-renders the public renditions of a media item
+// from its private original. Legacy uploads that only exist as public
+// objects are first copied into the private originals area so the
+// unwatermarked file stops being the one that is served.
+func (app *Application) regenerateDerivatives(ctx context.Context, media *models.Media, wm *utils.Watermark) error {
+	key, err := app.images().Regenerate(ctx, media.FileName, media.OriginalKey, wm)
+	if err != nil {
+		return fmt.Errorf("regenerating media %d: %w", media.ID, err)
+	}
+	if key != media.OriginalKey {
+		return app.MediaModel.SetOriginalKey(media.ID, key)
+	}
+	return nil
+}
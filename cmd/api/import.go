@@ -0,0 +1,329 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"ikm/models"
+	"ikm/utils"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/disintegration/imaging"
+)
+
+const (
+	maxImportArchiveSize = 2 << 30 // 2GB upload
+	importTimeout        = 30 * time.Minute
+)
+
+// importLimits bound what is extracted from a ZIP import. Sizes are checked
+// against the bytes actually decompressed, not the sizes the archive claims.
+type importLimits struct {
+	Files     int   // images per archive
+	FileSize  int64 // per decompressed image
+	TotalSize int64 // decompressed in total
+}
+
+var defaultImportLimits = importLimits{
+	Files:     1000,
+	FileSize:  200 << 20,
+	TotalSize: 4 << 30,
+}
+
+// skippedFile is an archive entry that was not imported, and why
+type skippedFile struct {
+	Name   string
+	Reason string
+}
+
+// importEntry is an image extracted from the archive into temp storage
+type importEntry struct {
+	Name    string // path inside the archive
+	Path    string // extracted temp file
+	Size    int64
+	TakenAt time.Time
+	HasDate bool
+}
+
+func (app *Application) ImportGalleryForm(w http.ResponseWriter, r *http.Request) {
+	app.render(w, r, "admin/import_gallery.html", map[string]interface{}{
+		"Title":      "Import Gallery",
+		"ActiveLink": "galleries",
+	})
+}
+
+// ImportGallery creates a gallery from a ZIP archive of photos. Every image
+// in the archive is run through the normal upload pipeline and attached in
+// filename or EXIF-date order; anything else is reported back as skipped.
+func (app *Application) ImportGallery(w http.ResponseWriter, r *http.Request) {
+	// Large archives take longer than the server-wide timeouts allow
+	rc := http.NewResponseController(w)
+	rc.SetReadDeadline(time.Now().Add(importTimeout))
+	rc.SetWriteDeadline(time.Now().Add(importTimeout))
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportArchiveSize)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		http.Error(w, "Archive is too large or the upload failed", http.StatusBadRequest)
+		return
+	}
+
+	file, header, err := r.FormFile("archive")
+	if err != nil {
+		http.Error(w, "Missing archive", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	if !strings.EqualFold(filepath.Ext(header.Filename), ".zip") {
+		http.Error(w, "Please upload a .zip archive", http.StatusBadRequest)
+		return
+	}
+
+	archive, err := zip.NewReader(file, header.Size)
+	if err != nil {
+		http.Error(w, "Could not read ZIP archive", http.StatusBadRequest)
+		return
+	}
+
+	tmpDir, err := os.MkdirTemp("", "ikm-import-")
+	if err != nil {
+		log.Printf("❌ Failed to create import dir: %v", err)
+		http.Error(w, "Failed to start import", http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(tmpDir)
+
+	entries, skipped := extractImportEntries(archive, tmpDir, defaultImportLimits)
+	if len(entries) == 0 {
+		http.Error(w, "No importable images found in archive", http.StatusBadRequest)
+		return
+	}
+	sortImportEntries(entries, r.FormValue("order"))
+
+	title := strings.TrimSpace(r.FormValue("title"))
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(header.Filename), filepath.Ext(header.Filename))
+	}
+
+	ctx := r.Context()
+	wm, err := app.loadWatermark(ctx)
+	if err != nil {
+		log.Printf("❌ Error loading watermark: %v", err)
+		http.Error(w, "Error loading watermark settings", http.StatusInternalServerError)
+		return
+	}
+
+	// The gallery is only created once there's an image to put in it, so an
+	// archive where nothing imports doesn't leave an empty gallery behind
+	galleryID := 0
+	imported := 0
+	for _, e := range entries {
+		f, err := os.Open(e.Path)
+		if err != nil {
+			skipped = append(skipped, skippedFile{e.Name, "could not be read"})
+			continue
+		}
+
+		contentType := mime.TypeByExtension(strings.ToLower(filepath.Ext(e.Name)))
+		media, err := app.processUpload(ctx, f, e.Size, contentType, path.Base(e.Name), wm)
+		f.Close()
+		if err != nil {
+			log.Printf("⚠️ Skipping %s from import: %v", e.Name, err)
+			skipped = append(skipped, skippedFile{e.Name, "not a readable image"})
+			continue
+		}
+
+		if galleryID == 0 {
+			galleryID, err = app.GalleryModel.CreateForImport(title, utils.Slugify(title))
+			if err != nil {
+				log.Printf("❌ Failed to create gallery %q: %v", title, err)
+				app.deleteMediaObjects(media.FileName, media.OriginalKey)
+				http.Error(w, "Error creating gallery", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if err := app.saveUploadedMedia(media, 0, galleryID); err != nil {
+			log.Printf("❌ Failed to save imported %s: %v", e.Name, err)
+			skipped = append(skipped, skippedFile{e.Name, "failed to save"})
+			continue
+		}
+
+		// First image in order becomes the cover
+		if imported == 0 {
+			if err := app.GalleryModel.SetCoverImage(galleryID, media.ID); err != nil {
+				log.Printf("⚠️ Failed to set cover for imported gallery %d: %v", galleryID, err)
+			}
+		}
+
+		imported++
+	}
+
+	var gallery *models.Gallery
+	if imported == 0 {
+		if galleryID > 0 {
+			app.removeEmptyGallery(galleryID)
+		}
+		log.Printf("⚠️ Import of %q saved no images (%d skipped)", header.Filename, len(skipped))
+	} else {
+		gallery, err = app.GalleryModel.GetByID(galleryID)
+		if err != nil {
+			http.Error(w, "Failed to load gallery", http.StatusInternalServerError)
+			return
+		}
+		log.Printf("✅ Imported %d images into gallery %d (%d skipped)", imported, galleryID, len(skipped))
+	}
+
+	app.renderPartialHTMX(w, "partials/import_report.html", map[string]interface{}{
+		"Gallery":  gallery,
+		"Imported": imported,
+		"Skipped":  skipped,
+	})
+}
+
+// removeEmptyGallery deletes a gallery an import created but couldn't save
+// any images into
+func (app *Application) removeEmptyGallery(id int) {
+	if err := app.GalleryModel.Delete(id); err != nil {
+		log.Printf("⚠️ Failed to remove empty imported gallery %d: %v", id, err)
+		return
+	}
+	if err := app.GalleryModel.Purge(id); err != nil {
+		log.Printf("⚠️ Failed to remove empty imported gallery %d: %v", id, err)
+	}
+}
+
+// extractImportEntries copies the images in archive into dir, enforcing the
+// import limits. Entries are written under generated names, never their
+// archive paths, so a crafted path can't escape dir.
+func extractImportEntries(archive *zip.Reader, dir string, limits importLimits) ([]*importEntry, []skippedFile) {
+	var entries []*importEntry
+	var skipped []skippedFile
+	var total int64
+
+	for _, zf := range archive.File {
+		name := zf.Name
+		base := path.Base(name)
+
+		switch {
+		case zf.FileInfo().IsDir():
+			continue
+		// macOS resource forks and dotfiles aren't photos anyone meant to include
+		case strings.HasPrefix(name, "__MACOSX/") || strings.HasPrefix(base, "."):
+			continue
+		case !isSafeArchivePath(name):
+			skipped = append(skipped, skippedFile{name, "unsafe path"})
+			continue
+		case !isImportableImage(base):
+			skipped = append(skipped, skippedFile{name, "not a supported image type"})
+			continue
+		case len(entries) >= limits.Files:
+			skipped = append(skipped, skippedFile{name, fmt.Sprintf("over the %d file limit", limits.Files)})
+			continue
+		case zf.UncompressedSize64 > uint64(limits.FileSize):
+			skipped = append(skipped, skippedFile{name, "file too large"})
+			continue
+		}
+
+		e, err := extractImportEntry(zf, filepath.Join(dir, fmt.Sprintf("%05d", len(entries))), limits.FileSize)
+		if err != nil {
+			skipped = append(skipped, skippedFile{name, err.Error()})
+			continue
+		}
+		if total+e.Size > limits.TotalSize {
+			os.Remove(e.Path)
+			skipped = append(skipped, skippedFile{name, "archive too large"})
+			continue
+		}
+		total += e.Size
+		entries = append(entries, e)
+	}
+
+	return entries, skipped
+}
+
+func extractImportEntry(zf *zip.File, dst string, maxSize int64) (*importEntry, error) {
+	src, err := zf.Open()
+	if err != nil {
+		return nil, fmt.Errorf("could not be read")
+	}
+	defer src.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return nil, fmt.Errorf("could not be extracted")
+	}
+	defer out.Close()
+
+	// Read one byte past the limit so an understated size is caught
+	n, err := io.Copy(out, io.LimitReader(src, maxSize+1))
+	if err != nil {
+		os.Remove(dst)
+		return nil, fmt.Errorf("corrupt archive entry")
+	}
+	if n > maxSize {
+		os.Remove(dst)
+		return nil, fmt.Errorf("file too large")
+	}
+
+	e := &importEntry{Name: zf.Name, Path: dst, Size: n}
+	if _, err := out.Seek(0, io.SeekStart); err == nil {
+		e.TakenAt, e.HasDate = utils.ExifDate(out)
+	}
+	return e, nil
+}
+
+// sortImportEntries orders entries by filename, or by EXIF capture date
+// when order is "exif". Photos without a date follow the dated ones.
+func sortImportEntries(entries []*importEntry, order string) {
+	byName := func(i, j int) bool {
+		return strings.ToLower(entries[i].Name) < strings.ToLower(entries[j].Name)
+	}
+
+	if order != "exif" {
+		sort.SliceStable(entries, byName)
+		return
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.HasDate != b.HasDate {
+			return a.HasDate
+		}
+		if a.HasDate && !a.TakenAt.Equal(b.TakenAt) {
+			return a.TakenAt.Before(b.TakenAt)
+		}
+		return byName(i, j)
+	})
+}
+
+// isSafeArchivePath rejects absolute paths, including Windows drive paths
+// whichever OS we run on, and any ".." component
+func isSafeArchivePath(name string) bool {
+	name = strings.ReplaceAll(name, `\`, "/")
+	if strings.HasPrefix(name, "/") || filepath.VolumeName(name) != "" {
+		return false
+	}
+	if len(name) >= 2 && name[1] == ':' {
+		return false
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+func isImportableImage(name string) bool {
+	_, err := imaging.FormatFromFilename(name)
+	return err == nil
+}
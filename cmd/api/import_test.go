@@ -0,0 +1,161 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"hash/crc32"
+	"strings"
+	"testing"
+)
+
+func TestIsSafeArchivePath(t *testing.T) {
+	cases := []struct {
+		name string
+		path string
+		want bool
+	}{
+		{"✅ plain file", "photo.jpg", true},
+		{"✅ nested folder", "wedding/day one/photo.jpg", true},
+		{"✅ dots in a name", "photo..final.jpg", true},
+		{"❌ parent directory", "../photo.jpg", false},
+		{"❌ parent in the middle", "wedding/../../etc/photo.jpg", false},
+		{"❌ windows parent", `wedding\..\..\photo.jpg`, false},
+		{"❌ absolute path", "/etc/photo.jpg", false},
+		{"❌ windows absolute path", `\Windows\photo.jpg`, false},
+		{"❌ drive letter", `C:\photo.jpg`, false},
+		{"❌ drive letter with slashes", "C:/photo.jpg", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isSafeArchivePath(tc.path); got != tc.want {
+				t.Errorf("isSafeArchivePath(%q) = %v, want %v", tc.path, got, tc.want)
+			}
+		})
+	}
+}
+
+// zipFile is an entry for buildZip. A claimedSize other than 0 is written
+// as the entry's uncompressed size in place of the real one.
+type zipFile struct {
+	name        string
+	size        int
+	claimedSize uint64
+}
+
+func buildZip(t *testing.T, files []zipFile) *zip.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		data := bytes.Repeat([]byte("x"), f.size)
+		if f.claimedSize == 0 {
+			w, err := zw.Create(f.name)
+			if err != nil {
+				t.Fatalf("Create %s failed: %v", f.name, err)
+			}
+			w.Write(data)
+			continue
+		}
+
+		w, err := zw.CreateRaw(&zip.FileHeader{
+			Name:               f.name,
+			Method:             zip.Store,
+			CRC32:              crc32.ChecksumIEEE(data),
+			CompressedSize64:   uint64(len(data)),
+			UncompressedSize64: f.claimedSize,
+		})
+		if err != nil {
+			t.Fatalf("CreateRaw %s failed: %v", f.name, err)
+		}
+		w.Write(data)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("NewReader failed: %v", err)
+	}
+	return r
+}
+
+func TestExtractImportEntries(t *testing.T) {
+	limits := importLimits{Files: 3, FileSize: 100, TotalSize: 250}
+
+	cases := []struct {
+		name        string
+		files       []zipFile
+		wantEntries []string
+		wantSkipped map[string]string
+	}{
+		{"✅ ordinary archive", []zipFile{
+			{name: "a.jpg", size: 10},
+			{name: "day/b.png", size: 10},
+		}, []string{"a.jpg", "day/b.png"}, nil},
+		{"✅ junk is left out quietly", []zipFile{
+			{name: "__MACOSX/._a.jpg", size: 10},
+			{name: "day/.DS_Store", size: 10},
+			{name: "a.jpg", size: 10},
+		}, []string{"a.jpg"}, nil},
+		{"❌ path traversal", []zipFile{
+			{name: "../../evil.jpg", size: 10},
+			{name: "day/../../evil.jpg", size: 10},
+			{name: "/etc/evil.jpg", size: 10},
+			{name: "a.jpg", size: 10},
+		}, []string{"a.jpg"}, map[string]string{
+			"../../evil.jpg":     "unsafe path",
+			"day/../../evil.jpg": "unsafe path",
+			"/etc/evil.jpg":      "unsafe path",
+		}},
+		{"❌ not an image", []zipFile{
+			{name: "notes.txt", size: 10},
+		}, nil, map[string]string{"notes.txt": "not a supported image type"}},
+		{"❌ too many files", []zipFile{
+			{name: "1.jpg", size: 10},
+			{name: "2.jpg", size: 10},
+			{name: "3.jpg", size: 10},
+			{name: "4.jpg", size: 10},
+		}, []string{"1.jpg", "2.jpg", "3.jpg"}, map[string]string{"4.jpg": "over the 3 file limit"}},
+		{"❌ file too large", []zipFile{
+			{name: "big.jpg", size: 101},
+			{name: "a.jpg", size: 100},
+		}, []string{"a.jpg"}, map[string]string{"big.jpg": "file too large"}},
+		{"❌ file larger than it claims", []zipFile{
+			{name: "liar.jpg", size: 500, claimedSize: 10},
+		}, nil, map[string]string{"liar.jpg": "corrupt archive entry"}},
+		{"❌ archive too large", []zipFile{
+			{name: "1.jpg", size: 100},
+			{name: "2.jpg", size: 100},
+			{name: "3.jpg", size: 100},
+		}, []string{"1.jpg", "2.jpg"}, map[string]string{"3.jpg": "archive too large"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			entries, skipped := extractImportEntries(buildZip(t, tc.files), dir, limits)
+
+			var names []string
+			for _, e := range entries {
+				names = append(names, e.Name)
+				if !strings.HasPrefix(e.Path, dir) {
+					t.Errorf("Expected %s to be extracted under %s, got %s", e.Name, dir, e.Path)
+				}
+			}
+			if strings.Join(names, ",") != strings.Join(tc.wantEntries, ",") {
+				t.Errorf("Expected entries %v, got %v", tc.wantEntries, names)
+			}
+
+			if len(skipped) != len(tc.wantSkipped) {
+				t.Fatalf("Expected %d skipped, got %v", len(tc.wantSkipped), skipped)
+			}
+			for _, s := range skipped {
+				if want, ok := tc.wantSkipped[s.Name]; !ok || s.Reason != want {
+					t.Errorf("Expected %s skipped for %q, got %q", s.Name, want, s.Reason)
+				}
+			}
+		})
+	}
+}
@@ -2,11 +2,16 @@ package main
 
 import (
 	"context"
+	ikmgo "ikm"
 	"ikm/models"
+	"ikm/utils"
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
 	"time"
+	_ "time/tzdata" // the runtime image has no zoneinfo for SITE_TIMEZONE
 
 	"github.com/getsentry/sentry-go"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -24,6 +29,27 @@ type Application struct {
 	SettingsModel *models.SettingsModel
 	ProjectModel  *models.ProjectModel
 
+	SelectionModel *models.SelectionModel
+	ShareLinkModel *models.ShareLinkModel
+	CategoryModel  *models.CategoryModel
+	OutboxModel    *models.OutboxModel
+
+	// Outgoing email and the templates it's rendered from
+	Mailer utils.Mailer
+	Emails *utils.EmailTemplates
+
+	// Checks the captcha on the contact form
+	Captcha utils.CaptchaVerifier
+
+	// Nudges the outbox sender when an email is queued
+	outboxWake chan struct{}
+
+	// Chunked uploads in progress
+	Uploads *UploadStore
+
+	// How long deleted items stay in the trash before they're purged
+	TrashRetention time.Duration
+
 	// S3 configuration
 	S3Client *minio.Client
 	S3Bucket string
@@ -109,11 +135,52 @@ func main() {
 		log.Fatalf("Database connection test failed: %v", err)
 	}
 
+	// Temporary storage for chunked uploads
+	uploadDir := os.Getenv("UPLOAD_TMP_DIR")
+	if uploadDir == "" {
+		uploadDir = filepath.Join(os.TempDir(), "ikm-uploads")
+	}
+	uploads, err := NewUploadStore(uploadDir)
+	if err != nil {
+		log.Fatalf("Unable to set up upload storage: %v", err)
+	}
+
+	// Trash retention, in days
+	trashRetentionDays := 30
+	if v := os.Getenv("TRASH_RETENTION_DAYS"); v != "" {
+		trashRetentionDays, err = strconv.Atoi(v)
+		if err != nil || trashRetentionDays < 1 {
+			log.Fatalf("Invalid TRASH_RETENTION_DAYS: %q", v)
+		}
+	}
+
+	// Time zone schedules are entered in
+	siteLocation, err = loadSiteLocation()
+	if err != nil {
+		log.Fatalf("Invalid SITE_TIMEZONE: %v", err)
+	}
+
 	// Load all templates
 	err = LoadTemplates()
 	if err != nil {
 		log.Fatalf("Failed to load templates: %v", err)
 	}
+	emails, err := utils.LoadEmailTemplates(ikmgo.EmbeddedFiles, "templates/emails")
+	if err != nil {
+		log.Fatalf("Failed to load email templates: %v", err)
+	}
+
+	// Outgoing email
+	mailer, err := utils.NewSMTPMailerFromEnv()
+	if err != nil {
+		log.Fatalf("Unable to set up email: %v", err)
+	}
+
+	// Contact form captcha
+	captcha, err := utils.NewCaptchaVerifierFromEnv()
+	if err != nil {
+		log.Fatalf("Unable to set up the captcha: %v", err)
+	}
 
 	// Initialize application struct
 	app := &Application{
@@ -125,6 +192,19 @@ func main() {
 		SettingsModel: &models.SettingsModel{DB: dbPool},
 		ProjectModel:  &models.ProjectModel{DB: dbPool},
 
+		SelectionModel: &models.SelectionModel{DB: dbPool},
+		ShareLinkModel: &models.ShareLinkModel{DB: dbPool},
+		CategoryModel:  &models.CategoryModel{DB: dbPool},
+		OutboxModel:    &models.OutboxModel{DB: dbPool},
+		outboxWake:     make(chan struct{}, 1),
+
+		Mailer:  mailer,
+		Emails:  emails,
+		Captcha: captcha,
+
+		Uploads:        uploads,
+		TrashRetention: time.Duration(trashRetentionDays) * 24 * time.Hour,
+
 		S3Client: s3Client,
 		S3Bucket: s3Bucket,
 	}
@@ -133,6 +213,15 @@ func main() {
 		log.Fatal(err)
 	}
 
+	// Publish and unpublish on schedule
+	go app.runScheduler(context.Background())
+
+	// Send queued email, retrying failures
+	go app.runOutbox(context.Background())
+
+	// Empty the trash of anything past its retention period
+	go app.runTrashPurge(context.Background())
+
 	// Ensure at least one admin user exists
 	if err := models.EnsureAdminUserExists(app.UserModel); err != nil {
 		log.Fatalf("❌ Error bootstrapping admin user: %v", err)
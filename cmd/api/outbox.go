@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"ikm/models"
+	"ikm/utils"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// The outbox sender wakes this often to retry failed emails; new emails
+// nudge it so they don't wait for the next tick
+const outboxInterval = 30 * time.Second
+
+// How many emails the sender claims at once
+const outboxBatchSize = 20
+
+// How long one email may take to send before it counts as failed. This is
+// well under models.OutboxLease, so no other sender claims the email while
+// it's in flight.
+const outboxSendTimeout = time.Minute
+
+// queueEmail renders the named email template and puts the result in the
+// outbox. It returns the outbox email's ID.
+func (app *Application) queueEmail(from, to, subject, name string, data interface{}) (int, error) {
+	html, text, err := app.Emails.Render(name, data)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := app.OutboxModel.Enqueue(name, from, to, subject, html, text)
+	if err != nil {
+		return 0, fmt.Errorf("failed to queue email: %w", err)
+	}
+	app.wakeOutbox()
+	return id, nil
+}
+
+// wakeOutbox tells the sender there's email due without waiting for it
+func (app *Application) wakeOutbox() {
+	select {
+	case app.outboxWake <- struct{}{}:
+	default:
+	}
+}
+
+// runOutbox sends outbox emails until ctx is done. Claiming leases each
+// email to one sender, so running several instances side by side is safe.
+func (app *Application) runOutbox(ctx context.Context) {
+	ticker := time.NewTicker(outboxInterval)
+	defer ticker.Stop()
+
+	for {
+		app.sendDueEmails()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-app.outboxWake:
+		}
+	}
+}
+
+func (app *Application) sendDueEmails() {
+	for {
+		claimedAt := time.Now()
+		emails, err := app.OutboxModel.ClaimDue(outboxBatchSize)
+		if err != nil {
+			log.Printf("❌ Error claiming outbox emails: %v", err)
+			return
+		}
+
+		for i, e := range emails {
+			// Slow sends can use up the batch's lease. What's left is
+			// claimed again once it runs out.
+			if time.Since(claimedAt)+outboxSendTimeout > models.OutboxLease {
+				log.Printf("⚠️ Outbox lease running out; leaving %d emails for the next run", len(emails)-i)
+				return
+			}
+
+			msg := utils.Message{From: e.From, To: e.To, Subject: e.Subject, HTML: e.HTMLBody, Text: e.TextBody}
+			ctx, cancel := context.WithTimeout(context.Background(), outboxSendTimeout)
+			err := app.Mailer.Send(ctx, msg)
+			cancel()
+			if err != nil {
+				log.Printf("❌ Error sending outbox email %d to %s (attempt %d): %v", e.ID, e.To, e.Attempts+1, err)
+				if err := app.OutboxModel.MarkFailed(e.ID, err); err != nil {
+					log.Printf("❌ Error recording failed outbox email %d: %v", e.ID, err)
+				}
+				continue
+			}
+
+			if err := app.OutboxModel.MarkSent(e.ID); err != nil {
+				log.Printf("❌ Error recording sent outbox email %d: %v", e.ID, err)
+				continue
+			}
+			log.Printf("✅ Sent outbox email %d to %s", e.ID, e.To)
+		}
+
+		if len(emails) < outboxBatchSize {
+			return
+		}
+	}
+}
+
+// AdminOutbox lists outbox emails in one status, pending by default
+func (app *Application) AdminOutbox(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case models.OutboxPending, models.OutboxDead, models.OutboxSent:
+	default:
+		status = models.OutboxPending
+	}
+
+	emails, err := app.OutboxModel.GetByStatus(status, 100)
+	if err != nil {
+		log.Printf("❌ Error fetching outbox: %v", err)
+		http.Error(w, "Error fetching outbox", http.StatusInternalServerError)
+		return
+	}
+
+	counts, err := app.OutboxModel.StatusCounts()
+	if err != nil {
+		log.Printf("❌ Error counting outbox: %v", err)
+		http.Error(w, "Error fetching outbox", http.StatusInternalServerError)
+		return
+	}
+
+	app.render(w, r, "admin/outbox.html", map[string]interface{}{
+		"Title":       "Outbox",
+		"Emails":      emails,
+		"Status":      status,
+		"Statuses":    models.OutboxStatuses,
+		"Counts":      counts,
+		"MaxAttempts": models.OutboxMaxAttempts,
+		"ActiveLink":  "contacts",
+	})
+}
+
+// ResendOutboxEmail queues a dead or sent email again
+func (app *Application) ResendOutboxEmail(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid email ID", http.StatusBadRequest)
+		return
+	}
+
+	email, err := app.OutboxModel.GetByID(id)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if err := app.OutboxModel.Resend(id); err != nil {
+		log.Printf("❌ Error resending outbox email %d: %v", id, err)
+		http.Error(w, "Error resending email", http.StatusInternalServerError)
+		return
+	}
+	app.wakeOutbox()
+
+	http.Redirect(w, r, "/admin/outbox?status="+email.Status, http.StatusSeeOther)
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"ikm/models"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Client galleries double as proofing galleries: once unlocked, visitors can
+// favourite images and submit their selection back to us. A client is
+// normally sent a share link, the secret link to their gallery; the gallery
+// password is the fallback for clients who were only given that.
+
+// hasClientAccess reports whether the visitor may open a client gallery,
+// either with its password or a share link in the URL. A share link is
+// remembered in its own cookie for as long as the password would be, but
+// never past the link's expiry, and stops working as soon as the link is
+// revoked. Favourites and submissions then work once the link has been
+// opened.
+func (app *Application) hasClientAccess(w http.ResponseWriter, r *http.Request, gallery *models.Gallery) bool {
+	return HasGalleryAccess(gallery, r) ||
+		hasShareLink(w, r, "gallery", gallery.ID, galleryAccessTTL, app.ShareLinkModel)
+}
+
+// clientGallery loads the client gallery at {slug} for a visitor who has
+// unlocked it, writing an error response and returning nil otherwise.
+func (app *Application) clientGallery(w http.ResponseWriter, r *http.Request) *models.Gallery {
+	gallery, err := app.GalleryModel.GetBySlug(chi.URLParam(r, "slug"))
+	if err != nil || !gallery.ClientAccess {
+		http.NotFound(w, r)
+		return nil
+	}
+	if !app.hasClientAccess(w, r, gallery) {
+		http.Error(w, "Please enter the gallery password", http.StatusForbidden)
+		return nil
+	}
+	return gallery
+}
+
+// renderProofingGallery shows an unlocked client gallery in full, with the
+// visitor's favourites marked
+func (app *Application) renderProofingGallery(w http.ResponseWriter, r *http.Request, gallery *models.Gallery) {
+	count, err := app.GalleryModel.GetMediaCount(gallery.ID)
+	if err != nil {
+		log.Printf("❌ Error counting media: %v", err)
+		http.Error(w, "Error retrieving media", http.StatusInternalServerError)
+		return
+	}
+
+	media, err := app.GalleryModel.GetMediaPaginated(gallery.ID, count, 0)
+	if err != nil {
+		log.Printf("❌ Error fetching media: %v", err)
+		http.Error(w, "Error retrieving media", http.StatusInternalServerError)
+		return
+	}
+
+	selection, err := app.SelectionModel.GetForVisitor(gallery.ID, ProofingVisitorID(w, r))
+	if err != nil {
+		log.Printf("❌ Error loading selection: %v", err)
+		http.Error(w, "Error loading selection", http.StatusInternalServerError)
+		return
+	}
+
+	app.render(w, r, "gallery.html", map[string]interface{}{
+		"Title":        gallery.Title,
+		"Description":  gallery.Description,
+		"ActiveLink":   "galleries",
+		"Gallery":      gallery,
+		"Media":        media,
+		"Proofing":     true,
+		"Selection":    selection,
+		"NoIndex":      true,
+		"ParentURL":    "/galleries",
+		"CurrentLabel": gallery.Title,
+		"ParentTitle":  "Galleries",
+	})
+}
+
+// ToggleFavourite adds or removes an image from the visitor's selection
+func (app *Application) ToggleFavourite(w http.ResponseWriter, r *http.Request) {
+	gallery := app.clientGallery(w, r)
+	if gallery == nil {
+		return
+	}
+
+	mediaID, err := strconv.Atoi(chi.URLParam(r, "mediaID"))
+	if err != nil {
+		http.Error(w, "Invalid media ID", http.StatusBadRequest)
+		return
+	}
+
+	visitorID := ProofingVisitorID(w, r)
+	favourite, toggleErr := app.SelectionModel.ToggleFavourite(gallery.ID, visitorID, mediaID, gallery.SelectionLimit)
+	if toggleErr != nil && !errors.Is(toggleErr, models.ErrSelectionLimit) && !errors.Is(toggleErr, models.ErrSelectionSubmitted) {
+		log.Printf("❌ Error toggling favourite %d in gallery %d: %v", mediaID, gallery.ID, toggleErr)
+		http.Error(w, "Error updating selection", http.StatusBadRequest)
+		return
+	}
+
+	selection, err := app.SelectionModel.GetForVisitor(gallery.ID, visitorID)
+	if err != nil {
+		log.Printf("❌ Error loading selection: %v", err)
+		http.Error(w, "Error loading selection", http.StatusInternalServerError)
+		return
+	}
+
+	notice := ""
+	if errors.Is(toggleErr, models.ErrSelectionLimit) {
+		notice = fmt.Sprintf("You can pick up to %d images.", gallery.SelectionLimit)
+	}
+
+	// The button swaps itself; the summary panel updates out of band
+	app.renderPartialHTMX(w, "partials/favourite_button.html", map[string]interface{}{
+		"Gallery":   gallery,
+		"MediaID":   mediaID,
+		"Favourite": favourite,
+		"Locked":    selection.Submitted(),
+	})
+	app.renderPartialHTMX(w, "partials/selection_summary.html", map[string]interface{}{
+		"Gallery":   gallery,
+		"Selection": selection,
+		"Notice":    notice,
+		"OOB":       true,
+	})
+}
+
+// SubmitSelection sends the visitor's favourites in with their details
+func (app *Application) SubmitSelection(w http.ResponseWriter, r *http.Request) {
+	gallery := app.clientGallery(w, r)
+	if gallery == nil {
+		return
+	}
+
+	visitorID := ProofingVisitorID(w, r)
+	name := strings.TrimSpace(r.FormValue("name"))
+	email := strings.TrimSpace(r.FormValue("email"))
+	comment := strings.TrimSpace(r.FormValue("comment"))
+
+	notice := ""
+	if name == "" {
+		notice = "Please tell us your name."
+	} else if err := app.SelectionModel.Submit(gallery.ID, visitorID, name, email, comment); err != nil {
+		log.Printf("❌ Error submitting selection for gallery %d: %v", gallery.ID, err)
+		notice = "Pick at least one image before sending your selection."
+	} else {
+		log.Printf("✅ Selection submitted for gallery %d by %s", gallery.ID, name)
+	}
+
+	selection, err := app.SelectionModel.GetForVisitor(gallery.ID, visitorID)
+	if err != nil {
+		log.Printf("❌ Error loading selection: %v", err)
+		http.Error(w, "Error loading selection", http.StatusInternalServerError)
+		return
+	}
+
+	// A submitted selection locks the favourite buttons, so reload the grid
+	if selection.Submitted() && notice == "" {
+		w.Header().Set("HX-Refresh", "true")
+	}
+
+	app.renderPartialHTMX(w, "partials/selection_summary.html", map[string]interface{}{
+		"Gallery":   gallery,
+		"Selection": selection,
+		"Notice":    notice,
+	})
+}
+
+// AdminGallerySelections lists the selections clients have submitted
+func (app *Application) AdminGallerySelections(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid gallery ID", http.StatusBadRequest)
+		return
+	}
+
+	gallery, err := app.GalleryModel.GetByID(id)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	selections, err := app.SelectionModel.GetSubmitted(id)
+	if err != nil {
+		log.Printf("❌ Error fetching selections: %v", err)
+		http.Error(w, "Error fetching selections", http.StatusInternalServerError)
+		return
+	}
+
+	app.render(w, r, "admin/gallery_selections.html", map[string]interface{}{
+		"Title":      "Client Selections",
+		"Gallery":    gallery,
+		"Selections": selections,
+		"ActiveLink": "galleries",
+	})
+}
+
+// ExportSelectionCSV downloads the file names in a submitted selection
+func (app *Application) ExportSelectionCSV(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "selectionID"))
+	if err != nil {
+		http.Error(w, "Invalid selection ID", http.StatusBadRequest)
+		return
+	}
+
+	galleryID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid gallery ID", http.StatusBadRequest)
+		return
+	}
+
+	// Selections are only exported through the gallery they belong to
+	selection, err := app.SelectionModel.GetByID(id)
+	if err != nil || selection.GalleryID != galleryID {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="selection-%d.csv"`, selection.ID))
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"original_name", "file_name"})
+	for _, m := range selection.Items {
+		cw.Write([]string{originalFileName(m.FileName), m.FileName})
+	}
+	cw.Flush()
+
+	if err := cw.Error(); err != nil {
+		log.Printf("❌ Error writing selection CSV: %v", err)
+	}
+}
+
+// SetGallerySelectionLimit updates how many favourites a client can pick
+func (app *Application) SetGallerySelectionLimit(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid gallery ID", http.StatusBadRequest)
+		return
+	}
+
+	limit, err := strconv.Atoi(r.FormValue("selection_limit"))
+	if err != nil || limit < 0 {
+		http.Error(w, "Invalid selection limit", http.StatusBadRequest)
+		return
+	}
+
+	if err := app.GalleryModel.SetSelectionLimit(id, limit); err != nil {
+		log.Printf("❌ Error updating selection limit: %v", err)
+		http.Error(w, "Error updating selection limit", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// Uploads are stored as "<unix nanos>_<original name>"
+var uploadNamePrefix = regexp.MustCompile(`^\d+_`)
+
+// originalFileName recovers the name a file had on the photographer's disk
+func originalFileName(fileName string) string {
+	return uploadNamePrefix.ReplaceAllString(fileName, "")
+}
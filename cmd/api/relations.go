@@ -0,0 +1,116 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// relatedProjectsLimit caps the related project cards on a project page
+const relatedProjectsLimit = 3
+
+// ProjectRelations renders the linked gallery and related project
+// checkboxes of a project
+func (app *Application) ProjectRelations(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+	app.renderProjectRelations(w, id)
+}
+
+// SetProjectRelations saves which galleries a project links to and which
+// projects are related to it
+func (app *Application) SetProjectRelations(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	if err := app.ProjectModel.SetGalleries(id, formIDs(r, "gallery_id")); err != nil {
+		log.Printf("❌ Error linking galleries to project %d: %v", id, err)
+		http.Error(w, "Error saving galleries", http.StatusInternalServerError)
+		return
+	}
+
+	var relatedIDs []int
+	for _, rid := range formIDs(r, "related_id") {
+		if rid != id {
+			relatedIDs = append(relatedIDs, rid)
+		}
+	}
+	if err := app.ProjectModel.SetRelated(id, relatedIDs); err != nil {
+		log.Printf("❌ Error setting related projects of project %d: %v", id, err)
+		http.Error(w, "Error saving related projects", http.StatusInternalServerError)
+		return
+	}
+
+	app.renderProjectRelations(w, id)
+}
+
+func (app *Application) renderProjectRelations(w http.ResponseWriter, projectID int) {
+	galleries, err := app.GalleryModel.GetAll()
+	if err != nil {
+		log.Printf("❌ Error fetching galleries: %v", err)
+		http.Error(w, "Error fetching galleries", http.StatusInternalServerError)
+		return
+	}
+
+	projects, err := app.ProjectModel.GetAll()
+	if err != nil {
+		log.Printf("❌ Error fetching projects: %v", err)
+		http.Error(w, "Error fetching projects", http.StatusInternalServerError)
+		return
+	}
+
+	galleryIDs, err := app.ProjectModel.GetGalleryIDs(projectID)
+	if err != nil {
+		log.Printf("❌ Error fetching galleries of project %d: %v", projectID, err)
+		http.Error(w, "Error fetching galleries", http.StatusInternalServerError)
+		return
+	}
+
+	relatedIDs, err := app.ProjectModel.GetRelatedIDs(projectID)
+	if err != nil {
+		log.Printf("❌ Error fetching related projects of project %d: %v", projectID, err)
+		http.Error(w, "Error fetching projects", http.StatusInternalServerError)
+		return
+	}
+
+	app.renderPartialHTMX(w, "partials/project_relations.html", map[string]interface{}{
+		"ProjectID":       projectID,
+		"Galleries":       galleries,
+		"Projects":        projects,
+		"SelectedGallery": idSet(galleryIDs),
+		"SelectedRelated": idSet(relatedIDs),
+	})
+}
+
+// formIDs reads the integer values of a repeated form field, skipping any
+// that don't parse
+func formIDs(r *http.Request, field string) []int {
+	var ids []int
+	for _, v := range r.Form[field] {
+		if id, err := strconv.Atoi(v); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func idSet(ids []int) map[int]bool {
+	set := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
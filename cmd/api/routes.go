@@ -33,7 +33,11 @@ func (app *Application) routes() http.Handler {
 	r.Get("/about", app.About)
 	r.Get("/contact", app.Contact)
 	r.Get("/galleries", app.PublicGalleriesList)
+	r.Get("/galleries/{category}", app.PublicGalleriesList)
 	r.Get("/gallery/{slug}", app.GalleryView)
+	r.Post("/gallery/{slug}/unlock", app.GalleryUnlock)
+	r.Post("/gallery/{slug}/favourite/{mediaID}", app.ToggleFavourite)
+	r.Post("/gallery/{slug}/selection", app.SubmitSelection)
 	r.Get("/projects", app.PublicProjectsList)
 	r.Get("/project/{slug}", app.PublicProjectView)
 
@@ -61,33 +65,62 @@ func (app *Application) routes() http.Handler {
 
 		// Galleries
 		r.Get("/galleries", app.AdminGalleries)
+		r.Post("/galleries/update-order", app.UpdateGalleryOrder)
 		r.Get("/gallery/create", app.CreateGalleryForm)
 		r.Post("/gallery/create", app.CreateGallery)
+		r.Get("/gallery/import", app.ImportGalleryForm)
+		r.Post("/gallery/import", app.ImportGallery)
 		r.Delete("/gallery/{id}", app.DeleteGallery)
+		r.Post("/gallery/{id}/duplicate", app.DuplicateGallery)
 		r.Post("/gallery/feature/{id}", app.SetFeaturedGallery)
 		r.Get("/gallery/{id}", app.EditGalleryForm)
 		r.Get("/gallery/edit/{id}", app.EditGalleryForm)
 		r.Post("/gallery/update/{id}", app.UpdateGallery)
 		r.Post("/gallery/{galleryID}/cover", app.SetCoverImage)
 		r.Post("/gallery/{id}/publish", app.SetGalleryVisibility)
+		r.Get("/gallery/{id}/schedule", app.GalleryScheduleView)
+		r.Post("/gallery/{id}/schedule", app.SetGallerySchedule)
+		r.Post("/gallery/{id}/watermark", app.SetGalleryWatermark)
+		r.Post("/gallery/{id}/client-access", app.SetGalleryClientAccess)
+		r.Post("/gallery/{id}/selection-limit", app.SetGallerySelectionLimit)
+		r.Get("/gallery/{id}/selections", app.AdminGallerySelections)
+		r.Get("/gallery/{id}/selections/{selectionID}.csv", app.ExportSelectionCSV)
+		r.Post("/gallery/{id}/regenerate", app.RegenerateGalleryMedia)
+		r.Get("/gallery/{id}/categories", app.GalleryCategories)
+		r.Post("/gallery/{id}/categories", app.SetGalleryCategories)
+		r.Get("/gallery/{id}/parent", app.GalleryParent)
+		r.Post("/gallery/{id}/parent", app.SetGalleryParent)
 		// HTMX: Gallery Info Edit View
 		r.Get("/gallery/info/{id}", app.AdminGalleryInfoView)
 		r.Get("/gallery/info/edit/{id}", app.AdminGalleryInfoEdit)
 
+		// Gallery categories
+		r.Get("/categories", app.AdminCategories)
+		r.Post("/categories", app.CreateCategory)
+		r.Post("/categories/{id}", app.UpdateCategory)
+		r.Delete("/categories/{id}", app.DeleteCategory)
+
 		// Projects
 		r.Get("/projects", app.AdminProjects)           // list view
 		r.Get("/project/create", app.CreateProjectForm) // show form
 		r.Post("/project/create", app.CreateProject)    // handle form submit
 		r.Delete("/project/{id}", app.DeleteProject)    // delete project
+		r.Post("/project/{id}/duplicate", app.DuplicateProject)
 		r.Get("/project/{id}", app.EditProjectForm)
 		r.Get("/project/edit/{id}", app.EditProjectForm)        // show edit form
 		r.Post("/project/edit/{id}", app.UpdateProject)         // handle update
 		r.Post("/project/{id}/cover", app.SetProjectCoverImage) // HTMX: update cover
 		r.Post("/project/update-order", app.UpdateProjectMediaOrder)
+		r.Post("/projects/update-order", app.UpdateProjectOrder)
 		r.Post("/project/{id}/publish", app.SetProjectVisibility)
+		r.Get("/project/{id}/schedule", app.ProjectScheduleView)
+		r.Post("/project/{id}/schedule", app.SetProjectSchedule)
 		r.Get("/project/{id}/info", app.ProjectInfoView)
 		r.Get("/project/{id}/info/edit", app.ProjectInfoEdit)
 		r.Post("/project/{id}/info", app.ProjectInfoUpdate)
+		r.Post("/project/{id}/preview", app.ProjectBodyPreview)
+		r.Get("/project/{id}/relations", app.ProjectRelations)
+		r.Post("/project/{id}/relations", app.SetProjectRelations)
 
 		//Users
 		r.Get("/users", app.AdminUsers)
@@ -100,7 +133,25 @@ func (app *Application) routes() http.Handler {
 		r.Get("/media/upload-modal", app.UploadMediaModal)
 		r.Get("/media/upload", app.UploadMediaForm)
 		r.Post("/media/upload", app.UploadMedia)
+		// Chunked, resumable uploads
+		r.Post("/media/uploads", app.CreateUpload)
+		r.Head("/media/uploads/{id}", app.UploadStatus)
+		r.Patch("/media/uploads/{id}", app.UploadChunk)
+		r.Delete("/media/uploads/{id}", app.CancelUpload)
 		r.Delete("/media/{id}", app.DeleteMedia)
+		r.Post("/media/{id}/replace", app.ReplaceMedia)
+		r.Get("/media/{id}/versions", app.MediaVersions)
+		r.Post("/media/{id}/versions/{versionID}/restore", app.RestoreMediaVersion)
+
+		// Trash
+		r.Get("/trash", app.AdminTrash)
+		r.Post("/trash/{kind}/{id}/restore", app.RestoreTrashed)
+		r.Delete("/trash/{kind}/{id}", app.PurgeTrashed)
+
+		// Share links for unpublished galleries and projects
+		r.Get("/share-links", app.AdminShareLinks)
+		r.Post("/share-links", app.CreateShareLink)
+		r.Post("/share-links/{id}/revoke", app.RevokeShareLink)
 		r.Post("/media/attach", app.AttachMediaToItem)
 		r.Post("/media/update-order-bulk", app.UpdateMediaOrderBulk)
 		r.Put("/media/unlink", app.UnlinkMediaFromItem)
@@ -108,6 +159,23 @@ func (app *Application) routes() http.Handler {
 
 		// Contacts
 		r.Get("/contacts", app.AdminContacts)
+		r.Get("/contacts/{id}", app.AdminContactView)
+		r.Post("/contacts/{id}/status", app.SetContactStatus)
+		r.Post("/contacts/{id}/assign", app.AssignContact)
+		r.Post("/contacts/{id}/notes", app.UpdateContactNotes)
+		r.Post("/contacts/{id}/reply", app.ReplyContact)
+
+		// Outgoing email
+		r.Get("/outbox", app.AdminOutbox)
+		r.Post("/outbox/{id}/resend", app.ResendOutboxEmail)
+
+		// Home page builder
+		r.Get("/home", app.AdminHome)
+		r.Get("/home/sections", app.HomeSections)
+		r.Post("/home/sections", app.AddHomeSection)
+		r.Post("/home/sections/{index}", app.UpdateHomeSection)
+		r.Post("/home/sections/{index}/move", app.MoveHomeSection)
+		r.Delete("/home/sections/{index}", app.DeleteHomeSection)
 
 		// Settings
 		r.Get("/settings", app.AdminSettings)
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"ikm/models"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// How often the scheduler looks for galleries and projects due to publish
+// or unpublish. Public pages only check the published flag, so this is also
+// how late a scheduled change can be.
+const scheduleInterval = time.Minute
+
+// datetime-local inputs send times without a zone; they're read in the site
+// time zone
+const scheduleInputLayout = "2006-01-02T15:04"
+
+// siteLocation is the time zone schedules are entered and shown in, set from
+// SITE_TIMEZONE at startup. The server's own zone is often UTC in a container.
+var siteLocation = time.Local
+
+// loadSiteLocation returns the time zone named by SITE_TIMEZONE, such as
+// "Australia/Sydney", or the server's zone when it isn't set
+func loadSiteLocation() (*time.Location, error) {
+	name := os.Getenv("SITE_TIMEZONE")
+	if name == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(name)
+}
+
+// siteTime converts t to the site time zone for display
+func siteTime(t time.Time) time.Time {
+	return t.In(siteLocation)
+}
+
+// runScheduler applies scheduled publishing until ctx is done. The updates
+// are idempotent, so running several instances side by side is harmless.
+func (app *Application) runScheduler(ctx context.Context) {
+	ticker := time.NewTicker(scheduleInterval)
+	defer ticker.Stop()
+
+	for {
+		app.applySchedules()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (app *Application) applySchedules() {
+	if n, err := app.GalleryModel.ApplySchedule(); err != nil {
+		log.Printf("❌ Error applying gallery schedules: %v", err)
+	} else if n > 0 {
+		log.Printf("✅ Applied publishing schedule to %d galleries", n)
+	}
+
+	if n, err := app.ProjectModel.ApplySchedule(); err != nil {
+		log.Printf("❌ Error applying project schedules: %v", err)
+	} else if n > 0 {
+		log.Printf("✅ Applied publishing schedule to %d projects", n)
+	}
+}
+
+// parseScheduleTimes reads the publish_at and unpublish_at form fields. An
+// empty field means no scheduled time.
+func parseScheduleTimes(r *http.Request) (publishAt, unpublishAt *time.Time, err error) {
+	parse := func(field string) (*time.Time, error) {
+		v := r.FormValue(field)
+		if v == "" {
+			return nil, nil
+		}
+		t, err := time.ParseInLocation(scheduleInputLayout, v, siteLocation)
+		if err != nil {
+			return nil, err
+		}
+		return &t, nil
+	}
+
+	if publishAt, err = parse("publish_at"); err != nil {
+		return nil, nil, err
+	}
+	if unpublishAt, err = parse("unpublish_at"); err != nil {
+		return nil, nil, err
+	}
+	return publishAt, unpublishAt, nil
+}
+
+// GalleryScheduleView renders the publishing schedule panel of a gallery
+func (app *Application) GalleryScheduleView(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid gallery ID", http.StatusBadRequest)
+		return
+	}
+	app.renderGallerySchedule(w, r, id, "")
+}
+
+func (app *Application) SetGallerySchedule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid gallery ID", http.StatusBadRequest)
+		return
+	}
+
+	errMsg := ""
+	publishAt, unpublishAt, err := parseScheduleTimes(r)
+	if err != nil {
+		errMsg = "Please enter valid dates and times."
+	} else if err := app.GalleryModel.SetSchedule(id, publishAt, unpublishAt); errors.Is(err, models.ErrScheduleOrder) {
+		errMsg = "The unpublish time must come after the publish time."
+	} else if err != nil {
+		log.Printf("❌ Error scheduling gallery %d: %v", id, err)
+		errMsg = "Could not save the schedule."
+	}
+
+	app.renderGallerySchedule(w, r, id, errMsg)
+}
+
+func (app *Application) renderGallerySchedule(w http.ResponseWriter, r *http.Request, id int, errMsg string) {
+	gallery, err := app.GalleryModel.GetByID(id)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	app.renderPartialHTMX(w, "partials/publish_schedule.html", map[string]interface{}{
+		"Action":      "/admin/gallery/" + strconv.Itoa(id) + "/schedule",
+		"Published":   gallery.Published,
+		"PublishAt":   gallery.PublishAt,
+		"UnpublishAt": gallery.UnpublishAt,
+		"TimeZone":    siteLocation.String(),
+		"Error":       errMsg,
+	})
+}
+
+// ProjectScheduleView renders the publishing schedule panel of a project
+func (app *Application) ProjectScheduleView(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+	app.renderProjectSchedule(w, r, id, "")
+}
+
+func (app *Application) SetProjectSchedule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	errMsg := ""
+	publishAt, unpublishAt, err := parseScheduleTimes(r)
+	if err != nil {
+		errMsg = "Please enter valid dates and times."
+	} else if err := app.ProjectModel.SetSchedule(id, publishAt, unpublishAt); errors.Is(err, models.ErrScheduleOrder) {
+		errMsg = "The unpublish time must come after the publish time."
+	} else if err != nil {
+		log.Printf("❌ Error scheduling project %d: %v", id, err)
+		errMsg = "Could not save the schedule."
+	}
+
+	app.renderProjectSchedule(w, r, id, errMsg)
+}
+
+func (app *Application) renderProjectSchedule(w http.ResponseWriter, r *http.Request, id int, errMsg string) {
+	project, err := app.ProjectModel.GetByID(id)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	app.renderPartialHTMX(w, "partials/publish_schedule.html", map[string]interface{}{
+		"Action":      "/admin/project/" + strconv.Itoa(id) + "/schedule",
+		"Published":   project.Published,
+		"PublishAt":   project.PublishAt,
+		"UnpublishAt": project.UnpublishAt,
+		"TimeZone":    siteLocation.String(),
+		"Error":       errMsg,
+	})
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"html/template"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseScheduleTimes_SiteTimeZone(t *testing.T) {
+	canberra, err := time.LoadLocation("Australia/Canberra")
+	if err != nil {
+		t.Fatalf("LoadLocation failed: %v", err)
+	}
+	defer func(prev *time.Location) { siteLocation = prev }(siteLocation)
+	siteLocation = canberra
+
+	form := url.Values{"publish_at": {"2026-03-02T09:00"}, "unpublish_at": {""}}
+	r := httptest.NewRequest("POST", "/", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	publishAt, unpublishAt, err := parseScheduleTimes(r)
+	if err != nil {
+		t.Fatalf("parseScheduleTimes failed: %v", err)
+	}
+	if unpublishAt != nil {
+		t.Errorf("Expected no unpublish time, got %v", unpublishAt)
+	}
+	// 09:00 AEDT is 22:00 UTC the day before
+	want := time.Date(2026, 3, 1, 22, 0, 0, 0, time.UTC)
+	if publishAt == nil || !publishAt.Equal(want) {
+		t.Fatalf("Expected %v, got %v", want, publishAt)
+	}
+
+	// The form shows it back in the site zone, whatever zone it's read in as
+	tmpl := template.Must(template.New("t").Funcs(funcMap).Parse(
+		`{{ with .PublishAt }}{{ (siteTime .).Format "2006-01-02T15:04" }}{{ end }}`))
+	var b strings.Builder
+	utc := publishAt.UTC()
+	if err := tmpl.Execute(&b, map[string]any{"PublishAt": &utc}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if b.String() != "2026-03-02T09:00" {
+		t.Errorf("Expected the form to show 2026-03-02T09:00, got %q", b.String())
+	}
+}
@@ -1,6 +1,11 @@
 package main
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"ikm/models"
 	"net/http"
 	"time"
 
@@ -58,3 +63,143 @@ func ClearSession(w http.ResponseWriter) {
 	}
 	http.SetCookie(w, cookie)
 }
+
+// Client gallery access lasts a month, so a client can come back to their
+// shoot without asking for the password again. Like sessions, it is also
+// lost when the server restarts and the cookie keys change.
+const galleryAccessTTL = 30 * 24 * time.Hour
+
+func galleryAccessCookieName(galleryID int) string {
+	return fmt.Sprintf("gallery_access_%d", galleryID)
+}
+
+// galleryAccessStamp ties an access cookie to the current password, so
+// changing the password locks out everyone who unlocked the old one.
+func galleryAccessStamp(gallery *models.Gallery) string {
+	sum := sha256.Sum256([]byte(gallery.PasswordHash))
+	return hex.EncodeToString(sum[:8])
+}
+
+// SetGalleryAccess grants the visitor access to a client gallery
+func SetGalleryAccess(gallery *models.Gallery, w http.ResponseWriter) {
+	name := galleryAccessCookieName(gallery.ID)
+	encoded, err := cookieHandler.Encode(name, galleryAccessStamp(gallery))
+	if err == nil {
+		cookie := &http.Cookie{
+			Name:     name,
+			Value:    encoded,
+			Path:     "/",
+			HttpOnly: true,
+			Secure:   true,
+			// Lax so a link emailed to the client keeps working
+			SameSite: http.SameSiteLaxMode,
+			Expires:  time.Now().Add(galleryAccessTTL),
+		}
+		http.SetCookie(w, cookie)
+	}
+}
+
+// HasGalleryAccess reports whether the visitor has unlocked a client gallery
+func HasGalleryAccess(gallery *models.Gallery, r *http.Request) bool {
+	name := galleryAccessCookieName(gallery.ID)
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return false
+	}
+
+	var stamp string
+	if err := cookieHandler.Decode(name, cookie.Value, &stamp); err != nil {
+		return false
+	}
+	return stamp == galleryAccessStamp(gallery)
+}
+
+// A redeemed share link is remembered for a few hours, so refreshing the
+// page doesn't use up another of the link's views
+const previewAccessTTL = 4 * time.Hour
+
+// shareAccess is what a share link cookie holds: the link that was redeemed,
+// so revoking it takes effect, and when the cookie stops working
+type shareAccess struct {
+	LinkID  int
+	Expires int64
+}
+
+// shareCookieName names the share link cookie of a gallery or project; kind
+// is "gallery" or "project"
+func shareCookieName(kind string, id int) string {
+	return fmt.Sprintf("share_%s_%d", kind, id)
+}
+
+// SetShareAccess remembers that the visitor redeemed link for a gallery or
+// project. The cookie lasts ttl, but never past the link's expiry; both the
+// link ID and the expiry are signed so neither can be changed.
+func SetShareAccess(kind string, id int, link *models.ShareLink, ttl time.Duration, w http.ResponseWriter) {
+	expires := time.Now().Add(ttl)
+	if link.ExpiresAt != nil && link.ExpiresAt.Before(expires) {
+		expires = *link.ExpiresAt
+	}
+
+	name := shareCookieName(kind, id)
+	encoded, err := cookieHandler.Encode(name, shareAccess{LinkID: link.ID, Expires: expires.Unix()})
+	if err == nil {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			Value:    encoded,
+			Path:     "/",
+			HttpOnly: true,
+			Secure:   true,
+			SameSite: http.SameSiteLaxMode,
+			Expires:  expires,
+		})
+	}
+}
+
+// ShareAccessLink returns the ID of the share link the visitor redeemed for
+// a gallery or project, if their cookie for it hasn't expired. Whether the
+// link itself still works is up to the caller.
+func ShareAccessLink(kind string, id int, r *http.Request) (int, bool) {
+	name := shareCookieName(kind, id)
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return 0, false
+	}
+
+	var access shareAccess
+	if err := cookieHandler.Decode(name, cookie.Value, &access); err != nil {
+		return 0, false
+	}
+	if time.Now().Unix() >= access.Expires {
+		return 0, false
+	}
+	return access.LinkID, true
+}
+
+// ProofingVisitorID identifies a visitor making a selection in a client
+// gallery, issuing a new ID the first time they're seen.
+func ProofingVisitorID(w http.ResponseWriter, r *http.Request) string {
+	if cookie, err := r.Cookie("proofing_visitor"); err == nil {
+		var id string
+		if err := cookieHandler.Decode("proofing_visitor", cookie.Value, &id); err == nil && id != "" {
+			return id
+		}
+	}
+
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	id := hex.EncodeToString(buf)
+
+	encoded, err := cookieHandler.Encode("proofing_visitor", id)
+	if err == nil {
+		http.SetCookie(w, &http.Cookie{
+			Name:     "proofing_visitor",
+			Value:    encoded,
+			Path:     "/",
+			HttpOnly: true,
+			Secure:   true,
+			SameSite: http.SameSiteLaxMode,
+			Expires:  time.Now().Add(galleryAccessTTL),
+		})
+	}
+	return id
+}
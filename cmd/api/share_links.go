@@ -0,0 +1,164 @@
+package main
+
+import (
+	"ikm/models"
+	"ikm/utils"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// shareLinks checks and redeems share links; *models.ShareLinkModel
+type shareLinks interface {
+	Redeem(kind string, id int, token string) (*models.ShareLink, error)
+	IsActive(kind string, id, linkID int) (bool, error)
+}
+
+// canPreview reports whether the request may see an unpublished gallery or
+// project, where kind is "gallery" or "project": logged-in admins always
+// can, anyone else needs a working share link.
+func canPreview(w http.ResponseWriter, r *http.Request, kind string, id int, links shareLinks) bool {
+	if userID, err := GetSession(r); err == nil && userID != 0 {
+		return true
+	}
+	return hasShareLink(w, r, kind, id, previewAccessTTL, links)
+}
+
+// hasShareLink reports whether the visitor holds a share link for a gallery
+// or project. A link they redeemed before is remembered in a cookie for ttl
+// and keeps working until it's revoked or expires, without counting more
+// views; otherwise the ?share= token is redeemed and counted.
+func hasShareLink(w http.ResponseWriter, r *http.Request, kind string, id int, ttl time.Duration, links shareLinks) bool {
+	if linkID, ok := ShareAccessLink(kind, id, r); ok {
+		active, err := links.IsActive(kind, id, linkID)
+		if err != nil {
+			log.Printf("❌ Error checking share link %d: %v", linkID, err)
+		}
+		if active {
+			return true
+		}
+	}
+
+	token := r.URL.Query().Get("share")
+	if token == "" {
+		return false
+	}
+
+	link, err := links.Redeem(kind, id, token)
+	if err != nil {
+		log.Printf("❌ Error redeeming share link: %v", err)
+		return false
+	}
+	if link == nil {
+		return false
+	}
+	SetShareAccess(kind, id, link, ttl, w)
+	return true
+}
+
+// AdminShareLinks renders the share links panel for a gallery or project
+func (app *Application) AdminShareLinks(w http.ResponseWriter, r *http.Request) {
+	galleryID, _ := strconv.Atoi(r.FormValue("gallery_id"))
+	projectID, _ := strconv.Atoi(r.FormValue("project_id"))
+	app.renderShareLinks(w, r, galleryID, projectID, "")
+}
+
+func (app *Application) CreateShareLink(w http.ResponseWriter, r *http.Request) {
+	galleryID, _ := strconv.Atoi(r.FormValue("gallery_id"))
+	projectID, _ := strconv.Atoi(r.FormValue("project_id"))
+
+	var expiresAt *time.Time
+	if days, err := strconv.Atoi(r.FormValue("expires_in_days")); err == nil && days > 0 {
+		t := time.Now().AddDate(0, 0, days)
+		expiresAt = &t
+	}
+
+	maxViews, _ := strconv.Atoi(r.FormValue("max_views"))
+	label := strings.TrimSpace(r.FormValue("label"))
+
+	errMsg := ""
+	if _, err := app.ShareLinkModel.Create(galleryID, projectID, label, expiresAt, maxViews); err != nil {
+		log.Printf("❌ Error creating share link: %v", err)
+		errMsg = "Could not create the share link."
+	}
+
+	app.renderShareLinks(w, r, galleryID, projectID, errMsg)
+}
+
+func (app *Application) RevokeShareLink(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid share link ID", http.StatusBadRequest)
+		return
+	}
+
+	link, err := app.ShareLinkModel.GetByID(id)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if err := app.ShareLinkModel.Revoke(id); err != nil {
+		log.Printf("❌ Error revoking share link %d: %v", id, err)
+	}
+
+	var galleryID, projectID int
+	if link.GalleryID != nil {
+		galleryID = *link.GalleryID
+	}
+	if link.ProjectID != nil {
+		projectID = *link.ProjectID
+	}
+	app.renderShareLinks(w, r, galleryID, projectID, "")
+}
+
+func (app *Application) renderShareLinks(w http.ResponseWriter, r *http.Request, galleryID, projectID int, errMsg string) {
+	data := map[string]interface{}{
+		"GalleryID": galleryID,
+		"ProjectID": projectID,
+		"Error":     errMsg,
+	}
+
+	switch {
+	case galleryID > 0:
+		gallery, err := app.GalleryModel.GetByID(galleryID)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		links, err := app.ShareLinkModel.GetForGallery(galleryID)
+		if err != nil {
+			log.Printf("❌ Error fetching share links: %v", err)
+			http.Error(w, "Error fetching share links", http.StatusInternalServerError)
+			return
+		}
+		data["Links"] = links
+		data["ShareBase"] = utils.BuildCanonicalURL(r, "/gallery/"+gallery.Slug)
+		data["Published"] = gallery.Published
+		data["ClientAccess"] = gallery.ClientAccess
+	case projectID > 0:
+		project, err := app.ProjectModel.GetByID(projectID)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		links, err := app.ShareLinkModel.GetForProject(projectID)
+		if err != nil {
+			log.Printf("❌ Error fetching share links: %v", err)
+			http.Error(w, "Error fetching share links", http.StatusInternalServerError)
+			return
+		}
+		data["Links"] = links
+		data["ShareBase"] = utils.BuildCanonicalURL(r, "/project/"+project.Slug)
+		data["Published"] = project.Published
+	default:
+		http.Error(w, "Missing gallery or project ID", http.StatusBadRequest)
+		return
+	}
+
+	app.renderPartialHTMX(w, "partials/share_links.html", data)
+}
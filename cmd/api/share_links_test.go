@@ -0,0 +1,106 @@
+package main
+
+import (
+	"ikm/models"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeShareLinks holds one share link for gallery 7, token "good"
+type fakeShareLinks struct {
+	link     models.ShareLink
+	revoked  bool
+	redeemed int
+}
+
+func (f *fakeShareLinks) Redeem(kind string, id int, token string) (*models.ShareLink, error) {
+	f.redeemed++
+	if kind != "gallery" || id != 7 || token != "good" || f.revoked {
+		return nil, nil
+	}
+	link := f.link
+	return &link, nil
+}
+
+func (f *fakeShareLinks) IsActive(kind string, id, linkID int) (bool, error) {
+	return kind == "gallery" && id == 7 && linkID == f.link.ID && !f.revoked, nil
+}
+
+func TestCanPreview_RedeemsOncePerVisitor(t *testing.T) {
+	links := &fakeShareLinks{link: models.ShareLink{ID: 3}}
+
+	// First visit redeems the link and sets the share cookie
+	w := httptest.NewRecorder()
+	if !canPreview(w, httptest.NewRequest("GET", "/gallery/draft?share=good", nil), "gallery", 7, links) {
+		t.Fatal("Expected a working share link to allow a preview")
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("Expected a share cookie, got %d cookies", len(cookies))
+	}
+
+	cases := []struct {
+		name   string
+		url    string
+		kind   string
+		id     int
+		cookie bool
+		want   bool
+	}{
+		{"✅ reload with the cookie", "/gallery/draft?share=good", "gallery", 7, true, true},
+		{"✅ cookie without the token", "/gallery/draft", "gallery", 7, true, true},
+		{"❌ cookie for another gallery", "/gallery/other", "gallery", 8, true, false},
+		{"❌ cookie for a project with the same ID", "/project/draft", "project", 7, true, false},
+		{"❌ no cookie or token", "/gallery/draft", "gallery", 7, false, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tc.url, nil)
+			if tc.cookie {
+				r.AddCookie(cookies[0])
+			}
+			if got := canPreview(httptest.NewRecorder(), r, tc.kind, tc.id, links); got != tc.want {
+				t.Errorf("canPreview() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+	if links.redeemed != 1 {
+		t.Errorf("Expected the link to be redeemed once, got %d", links.redeemed)
+	}
+
+	// Revoking the link locks out visitors who already hold the cookie
+	links.revoked = true
+	r := httptest.NewRequest("GET", "/gallery/draft", nil)
+	r.AddCookie(cookies[0])
+	if canPreview(httptest.NewRecorder(), r, "gallery", 7, links) {
+		t.Error("Expected a revoked link's cookie to stop working")
+	}
+}
+
+func TestSetShareAccess_CappedAtLinkExpiry(t *testing.T) {
+	soon := time.Now().Add(10 * time.Minute)
+	cases := []struct {
+		name string
+		link *models.ShareLink
+		want time.Time
+	}{
+		{"✅ link without expiry", &models.ShareLink{ID: 1}, time.Now().Add(previewAccessTTL)},
+		{"✅ link expiring first", &models.ShareLink{ID: 1, ExpiresAt: &soon}, soon},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			SetShareAccess("gallery", 7, tc.link, previewAccessTTL, w)
+			cookies := w.Result().Cookies()
+			if len(cookies) != 1 {
+				t.Fatalf("Expected a share cookie, got %d cookies", len(cookies))
+			}
+			if d := cookies[0].Expires.Sub(tc.want); d > time.Second || d < -time.Second {
+				t.Errorf("Expected the cookie to expire at %v, got %v", tc.want, cookies[0].Expires)
+			}
+		})
+	}
+}
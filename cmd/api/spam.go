@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// Form fields the spam filter reads from the contact form
+const (
+	// A field hidden from people, so anything in it came from a bot
+	honeypotField = "website"
+	// When the form was served, signed so it can't be made up
+	formTokenField = "form_token"
+)
+
+// issueFormToken returns a signed token holding the time the form was
+// served, for formFillTime to read back when it's sent
+func issueFormToken() string {
+	token, err := cookieHandler.Encode(formTokenField, time.Now().UnixMilli())
+	if err != nil {
+		return ""
+	}
+	return token
+}
+
+// formFillTime returns how long ago token was issued. It reports false for
+// a missing or forged token, or one from before the server restarted.
+func formFillTime(token string) (time.Duration, bool) {
+	if token == "" {
+		return 0, false
+	}
+	var issued int64
+	if err := cookieHandler.Decode(formTokenField, token, &issued); err != nil {
+		return 0, false
+	}
+	return time.Since(time.UnixMilli(issued)), true
+}
+
+// clientIP is the address the request came from, without the port
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -32,6 +32,7 @@ var funcMap = template.FuncMap{
 		}
 		return d, nil
 	},
+	"siteTime": siteTime,
 	"add": func(a, b int) int {
 		return a + b
 	},
@@ -126,6 +127,11 @@ func LoadTemplates() error {
 			return err
 		}
 
+		// Emails have their own layouts; see utils.LoadEmailTemplates
+		if strings.HasPrefix(path, "templates/emails/") {
+			return nil
+		}
+
 		if strings.Contains(path, "/partials/") {
 			partials = append(partials, path)
 		} else {
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"ikm/models"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Trash is purged this often; items older than the retention period go
+const trashPurgeInterval = time.Hour
+
+// AdminTrash lists trashed galleries, projects and media
+func (app *Application) AdminTrash(w http.ResponseWriter, r *http.Request) {
+	galleries, err := app.GalleryModel.GetTrashed()
+	if err != nil {
+		log.Printf("❌ Error fetching trashed galleries: %v", err)
+		http.Error(w, "Error fetching trash", http.StatusInternalServerError)
+		return
+	}
+
+	projects, err := app.ProjectModel.GetTrashed()
+	if err != nil {
+		log.Printf("❌ Error fetching trashed projects: %v", err)
+		http.Error(w, "Error fetching trash", http.StatusInternalServerError)
+		return
+	}
+
+	media, err := app.MediaModel.GetTrashed()
+	if err != nil {
+		log.Printf("❌ Error fetching trashed media: %v", err)
+		http.Error(w, "Error fetching trash", http.StatusInternalServerError)
+		return
+	}
+
+	app.render(w, r, "admin/trash.html", map[string]interface{}{
+		"Title":         "Trash",
+		"Galleries":     galleries,
+		"Projects":      projects,
+		"Media":         media,
+		"RetentionDays": int(app.TrashRetention.Hours() / 24),
+		"ActiveLink":    "trash",
+	})
+}
+
+// RestoreTrashed takes a gallery, project or media item out of the trash
+func (app *Application) RestoreTrashed(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	kind := chi.URLParam(r, "kind")
+	switch kind {
+	case "gallery":
+		err = app.GalleryModel.Restore(id)
+	case "project":
+		err = app.ProjectModel.Restore(id)
+	case "media":
+		err = app.MediaModel.Restore(id)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Error restoring %s %d: %v", kind, id, err)
+		http.Error(w, "Error restoring item", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("✅ Restored %s %d from trash", kind, id)
+
+	// HTMX: Remove the row without reloading
+	w.WriteHeader(http.StatusOK)
+}
+
+// PurgeTrashed permanently deletes a trashed item, and for media its files
+func (app *Application) PurgeTrashed(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	kind := chi.URLParam(r, "kind")
+	switch kind {
+	case "gallery":
+		err = app.GalleryModel.Purge(id)
+	case "project":
+		err = app.ProjectModel.Purge(id)
+	case "media":
+		var files []models.StoredFile
+		files, err = app.MediaModel.Purge(id)
+		app.deleteStoredFiles(files)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Error purging %s %d: %v", kind, id, err)
+		http.Error(w, "Error deleting item", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// runTrashPurge permanently deletes anything that has been in the trash
+// longer than app.TrashRetention, until ctx is done
+func (app *Application) runTrashPurge(ctx context.Context) {
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := app.purgeExpiredTrash(time.Now().Add(-app.TrashRetention)); err != nil {
+			log.Printf("❌ Error purging trash: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (app *Application) purgeExpiredTrash(cutoff time.Time) error {
+	galleries, err := app.GalleryModel.PurgeTrashedBefore(cutoff)
+	if err != nil {
+		return fmt.Errorf("galleries: %w", err)
+	}
+
+	projects, err := app.ProjectModel.PurgeTrashedBefore(cutoff)
+	if err != nil {
+		return fmt.Errorf("projects: %w", err)
+	}
+
+	files, err := app.MediaModel.PurgeTrashedBefore(cutoff)
+	if err != nil {
+		return fmt.Errorf("media: %w", err)
+	}
+	app.deleteStoredFiles(files)
+
+	if galleries+projects > 0 || len(files) > 0 {
+		log.Printf("🧹 Purged %d galleries, %d projects and %d media files from trash", galleries, projects, len(files))
+	}
+	return nil
+}
+
+// deleteStoredFiles removes purged media files from S3
+func (app *Application) deleteStoredFiles(files []models.StoredFile) {
+	for _, f := range files {
+		app.deleteMediaObjects(f.FileName, f.OriginalKey)
+	}
+}
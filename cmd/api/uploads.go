@@ -0,0 +1,448 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ikm/models"
+	"ikm/utils"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Chunked uploads follow a small subset of the tus protocol: the client
+// creates an upload, then PATCHes the file in pieces, each carrying the
+// Upload-Offset it starts at. A HEAD request reports how much the server
+// already holds, so an interrupted upload resumes from there. Once the last
+// byte arrives the assembled file goes through the normal media pipeline.
+const (
+	maxUploadSize  = 2 << 30  // 2GB per file
+	maxChunkSize   = 16 << 20 // well above the client's chunk size
+	uploadChunkLen = 5 << 20  // chunk size suggested to clients
+	uploadTTL      = 24 * time.Hour
+
+	// Decoding and watermarking a large file outlasts the server-wide
+	// write timeout
+	uploadProcessTimeout = 10 * time.Minute
+)
+
+var (
+	errUploadNotFound = errors.New("upload not found")
+	errOffsetMismatch = errors.New("upload offset mismatch")
+)
+
+// PendingUpload is the state of a chunked upload, persisted next to the
+// partial file so uploads survive a server restart.
+type PendingUpload struct {
+	ID          string    `json:"id"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Offset      int64     `json:"offset"`
+	GalleryID   int       `json:"gallery_id"`
+	ProjectID   int       `json:"project_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastWriteAt time.Time `json:"last_write_at"`
+}
+
+// lastActive is when the upload last received bytes. Uploads saved before
+// LastWriteAt was recorded fall back to when they started.
+func (up *PendingUpload) lastActive() time.Time {
+	if up.LastWriteAt.IsZero() {
+		return up.CreatedAt
+	}
+	return up.LastWriteAt
+}
+
+// UploadStore assembles chunked uploads in a temporary directory
+type UploadStore struct {
+	Dir string
+
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+func NewUploadStore(dir string) (*UploadStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating upload dir: %w", err)
+	}
+	return &UploadStore{Dir: dir, locks: make(map[string]*sync.Mutex)}, nil
+}
+
+func (s *UploadStore) partPath(id string) string { return filepath.Join(s.Dir, id+".part") }
+func (s *UploadStore) infoPath(id string) string { return filepath.Join(s.Dir, id+".json") }
+
+// lock serialises chunks for one upload so two PATCHes can't interleave
+func (s *UploadStore) lock(id string) func() {
+	l := s.mutex(id)
+	l.Lock()
+	return l.Unlock
+}
+
+// tryLock is lock without the wait. ok is false if the upload is busy.
+func (s *UploadStore) tryLock(id string) (unlock func(), ok bool) {
+	l := s.mutex(id)
+	if !l.TryLock() {
+		return nil, false
+	}
+	return l.Unlock, true
+}
+
+func (s *UploadStore) mutex(id string) *sync.Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.locks[id]
+	if !ok {
+		l = &sync.Mutex{}
+		s.locks[id] = l
+	}
+	return l
+}
+
+func (s *UploadStore) Create(fileName, contentType string, size int64, galleryID, projectID int) (*PendingUpload, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	up := &PendingUpload{
+		ID:          hex.EncodeToString(buf),
+		FileName:    filepath.Base(fileName),
+		ContentType: contentType,
+		Size:        size,
+		GalleryID:   galleryID,
+		ProjectID:   projectID,
+		CreatedAt:   now,
+		LastWriteAt: now,
+	}
+
+	f, err := os.OpenFile(s.partPath(up.ID), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+
+	if err := s.save(up); err != nil {
+		os.Remove(s.partPath(up.ID))
+		return nil, err
+	}
+	return up, nil
+}
+
+func (s *UploadStore) Get(id string) (*PendingUpload, error) {
+	if !isUploadID(id) {
+		return nil, errUploadNotFound
+	}
+	data, err := os.ReadFile(s.infoPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errUploadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var up PendingUpload
+	if err := json.Unmarshal(data, &up); err != nil {
+		return nil, err
+	}
+	return &up, nil
+}
+
+// WriteChunk appends r to the upload, which must currently be at offset.
+// It returns the upload with its new offset.
+func (s *UploadStore) WriteChunk(id string, offset int64, r io.Reader) (*PendingUpload, error) {
+	unlock := s.lock(id)
+	defer unlock()
+
+	up, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if offset != up.Offset {
+		return up, errOffsetMismatch
+	}
+	// A finished upload takes no more bytes. Sending its final offset again
+	// retries processing it, in case that failed.
+	if up.Offset == up.Size {
+		return up, nil
+	}
+
+	f, err := os.OpenFile(s.partPath(id), os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Drop anything past the recorded offset left by an interrupted write
+	if err := f.Truncate(offset); err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	n, err := io.Copy(f, io.LimitReader(r, up.Size-offset))
+	up.Offset += n
+	up.LastWriteAt = time.Now()
+	if saveErr := s.save(up); saveErr != nil {
+		return nil, saveErr
+	}
+	// A dropped connection still keeps whatever made it to disk
+	if err != nil {
+		return up, err
+	}
+	return up, nil
+}
+
+// Open returns the assembled file of a finished upload
+func (s *UploadStore) Open(id string) (*os.File, error) {
+	return os.Open(s.partPath(id))
+}
+
+func (s *UploadStore) Remove(id string) {
+	os.Remove(s.partPath(id))
+	os.Remove(s.infoPath(id))
+
+	s.mu.Lock()
+	delete(s.locks, id)
+	s.mu.Unlock()
+}
+
+// RemoveExpired deletes uploads that haven't received a chunk for ttl.
+// Uploads that are busy taking a chunk or being processed are left alone.
+func (s *UploadStore) RemoveExpired(ttl time.Duration) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		log.Printf("⚠️ Failed to read upload dir: %v", err)
+		return
+	}
+	for _, e := range entries {
+		if filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		id := e.Name()[:len(e.Name())-len(".json")]
+		unlock, ok := s.tryLock(id)
+		if !ok {
+			continue
+		}
+		up, err := s.Get(id)
+		if err != nil || time.Since(up.lastActive()) > ttl {
+			s.Remove(id)
+		}
+		unlock()
+	}
+}
+
+func (s *UploadStore) save(up *PendingUpload) error {
+	data, err := json.Marshal(up)
+	if err != nil {
+		return err
+	}
+	tmp := s.infoPath(up.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.infoPath(up.ID))
+}
+
+func isUploadID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+// CreateUpload starts a chunked upload and returns its ID
+func (app *Application) CreateUpload(w http.ResponseWriter, r *http.Request) {
+	size, err := strconv.ParseInt(r.FormValue("size"), 10, 64)
+	if err != nil || size <= 0 {
+		http.Error(w, "Invalid upload size", http.StatusBadRequest)
+		return
+	}
+	if size > maxUploadSize {
+		http.Error(w, "File is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	fileName := r.FormValue("filename")
+	if fileName == "" {
+		http.Error(w, "Missing file name", http.StatusBadRequest)
+		return
+	}
+
+	galleryID, _ := strconv.Atoi(r.FormValue("gallery_id"))
+	projectID, _ := strconv.Atoi(r.FormValue("project_id"))
+
+	// Opportunistic cleanup of abandoned uploads
+	go app.Uploads.RemoveExpired(uploadTTL)
+
+	up, err := app.Uploads.Create(fileName, r.FormValue("content_type"), size, galleryID, projectID)
+	if err != nil {
+		log.Printf("❌ Failed to create upload: %v", err)
+		http.Error(w, "Failed to create upload", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Location", "/admin/media/uploads/"+up.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{
+		"id":         up.ID,
+		"offset":     up.Offset,
+		"chunk_size": uploadChunkLen,
+	})
+}
+
+// UploadStatus reports how many bytes of an upload the server holds
+func (app *Application) UploadStatus(w http.ResponseWriter, r *http.Request) {
+	up, err := app.Uploads.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Upload not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(up.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(up.Size, 10))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+}
+
+// UploadChunk appends one chunk. When it completes the file, the upload is
+// processed and the rendered media item is returned.
+func (app *Application) UploadChunk(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		http.Error(w, "Invalid Upload-Offset", http.StatusBadRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxChunkSize)
+	up, err := app.Uploads.WriteChunk(id, offset, r.Body)
+	switch {
+	case errors.Is(err, errUploadNotFound):
+		http.Error(w, "Upload not found", http.StatusNotFound)
+		return
+	case errors.Is(err, errOffsetMismatch):
+		// Tell the client where to resume from
+		w.Header().Set("Upload-Offset", strconv.FormatInt(up.Offset, 10))
+		http.Error(w, "Upload offset mismatch", http.StatusConflict)
+		return
+	case err != nil:
+		log.Printf("❌ Failed to write chunk for upload %s: %v", id, err)
+		if up != nil {
+			w.Header().Set("Upload-Offset", strconv.FormatInt(up.Offset, 10))
+		}
+		http.Error(w, "Failed to write chunk", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(up.Offset, 10))
+	if up.Offset < up.Size {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	app.finishUpload(w, r, up)
+}
+
+// finishUpload runs an assembled upload through the media pipeline. The
+// assembled file is only removed once the media is saved, so a storage or
+// database error can be retried without uploading the file again.
+func (app *Application) finishUpload(w http.ResponseWriter, r *http.Request, up *PendingUpload) {
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(uploadProcessTimeout))
+
+	// Only one request processes an upload; a retry that was waiting finds
+	// it gone once the first succeeds
+	unlock := app.Uploads.lock(up.ID)
+	defer unlock()
+	if _, err := app.Uploads.Get(up.ID); err != nil {
+		http.Error(w, "Upload not found", http.StatusNotFound)
+		return
+	}
+
+	file, err := app.Uploads.Open(up.ID)
+	if err != nil {
+		log.Printf("❌ Failed to open assembled upload %s: %v", up.ID, err)
+		http.Error(w, "Failed to open upload", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	ctx := r.Context()
+	wm, err := app.loadWatermark(ctx)
+	if err != nil {
+		log.Printf("❌ Error loading watermark: %v", err)
+		http.Error(w, "Error loading watermark settings", http.StatusInternalServerError)
+		return
+	}
+	if up.GalleryID > 0 {
+		wm = app.watermarkForGallery(up.GalleryID, wm)
+	}
+
+	media, err := app.processUpload(ctx, file, up.Size, up.ContentType, up.FileName, wm)
+	if errors.Is(err, utils.ErrUnreadableImage) {
+		// Retrying won't help, so don't keep the file around
+		log.Printf("❌ Upload %s is not a readable image: %v", up.FileName, err)
+		app.Uploads.Remove(up.ID)
+		http.Error(w, "File is not a readable image", http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Error processing upload %s: %v", up.FileName, err)
+		http.Error(w, "Error processing file", http.StatusInternalServerError)
+		return
+	}
+
+	err = app.saveUploadedMedia(media, up.ProjectID, up.GalleryID)
+	if errors.Is(err, models.ErrOwnerNotFound) {
+		// The gallery or project went away mid-upload; no retry can attach it
+		log.Printf("❌ Dropping upload %s: %v", up.FileName, err)
+		app.Uploads.Remove(up.ID)
+		http.Error(w, "Gallery or project no longer exists", http.StatusGone)
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Failed to save upload %s: %v", up.FileName, err)
+		http.Error(w, "Failed to save media", http.StatusInternalServerError)
+		return
+	}
+
+	app.Uploads.Remove(up.ID)
+	log.Printf("✅ Assembled and processed upload %s as media %d", up.ID, media.ID)
+	w.Header().Set("Content-Type", "text/html")
+	app.renderPartialHTMX(w, "partials/media_item.html", map[string]any{
+		"Media":     media,
+		"ProjectID": up.ProjectID,
+		"GalleryID": up.GalleryID,
+	})
+}
+
+// CancelUpload discards a chunked upload and its partial file
+func (app *Application) CancelUpload(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := app.Uploads.Get(id); err != nil {
+		http.Error(w, "Upload not found", http.StatusNotFound)
+		return
+	}
+
+	unlock := app.Uploads.lock(id)
+	app.Uploads.Remove(id)
+	unlock()
+
+	w.WriteHeader(http.StatusNoContent)
+}
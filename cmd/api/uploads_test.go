@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestUploadStore(t *testing.T) *UploadStore {
+	t.Helper()
+	s, err := NewUploadStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewUploadStore failed: %v", err)
+	}
+	return s
+}
+
+// brokenReader hands out data and then fails, like a dropped connection
+type brokenReader struct{ data io.Reader }
+
+func (b *brokenReader) Read(p []byte) (int, error) {
+	n, err := b.data.Read(p)
+	if err == io.EOF {
+		return n, errors.New("connection reset")
+	}
+	return n, err
+}
+
+func TestUploadStore_WriteChunk(t *testing.T) {
+	type chunk struct {
+		offset     int64
+		data       string
+		broken     bool
+		wantOffset int64
+		wantErr    error
+	}
+
+	cases := []struct {
+		name   string
+		chunks []chunk
+		want   string
+	}{
+		{"✅ chunks in order", []chunk{
+			{0, "hello ", false, 6, nil},
+			{6, "world", false, 11, nil},
+		}, "hello world"},
+		{"❌ chunk at the wrong offset", []chunk{
+			{0, "hello ", false, 6, nil},
+			{3, "lo world", false, 6, errOffsetMismatch},
+			{6, "world", false, 11, nil},
+		}, "hello world"},
+		{"✅ resumes after a dropped connection", []chunk{
+			{0, "hel", true, 3, errors.New("connection reset")},
+			{3, "lo world", false, 11, nil},
+		}, "hello world"},
+		{"✅ bytes past the size are ignored", []chunk{
+			{0, "hello world and more", false, 11, nil},
+		}, "hello world"},
+		{"✅ the final offset again retries processing", []chunk{
+			{0, "hello world", false, 11, nil},
+			{11, "", false, 11, nil},
+			{11, "extra", false, 11, nil},
+		}, "hello world"},
+		{"❌ past the end", []chunk{
+			{0, "hello world", false, 11, nil},
+			{12, "x", false, 11, errOffsetMismatch},
+		}, "hello world"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestUploadStore(t)
+			up, err := s.Create("../../photo.jpg", "image/jpeg", 11, 3, 0)
+			if err != nil {
+				t.Fatalf("Create failed: %v", err)
+			}
+			if up.FileName != "photo.jpg" {
+				t.Errorf("Expected the path to be stripped from the name, got %q", up.FileName)
+			}
+
+			for i, c := range tc.chunks {
+				var r io.Reader = strings.NewReader(c.data)
+				if c.broken {
+					r = &brokenReader{r}
+				}
+				got, err := s.WriteChunk(up.ID, c.offset, r)
+				if (err == nil) != (c.wantErr == nil) || (errors.Is(c.wantErr, errOffsetMismatch) && !errors.Is(err, errOffsetMismatch)) {
+					t.Fatalf("Chunk %d: expected error %v, got %v", i, c.wantErr, err)
+				}
+				if got.Offset != c.wantOffset {
+					t.Fatalf("Chunk %d: expected offset %d, got %d", i, c.wantOffset, got.Offset)
+				}
+			}
+
+			// The offset survives a restart, since it's read back from disk
+			saved, err := s.Get(up.ID)
+			if err != nil || saved.Offset != 11 || saved.GalleryID != 3 {
+				t.Fatalf("Expected the saved upload to be complete, got %+v (%v)", saved, err)
+			}
+
+			f, err := s.Open(up.ID)
+			if err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+			defer f.Close()
+			data, _ := io.ReadAll(f)
+			if string(data) != tc.want {
+				t.Errorf("Expected %q, got %q", tc.want, data)
+			}
+		})
+	}
+}
+
+func TestUploadStore_ConcurrentChunks(t *testing.T) {
+	s := newTestUploadStore(t)
+	up, err := s.Create("photo.jpg", "image/jpeg", 1000, 0, 0)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// Several clients racing to write the first chunk: exactly one wins
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	wins, mismatches := 0, 0
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(b byte) {
+			defer wg.Done()
+			_, err := s.WriteChunk(up.ID, 0, bytes.NewReader(bytes.Repeat([]byte{b}, 100)))
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				wins++
+			case errors.Is(err, errOffsetMismatch):
+				mismatches++
+			default:
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}(byte('a' + i))
+	}
+	wg.Wait()
+
+	if wins != 1 || mismatches != 7 {
+		t.Fatalf("Expected 1 write and 7 mismatches, got %d and %d", wins, mismatches)
+	}
+	f, _ := s.Open(up.ID)
+	defer f.Close()
+	data, _ := io.ReadAll(f)
+	if len(data) != 100 || bytes.Count(data, data[:1]) != 100 {
+		t.Errorf("Expected one client's 100 bytes, got %q", data)
+	}
+}
+
+func TestUploadStore_Get(t *testing.T) {
+	s := newTestUploadStore(t)
+	up, err := s.Create("photo.jpg", "image/jpeg", 10, 0, 0)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		id      string
+		wantErr bool
+	}{
+		{"✅ existing upload", up.ID, false},
+		{"❌ unknown ID", strings.Repeat("0", 32), true},
+		{"❌ path in the ID", "../" + up.ID[3:], true},
+		{"❌ too short", up.ID[:8], true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := s.Get(tc.id)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Get error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err != nil && !errors.Is(err, errUploadNotFound) {
+				t.Errorf("Expected errUploadNotFound, got %v", err)
+			}
+		})
+	}
+
+	s.Remove(up.ID)
+	if _, err := s.Get(up.ID); !errors.Is(err, errUploadNotFound) {
+		t.Errorf("Expected a removed upload to be gone, got %v", err)
+	}
+	if _, err := os.Stat(s.partPath(up.ID)); !os.IsNotExist(err) {
+		t.Errorf("Expected the partial file to be deleted, got %v", err)
+	}
+}
+
+func TestUploadStore_RemoveExpired(t *testing.T) {
+	s := newTestUploadStore(t)
+	old := time.Now().Add(-2 * uploadTTL)
+
+	fresh, _ := s.Create("fresh.jpg", "image/jpeg", 10, 0, 0)
+	stale, _ := s.Create("stale.jpg", "image/jpeg", 10, 0, 0)
+	stale.CreatedAt, stale.LastWriteAt = old, old
+	// Started long ago on a slow link, but still receiving chunks
+	slow, _ := s.Create("slow.jpg", "image/jpeg", 10, 0, 0)
+	slow.CreatedAt = old
+	// Idle, but a chunk or processing holds it right now
+	busy, _ := s.Create("busy.jpg", "image/jpeg", 10, 0, 0)
+	busy.CreatedAt, busy.LastWriteAt = old, old
+	for _, up := range []*PendingUpload{stale, slow, busy} {
+		if err := s.save(up); err != nil {
+			t.Fatalf("save failed: %v", err)
+		}
+	}
+
+	unlock := s.lock(busy.ID)
+	s.RemoveExpired(uploadTTL)
+	unlock()
+
+	for _, up := range []*PendingUpload{fresh, slow, busy} {
+		if _, err := s.Get(up.ID); err != nil {
+			t.Errorf("Expected %s to be kept, got %v", up.FileName, err)
+		}
+	}
+	if _, err := s.Get(stale.ID); !errors.Is(err, errUploadNotFound) {
+		t.Errorf("Expected the stale upload to be removed, got %v", err)
+	}
+	if _, err := os.Stat(s.partPath(stale.ID)); !os.IsNotExist(err) {
+		t.Errorf("Expected the stale partial file to be deleted, got %v", err)
+	}
+}
+
+func TestUploadStore_WriteChunkRecordsActivity(t *testing.T) {
+	s := newTestUploadStore(t)
+	up, _ := s.Create("photo.jpg", "image/jpeg", 11, 0, 0)
+	up.CreatedAt = time.Now().Add(-2 * uploadTTL)
+	up.LastWriteAt = up.CreatedAt
+	s.save(up)
+
+	if _, err := s.WriteChunk(up.ID, 0, strings.NewReader("hello")); err != nil {
+		t.Fatalf("WriteChunk failed: %v", err)
+	}
+	s.RemoveExpired(uploadTTL)
+
+	got, err := s.Get(up.ID)
+	if err != nil {
+		t.Fatalf("Expected the active upload to be kept, got %v", err)
+	}
+	if time.Since(got.LastWriteAt) > time.Minute {
+		t.Errorf("Expected LastWriteAt to be updated, got %v", got.LastWriteAt)
+	}
+}
@@ -0,0 +1,123 @@
+// Command originals moves the originals of media uploaded before they were
+// kept privately into the Originals/ area, and rebuilds the public
+// renditions from them with the current watermark settings. This covers
+// every legacy item, including media that is only shown in projects and so
+// can't be regenerated from a gallery.
+//
+// It needs DB_URL and the same VULTR_S3_* settings as the server:
+//
+//	go run ./cmd/originals
+//
+// Items that fail are logged and skipped; running it again picks them up.
+package main
+
+import (
+	"context"
+	"flag"
+	"ikm/models"
+	"ikm/utils"
+	"log"
+	"os"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+func main() {
+	batch := flag.Int("batch", 100, "number of media items to process per query")
+	flag.Parse()
+
+	if os.Getenv("ENV") != "production" {
+		if err := godotenv.Load(); err != nil {
+			log.Printf("⚠️  .env file not found, assuming env vars are set")
+		}
+	}
+
+	s3Client, err := minio.New(os.Getenv("VULTR_S3_ENDPOINT"), &minio.Options{
+		Creds:  credentials.NewStaticV4(os.Getenv("VULTR_S3_ACCESS_KEY"), os.Getenv("VULTR_S3_SECRET_KEY"), ""),
+		Secure: true,
+	})
+	if err != nil {
+		log.Fatalf("Unable to initialize S3 client: %v", err)
+	}
+	images := &utils.ImageStore{
+		Client:   s3Client,
+		Bucket:   os.Getenv("VULTR_S3_BUCKET"),
+		Endpoint: os.Getenv("VULTR_S3_ENDPOINT"),
+	}
+
+	dbPool, err := pgxpool.New(context.Background(), os.Getenv("DB_URL"))
+	if err != nil {
+		log.Fatalf("Unable to connect to database: %v", err)
+	}
+	defer dbPool.Close()
+
+	if err := models.CreateTablesIfNotExist(dbPool); err != nil {
+		log.Fatal(err)
+	}
+
+	mediaModel := &models.MediaModel{DB: dbPool}
+	settingsModel := &models.SettingsModel{DB: dbPool}
+
+	ctx := context.Background()
+	settings, err := settingsModel.GetAll()
+	if err != nil {
+		log.Fatalf("❌ Failed to load settings: %v", err)
+	}
+	wm, err := images.LoadWatermark(ctx, settings)
+	if err != nil {
+		log.Fatalf("❌ Failed to load watermark: %v", err)
+	}
+
+	// Items that fail are remembered so they are not retried forever
+	failed := make(map[int]bool)
+	done := 0
+
+	for {
+		media, err := mediaModel.GetWithoutOriginal(*batch + len(failed))
+		if err != nil {
+			log.Fatalf("❌ Failed to load media: %v", err)
+		}
+
+		progress := false
+		for _, m := range media {
+			if failed[m.ID] {
+				continue
+			}
+			if err := backfill(ctx, images, mediaModel, m, wm); err != nil {
+				log.Printf("❌ Media %d (%s): %v", m.ID, m.FileName, err)
+				failed[m.ID] = true
+				continue
+			}
+			done++
+			progress = true
+		}
+
+		if !progress {
+			break
+		}
+	}
+
+	log.Printf("✅ Moved %d originals, %d failed", done, len(failed))
+}
+
+func backfill(ctx context.Context, images *utils.ImageStore, mediaModel *models.MediaModel, m *models.Media, wm *utils.Watermark) error {
+	optOut, err := mediaModel.WatermarkOptOut(m.ID)
+	if err != nil {
+		return err
+	}
+	if optOut {
+		wm = nil
+	}
+
+	key, err := images.Regenerate(ctx, m.FileName, "", wm)
+	if err != nil {
+		return err
+	}
+
+	// Only recorded once the public files have been replaced, so an item
+	// that fails half way is picked up again by the next run
+	return mediaModel.SetOriginalKey(m.ID, key)
+}
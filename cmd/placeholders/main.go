@@ -0,0 +1,107 @@
+// Command placeholders backfills BlurHash strings and dominant colours for
+// media uploaded before placeholders were generated at upload time.
+//
+// It downloads each item's public thumbnail, so only DB_URL is required:
+//
+//	go run ./cmd/placeholders
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"ikm/models"
+	"ikm/utils"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/disintegration/imaging"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	batch := flag.Int("batch", 100, "number of media items to process per query")
+	flag.Parse()
+
+	if os.Getenv("ENV") != "production" {
+		if err := godotenv.Load(); err != nil {
+			log.Printf("⚠️  .env file not found, assuming env vars are set")
+		}
+	}
+
+	dbPool, err := pgxpool.New(context.Background(), os.Getenv("DB_URL"))
+	if err != nil {
+		log.Fatalf("Unable to connect to database: %v", err)
+	}
+	defer dbPool.Close()
+
+	if err := models.CreateTablesIfNotExist(dbPool); err != nil {
+		log.Fatal(err)
+	}
+
+	mediaModel := &models.MediaModel{DB: dbPool}
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	// Items that fail are remembered so they are not retried forever
+	failed := make(map[int]bool)
+	done := 0
+
+	for {
+		media, err := mediaModel.GetMissingPlaceholders(*batch + len(failed))
+		if err != nil {
+			log.Fatalf("❌ Failed to load media: %v", err)
+		}
+
+		progress := false
+		for _, m := range media {
+			if failed[m.ID] {
+				continue
+			}
+			if err := backfill(client, mediaModel, m); err != nil {
+				log.Printf("❌ Media %d (%s): %v", m.ID, m.FileName, err)
+				failed[m.ID] = true
+				continue
+			}
+			done++
+			progress = true
+		}
+
+		if !progress {
+			break
+		}
+	}
+
+	log.Printf("✅ Backfilled %d media items, %d failed", done, len(failed))
+}
+
+func backfill(client *http.Client, mediaModel *models.MediaModel, m *models.Media) error {
+	url := m.ThumbnailURL
+	if url == "" {
+		url = m.FullURL
+	}
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	img, err := imaging.Decode(resp.Body, imaging.AutoOrientation(true))
+	if err != nil {
+		return err
+	}
+
+	hash, err := utils.BlurHash(img, 4, 3)
+	if err != nil {
+		return err
+	}
+
+	return mediaModel.SetPlaceholder(m.ID, hash, utils.DominantColor(img))
+}
@@ -0,0 +1,139 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Category groups galleries for the public galleries page. A gallery can be
+// in any number of categories.
+type Category struct {
+	ID          int
+	Name        string
+	Slug        string
+	Description string
+	Position    int
+	// PublicCount is the number of published galleries in the category
+	PublicCount int
+}
+
+type CategoryModel struct {
+	DB *pgxpool.Pool
+}
+
+const categoryColumns = `
+	c.id, c.name, c.slug, COALESCE(c.description, ''), c.position,
+	(SELECT COUNT(*) FROM gallery_categories gc
+	 JOIN galleries g ON g.id = gc.gallery_id
+	 WHERE gc.category_id = c.id AND g.published = TRUE AND g.deleted_at IS NULL)`
+
+// GetAll returns every category in display order
+func (c *CategoryModel) GetAll() ([]*Category, error) {
+	return c.list(`SELECT ` + categoryColumns + ` FROM categories c ORDER BY c.position ASC, c.name ASC`)
+}
+
+// GetForGallery returns the categories a gallery is in, in display order
+func (c *CategoryModel) GetForGallery(galleryID int) ([]*Category, error) {
+	return c.list(`
+		SELECT `+categoryColumns+`
+		FROM categories c
+		JOIN gallery_categories gc ON gc.category_id = c.id
+		WHERE gc.gallery_id = $1
+		ORDER BY c.position ASC, c.name ASC`, galleryID)
+}
+
+func (c *CategoryModel) GetBySlug(slug string) (*Category, error) {
+	categories, err := c.list(`SELECT `+categoryColumns+` FROM categories c WHERE c.slug = $1`, slug)
+	if err != nil {
+		return nil, err
+	}
+	if len(categories) == 0 {
+		return nil, fmt.Errorf("no category found with slug %q", slug)
+	}
+	return categories[0], nil
+}
+
+// Create adds a category at the end of the display order
+func (c *CategoryModel) Create(name, slug, description string) error {
+	if strings.TrimSpace(name) == "" || strings.TrimSpace(slug) == "" {
+		return fmt.Errorf("name and slug cannot be empty")
+	}
+	_, err := c.DB.Exec(context.Background(), `
+		INSERT INTO categories (name, slug, description, position)
+		VALUES ($1, $2, $3, (SELECT COALESCE(MAX(position), -1) + 1 FROM categories))`,
+		name, slug, description)
+	return err
+}
+
+func (c *CategoryModel) Update(id int, name, slug, description string, position int) error {
+	if strings.TrimSpace(name) == "" || strings.TrimSpace(slug) == "" {
+		return fmt.Errorf("name and slug cannot be empty")
+	}
+	res, err := c.DB.Exec(context.Background(),
+		`UPDATE categories SET name = $1, slug = $2, description = $3, position = $4 WHERE id = $5`,
+		name, slug, description, position, id)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("no category found with ID %d", id)
+	}
+	return nil
+}
+
+// Delete removes a category; its galleries are only unassigned
+func (c *CategoryModel) Delete(id int) error {
+	res, err := c.DB.Exec(context.Background(), `DELETE FROM categories WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("no category found with ID %d", id)
+	}
+	return nil
+}
+
+// SetGalleryCategories replaces the categories a gallery is in
+func (c *CategoryModel) SetGalleryCategories(galleryID int, categoryIDs []int) error {
+	ctx := context.Background()
+	tx, err := c.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM gallery_categories WHERE gallery_id = $1`, galleryID); err != nil {
+		return err
+	}
+	if len(categoryIDs) > 0 {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO gallery_categories (gallery_id, category_id)
+			SELECT $1, id FROM categories WHERE id = ANY($2)`, galleryID, categoryIDs)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (c *CategoryModel) list(query string, args ...any) ([]*Category, error) {
+	rows, err := c.DB.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []*Category
+	for rows.Next() {
+		cat := &Category{}
+		if err := rows.Scan(&cat.ID, &cat.Name, &cat.Slug, &cat.Description, &cat.Position, &cat.PublicCount); err != nil {
+			return nil, err
+		}
+		categories = append(categories, cat)
+	}
+	return categories, rows.Err()
+}
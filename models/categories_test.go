@@ -0,0 +1,92 @@
+package models
+
+import "testing"
+
+func TestCategoryModel_GalleryAssignment(t *testing.T) {
+	db := setupTestDB(t)
+	model := &CategoryModel{DB: db}
+	galleries := &GalleryModel{DB: db}
+
+	if err := model.Create("Weddings", "weddings", "Big days"); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := model.Create("Portraits", "portraits", ""); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	weddings, err := model.GetBySlug("weddings")
+	if err != nil {
+		t.Fatalf("GetBySlug failed: %v", err)
+	}
+	portraits, _ := model.GetBySlug("portraits")
+
+	published, _ := galleries.CreateAndReturnID("Smith Wedding", "", "smith-wedding")
+	galleries.SetPublished(published, true)
+	draft, _ := galleries.CreateAndReturnID("Jones Wedding", "", "jones-wedding")
+
+	cases := []struct {
+		name       string
+		galleryID  int
+		categories []int
+		category   *Category
+		wantPublic int
+	}{
+		{"✅ assign published gallery", published, []int{weddings.ID, portraits.ID}, weddings, 1},
+		{"✅ drafts are not listed", draft, []int{weddings.ID}, weddings, 1},
+		{"✅ reassign replaces", published, []int{portraits.ID}, weddings, 0},
+		{"✅ unknown categories are ignored", published, []int{portraits.ID, 9999}, portraits, 1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := model.SetGalleryCategories(tc.galleryID, tc.categories); err != nil {
+				t.Fatalf("SetGalleryCategories failed: %v", err)
+			}
+
+			list, err := galleries.GetPublicByCategory(tc.category.ID)
+			if err != nil {
+				t.Fatalf("GetPublicByCategory failed: %v", err)
+			}
+			if len(list) != tc.wantPublic {
+				t.Errorf("Expected %d public galleries in %s, got %d", tc.wantPublic, tc.category.Slug, len(list))
+			}
+
+			cat, _ := model.GetBySlug(tc.category.Slug)
+			if cat.PublicCount != tc.wantPublic {
+				t.Errorf("Expected PublicCount %d, got %d", tc.wantPublic, cat.PublicCount)
+			}
+		})
+	}
+
+	if err := model.Create("", "", ""); err == nil {
+		t.Error("Expected error for empty category, got nil")
+	}
+	if err := model.Create("Weddings", "weddings", ""); err == nil {
+		t.Error("Expected error for duplicate slug, got nil")
+	}
+}
+
+func TestCreateTables_SeedsCategoriesOnce(t *testing.T) {
+	db := setupTestDB(t)
+	model := &CategoryModel{DB: db}
+
+	// setupTestDB empties settings too, so this is a first run
+	if err := CreateTablesIfNotExist(db); err != nil {
+		t.Fatalf("CreateTablesIfNotExist failed: %v", err)
+	}
+	seeded, _ := model.GetAll()
+	if len(seeded) != 4 {
+		t.Fatalf("Expected 4 seeded categories, got %d", len(seeded))
+	}
+
+	for _, c := range seeded {
+		if err := model.Delete(c.ID); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+	}
+	if err := CreateTablesIfNotExist(db); err != nil {
+		t.Fatalf("CreateTablesIfNotExist failed: %v", err)
+	}
+	if got, _ := model.GetAll(); len(got) != 0 {
+		t.Errorf("Expected deleted categories to stay deleted, got %d", len(got))
+	}
+}
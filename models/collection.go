@@ -0,0 +1,300 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Galleries and projects both hold an ordered list of media. A
+// MediaCollection is that list for one kind of owner, and every change to it
+// goes through here so the two behave the same.
+//
+// Positions in a collection are dense: 0 to n-1 with no gaps or repeats.
+// Each change locks the owner row first, so concurrent edits to the same
+// collection queue up instead of interleaving, and starts by closing any gaps
+// left by media purged from under it. Trashed media keeps its place so it
+// comes back where it was when restored.
+
+// ErrNotInCollection is returned when moving media that isn't in the
+// collection
+var ErrNotInCollection = errors.New("media is not in this collection")
+
+// ErrOwnerNotFound is returned when the gallery or project of a collection
+// doesn't exist, or is in the trash when adding new media to it
+var ErrOwnerNotFound = errors.New("gallery or project not found")
+
+// MediaCollection is the ordered media of galleries or of projects
+type MediaCollection struct {
+	DB *pgxpool.Pool
+
+	kind       string // "gallery" or "project", for errors
+	ownerTable string // galleries or projects
+	table      string // the join table
+	owner      string // the owner column of the join table
+}
+
+// Media returns the ordered media of galleries
+func (g *GalleryModel) Media() *MediaCollection {
+	return &MediaCollection{DB: g.DB, kind: "gallery", ownerTable: "galleries", table: "gallery_media", owner: "gallery_id"}
+}
+
+// Media returns the ordered media of projects
+func (p *ProjectModel) Media() *MediaCollection {
+	return &MediaCollection{DB: p.DB, kind: "project", ownerTable: "projects", table: "project_media", owner: "project_id"}
+}
+
+// MediaIDs returns the media of a collection in order, trashed media included
+func (c *MediaCollection) MediaIDs(ownerID int) ([]int, error) {
+	return c.mediaIDs(context.Background(), c.DB, ownerID)
+}
+
+// Append adds media to the end of a collection in the given order. Media
+// already in it stays where it is.
+func (c *MediaCollection) Append(ownerID int, mediaIDs ...int) error {
+	return c.edit(ownerID, func(ctx context.Context, tx pgx.Tx) error {
+		for _, mediaID := range mediaIDs {
+			if err := c.append(ctx, tx, ownerID, mediaID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// AppendNew saves a new media item and adds it to the end of a collection in
+// one transaction, so a failed attach never leaves the media in the library
+// on its own. A trashed owner takes no new media. m.ID is set on success.
+func (c *MediaCollection) AppendNew(ownerID int, m *Media) error {
+	err := c.edit(ownerID, func(ctx context.Context, tx pgx.Tx) error {
+		var trashed bool
+		err := tx.QueryRow(ctx,
+			`SELECT deleted_at IS NOT NULL FROM `+c.ownerTable+` WHERE id = $1`, ownerID).Scan(&trashed)
+		if err != nil {
+			return err
+		}
+		if trashed {
+			return fmt.Errorf("%w: %s %d is in the trash", ErrOwnerNotFound, c.kind, ownerID)
+		}
+
+		if err := insertMedia(ctx, tx, m); err != nil {
+			return err
+		}
+		return c.append(ctx, tx, ownerID, m.ID)
+	})
+	if err != nil {
+		m.ID = 0
+	}
+	return err
+}
+
+// Insert adds media to a collection at position, shifting what follows. A
+// position past the end appends; media already in the collection is moved.
+func (c *MediaCollection) Insert(ownerID, mediaID, position int) error {
+	return c.edit(ownerID, func(ctx context.Context, tx pgx.Tx) error {
+		current, count, err := c.positionOf(ctx, tx, ownerID, mediaID)
+		if err != nil {
+			return err
+		}
+		if current >= 0 {
+			return c.move(ctx, tx, ownerID, mediaID, current, clamp(position, count-1))
+		}
+
+		position = clamp(position, count)
+		_, err = tx.Exec(ctx, `
+			UPDATE `+c.table+` SET position = position + 1
+			WHERE `+c.owner+` = $1 AND position >= $2`, ownerID, position)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx,
+			`INSERT INTO `+c.table+` (`+c.owner+`, media_id, position) VALUES ($1, $2, $3)`,
+			ownerID, mediaID, position)
+		return err
+	})
+}
+
+// Move puts media already in a collection at position, shifting what lies
+// between. A position past the end moves it last.
+func (c *MediaCollection) Move(ownerID, mediaID, position int) error {
+	return c.edit(ownerID, func(ctx context.Context, tx pgx.Tx) error {
+		current, count, err := c.positionOf(ctx, tx, ownerID, mediaID)
+		if err != nil {
+			return err
+		}
+		if current < 0 {
+			return ErrNotInCollection
+		}
+		return c.move(ctx, tx, ownerID, mediaID, current, clamp(position, count-1))
+	})
+}
+
+// Reorder puts a collection in the order of mediaIDs. IDs not in the
+// collection are ignored, and media left out keeps its relative order after
+// the listed media, so trashed media the admin grid doesn't show survives a
+// drag and drop.
+func (c *MediaCollection) Reorder(ownerID int, mediaIDs []int) error {
+	return c.edit(ownerID, func(ctx context.Context, tx pgx.Tx) error {
+		current, err := c.mediaIDs(ctx, tx, ownerID)
+		if err != nil {
+			return err
+		}
+
+		member := make(map[int]bool, len(current))
+		for _, id := range current {
+			member[id] = true
+		}
+		order := make([]int, 0, len(current))
+		for _, id := range mediaIDs {
+			if member[id] {
+				order = append(order, id)
+				member[id] = false
+			}
+		}
+		for _, id := range current {
+			if member[id] {
+				order = append(order, id)
+			}
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE `+c.table+` t SET position = o.ord - 1
+			FROM UNNEST($2::INTEGER[]) WITH ORDINALITY AS o (media_id, ord)
+			WHERE t.`+c.owner+` = $1 AND t.media_id = o.media_id`, ownerID, order)
+		return err
+	})
+}
+
+// Remove takes media out of a collection and closes the gap it leaves. The
+// media itself stays in the library.
+func (c *MediaCollection) Remove(ownerID, mediaID int) error {
+	return c.edit(ownerID, func(ctx context.Context, tx pgx.Tx) error {
+		_, err := tx.Exec(ctx,
+			`DELETE FROM `+c.table+` WHERE `+c.owner+` = $1 AND media_id = $2`, ownerID, mediaID)
+		if err != nil {
+			return err
+		}
+		return c.compact(ctx, tx, ownerID)
+	})
+}
+
+// edit runs fn in a transaction holding the owner's row lock, on a compacted
+// collection
+func (c *MediaCollection) edit(ownerID int, fn func(ctx context.Context, tx pgx.Tx) error) error {
+	ctx := context.Background()
+	tx, err := c.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var id int
+	err = tx.QueryRow(ctx, `SELECT id FROM `+c.ownerTable+` WHERE id = $1 FOR UPDATE`, ownerID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%w: no %s with ID %d", ErrOwnerNotFound, c.kind, ownerID)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := c.compact(ctx, tx, ownerID); err != nil {
+		return err
+	}
+	if err := fn(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// append adds media to the end of a collection unless it's already in it
+func (c *MediaCollection) append(ctx context.Context, tx pgx.Tx, ownerID, mediaID int) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO `+c.table+` (`+c.owner+`, media_id, position)
+		SELECT $1, $2, COUNT(*) FROM `+c.table+` WHERE `+c.owner+` = $1
+		ON CONFLICT (`+c.owner+`, media_id) DO NOTHING`, ownerID, mediaID)
+	return err
+}
+
+// compact renumbers a collection 0 to n-1, keeping its order
+func (c *MediaCollection) compact(ctx context.Context, tx pgx.Tx, ownerID int) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE `+c.table+` t SET position = r.pos
+		FROM (
+			SELECT media_id, ROW_NUMBER() OVER (ORDER BY position, media_id) - 1 AS pos
+			FROM `+c.table+` WHERE `+c.owner+` = $1
+		) r
+		WHERE t.`+c.owner+` = $1 AND t.media_id = r.media_id AND t.position IS DISTINCT FROM r.pos`, ownerID)
+	return err
+}
+
+// positionOf returns where media sits in a collection, or -1 if it isn't in
+// it, and how many media the collection holds
+func (c *MediaCollection) positionOf(ctx context.Context, tx pgx.Tx, ownerID, mediaID int) (int, int, error) {
+	var position, count int
+	err := tx.QueryRow(ctx, `
+		SELECT COALESCE(MAX(position) FILTER (WHERE media_id = $2), -1), COUNT(*)
+		FROM `+c.table+` WHERE `+c.owner+` = $1`, ownerID, mediaID).Scan(&position, &count)
+	return position, count, err
+}
+
+func (c *MediaCollection) move(ctx context.Context, tx pgx.Tx, ownerID, mediaID, from, to int) error {
+	if from == to {
+		return nil
+	}
+
+	var err error
+	if from < to {
+		_, err = tx.Exec(ctx, `
+			UPDATE `+c.table+` SET position = position - 1
+			WHERE `+c.owner+` = $1 AND position > $2 AND position <= $3`, ownerID, from, to)
+	} else {
+		_, err = tx.Exec(ctx, `
+			UPDATE `+c.table+` SET position = position + 1
+			WHERE `+c.owner+` = $1 AND position >= $3 AND position < $2`, ownerID, from, to)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx,
+		`UPDATE `+c.table+` SET position = $3 WHERE `+c.owner+` = $1 AND media_id = $2`, ownerID, mediaID, to)
+	return err
+}
+
+// rowsQuerier is a pool or a transaction, for reads that happen in both
+type rowsQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+func (c *MediaCollection) mediaIDs(ctx context.Context, q rowsQuerier, ownerID int) ([]int, error) {
+	rows, err := q.Query(ctx,
+		`SELECT media_id FROM `+c.table+` WHERE `+c.owner+` = $1 ORDER BY position ASC, media_id ASC`, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// clamp keeps position within 0 and max
+func clamp(position, max int) int {
+	if position > max {
+		position = max
+	}
+	if position < 0 {
+		position = 0
+	}
+	return position
+}
@@ -0,0 +1,221 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// assertDense checks a collection's positions run 0 to n-1 with no repeats
+func assertDense(t *testing.T, c *MediaCollection, ownerID int) {
+	t.Helper()
+
+	rows, err := c.DB.Query(context.Background(),
+		`SELECT position FROM `+c.table+` WHERE `+c.owner+` = $1 ORDER BY position ASC`, ownerID)
+	if err != nil {
+		t.Fatalf("reading positions failed: %v", err)
+	}
+	defer rows.Close()
+
+	want := 0
+	for rows.Next() {
+		var position int
+		if err := rows.Scan(&position); err != nil {
+			t.Fatalf("scanning position failed: %v", err)
+		}
+		if position != want {
+			t.Fatalf("Expected position %d, got %d", want, position)
+		}
+		want++
+	}
+}
+
+func TestMediaCollection_Edits(t *testing.T) {
+	db := setupTestDB(t)
+	galleries := &GalleryModel{DB: db}
+	media := &MediaModel{DB: db}
+
+	galleryID, _ := galleries.CreateAndReturnID("Ordering", "", "collection-ordering")
+	ids := make([]int, 5)
+	for i := range ids {
+		ids[i], _ = media.InsertAndReturnID(fmt.Sprintf("%d.jpg", i), "full.jpg", "thumb.jpg")
+	}
+	a, b, c, d, e := ids[0], ids[1], ids[2], ids[3], ids[4]
+	col := galleries.Media()
+
+	cases := []struct {
+		name    string
+		edit    func() error
+		want    []int
+		wantErr bool
+	}{
+		{"✅ append", func() error { return col.Append(galleryID, a, b, c) }, []int{a, b, c}, false},
+		{"✅ append skips members", func() error { return col.Append(galleryID, a) }, []int{a, b, c}, false},
+		{"✅ insert in the middle", func() error { return col.Insert(galleryID, d, 1) }, []int{a, d, b, c}, false},
+		{"✅ insert past the end appends", func() error { return col.Insert(galleryID, e, 99) }, []int{a, d, b, c, e}, false},
+		{"✅ insert a member moves it", func() error { return col.Insert(galleryID, e, 0) }, []int{e, a, d, b, c}, false},
+		{"✅ move down", func() error { return col.Move(galleryID, e, 3) }, []int{a, d, b, e, c}, false},
+		{"✅ move up", func() error { return col.Move(galleryID, c, 0) }, []int{c, a, d, b, e}, false},
+		{"✅ reorder keeps unlisted media after", func() error { return col.Reorder(galleryID, []int{b, a, 9999}) }, []int{b, a, c, d, e}, false},
+		{"✅ remove closes the gap", func() error { return col.Remove(galleryID, a) }, []int{b, c, d, e}, false},
+		{"❌ move a non-member", func() error { return col.Move(galleryID, a, 0) }, []int{b, c, d, e}, true},
+		{"❌ invalid gallery", func() error { return col.Append(9999, a) }, []int{b, c, d, e}, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.edit()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("edit error = %v, wantErr %v", err, tc.wantErr)
+			}
+
+			got, err := col.MediaIDs(galleryID)
+			if err != nil {
+				t.Fatalf("MediaIDs failed: %v", err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+				t.Errorf("Expected %v, got %v", tc.want, got)
+			}
+			assertDense(t, col, galleryID)
+		})
+	}
+
+	if err := col.Move(galleryID, a, 0); !errors.Is(err, ErrNotInCollection) {
+		t.Errorf("Expected ErrNotInCollection, got %v", err)
+	}
+}
+
+func TestMediaCollection_GapsFromPurgeAreClosed(t *testing.T) {
+	db := setupTestDB(t)
+	projects := &ProjectModel{DB: db}
+	media := &MediaModel{DB: db}
+
+	projects.Create("Purged", "", "collection-purged")
+	project, _ := projects.GetBySlug("collection-purged")
+	a, _ := media.InsertAndReturnID("a.jpg", "full.jpg", "thumb.jpg")
+	b, _ := media.InsertAndReturnID("b.jpg", "full.jpg", "thumb.jpg")
+	c, _ := media.InsertAndReturnID("c.jpg", "full.jpg", "thumb.jpg")
+	col := projects.Media()
+	col.Append(project.ID, a, b, c)
+
+	media.Delete(b)
+	if _, err := media.Purge(b); err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+
+	d, _ := media.InsertAndReturnID("d.jpg", "full.jpg", "thumb.jpg")
+	if err := col.Insert(project.ID, d, 1); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	got, _ := col.MediaIDs(project.ID)
+	if fmt.Sprint(got) != fmt.Sprint([]int{a, d, c}) {
+		t.Errorf("Expected %v, got %v", []int{a, d, c}, got)
+	}
+	assertDense(t, col, project.ID)
+}
+
+func TestMediaCollection_ConcurrentEdits(t *testing.T) {
+	db := setupTestDB(t)
+	galleries := &GalleryModel{DB: db}
+	media := &MediaModel{DB: db}
+
+	galleryID, _ := galleries.CreateAndReturnID("Busy", "", "collection-busy")
+	col := galleries.Media()
+
+	const n = 20
+	ids := make([]int, n)
+	for i := range ids {
+		ids[i], _ = media.InsertAndReturnID(fmt.Sprintf("%d.jpg", i), "full.jpg", "thumb.jpg")
+	}
+	col.Append(galleryID, ids[:n/2]...)
+
+	// Uploads, inserts, moves, reorders and removals all at once
+	var wg sync.WaitGroup
+	errs := make(chan error, 4*n)
+	for i := n / 2; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%2 == 0 {
+				errs <- col.Append(galleryID, ids[i])
+			} else {
+				errs <- col.Insert(galleryID, ids[i], i%3)
+			}
+		}(i)
+	}
+	for i := 0; i < n/2; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			switch i % 3 {
+			case 0:
+				errs <- col.Move(galleryID, ids[i], n-i)
+			case 1:
+				errs <- col.Reorder(galleryID, []int{ids[i], ids[0]})
+			default:
+				errs <- col.Remove(galleryID, ids[i])
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("concurrent edit failed: %v", err)
+		}
+	}
+
+	got, err := col.MediaIDs(galleryID)
+	if err != nil {
+		t.Fatalf("MediaIDs failed: %v", err)
+	}
+	removed := 0
+	for i := 0; i < n/2; i++ {
+		if i%3 == 2 {
+			removed++
+		}
+	}
+	if len(got) != n-removed {
+		t.Errorf("Expected %d media, got %d", n-removed, len(got))
+	}
+	assertDense(t, col, galleryID)
+}
+
+func TestMediaCollection_AppendNew(t *testing.T) {
+	db := setupTestDB(t)
+	galleries := &GalleryModel{DB: db}
+	media := &MediaModel{DB: db}
+
+	galleryID, _ := galleries.CreateAndReturnID("Uploads", "", "collection-uploads")
+	col := galleries.Media()
+
+	added := &Media{FileName: "new.jpg", FullURL: "full.jpg", ThumbnailURL: "thumb.jpg", BlurHash: "LEHV6nWB2yk8", DominantColor: "#aabbcc"}
+	if err := col.AppendNew(galleryID, added); err != nil {
+		t.Fatalf("AppendNew failed: %v", err)
+	}
+	got, _ := col.MediaIDs(galleryID)
+	if fmt.Sprint(got) != fmt.Sprint([]int{added.ID}) {
+		t.Errorf("Expected %v, got %v", []int{added.ID}, got)
+	}
+	saved, err := media.GetByID(added.ID)
+	if err != nil || saved.BlurHash != added.BlurHash {
+		t.Errorf("Expected the placeholder to be saved, got %+v (%v)", saved, err)
+	}
+
+	before, _ := media.Count()
+	galleries.Delete(galleryID)
+
+	orphan := &Media{FileName: "late.jpg", FullURL: "full.jpg", ThumbnailURL: "thumb.jpg"}
+	if err := col.AppendNew(galleryID, orphan); !errors.Is(err, ErrOwnerNotFound) {
+		t.Errorf("Expected ErrOwnerNotFound for a trashed gallery, got %v", err)
+	}
+	if err := col.AppendNew(9999, orphan); !errors.Is(err, ErrOwnerNotFound) {
+		t.Errorf("Expected ErrOwnerNotFound for a missing gallery, got %v", err)
+	}
+	if after, _ := media.Count(); after != before || orphan.ID != 0 {
+		t.Errorf("Expected no media saved, got %d rows (was %d) and ID %d", after, before, orphan.ID)
+	}
+}
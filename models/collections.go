@@ -0,0 +1,165 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Galleries nest into collections through an optional parent gallery. A
+// collection page lists its sub-galleries alongside its own media.
+
+// ErrGalleryCycle is returned when a gallery would end up inside itself,
+// directly or through one of its sub-galleries
+var ErrGalleryCycle = errors.New("a gallery can't be nested inside itself or its sub-galleries")
+
+// maxNestingDepth bounds the walk up the parent chain for breadcrumbs
+const maxNestingDepth = 50
+
+// galleryTreeLock serialises parent changes so two concurrent moves can't
+// form a cycle between them
+const galleryTreeLock = 7301
+
+// GalleryLink is the little a collection needs to show or link a gallery
+type GalleryLink struct {
+	ID            int
+	Title         string
+	Slug          string
+	Published     bool
+	CoverImageURL *string
+	MediaCount    int
+}
+
+// SetParent moves a gallery into a collection, or back to the top level when
+// parentID is nil
+func (g *GalleryModel) SetParent(id int, parentID *int) error {
+	ctx := context.Background()
+	tx, err := g.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, galleryTreeLock); err != nil {
+		return err
+	}
+
+	if parentID != nil {
+		if *parentID == id {
+			return ErrGalleryCycle
+		}
+
+		var exists bool
+		err = tx.QueryRow(ctx,
+			`SELECT EXISTS (SELECT 1 FROM galleries WHERE id = $1 AND deleted_at IS NULL)`, *parentID).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("no gallery found with ID %d", *parentID)
+		}
+
+		// Walk up from the new parent; meeting the gallery means a loop
+		var cycle bool
+		err = tx.QueryRow(ctx, `
+			WITH RECURSIVE ancestors (id, parent_id) AS (
+				SELECT id, parent_id FROM galleries WHERE id = $1
+				UNION
+				SELECT g.id, g.parent_id FROM galleries g JOIN ancestors a ON g.id = a.parent_id
+			)
+			SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = $2)`, *parentID, id).Scan(&cycle)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return ErrGalleryCycle
+		}
+	}
+
+	res, err := tx.Exec(ctx,
+		`UPDATE galleries SET parent_id = $1 WHERE id = $2 AND deleted_at IS NULL`, parentID, id)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("no gallery found with ID %d", id)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetChildren lists the published sub-galleries of a collection with their
+// covers
+func (g *GalleryModel) GetChildren(parentID int) ([]*GalleryLink, error) {
+	return g.links(`
+		SELECT g.id, g.title, g.slug, g.published, m.full_url,
+		       (SELECT COUNT(*) FROM gallery_media gm2 JOIN media m2 ON m2.id = gm2.media_id WHERE gm2.gallery_id = g.id AND m2.deleted_at IS NULL)
+		FROM galleries g
+		LEFT JOIN media m ON g.cover_image_id = m.id AND m.deleted_at IS NULL
+		WHERE g.parent_id = $1 AND g.published = TRUE AND g.deleted_at IS NULL
+		ORDER BY `+galleryListOrder, parentID)
+}
+
+// GetFeatured lists published featured galleries with their covers, in list
+// order, for the home page
+func (g *GalleryModel) GetFeatured(limit int) ([]*GalleryLink, error) {
+	return g.links(`
+		SELECT g.id, g.title, g.slug, g.published, m.full_url,
+		       (SELECT COUNT(*) FROM gallery_media gm2 JOIN media m2 ON m2.id = gm2.media_id WHERE gm2.gallery_id = g.id AND m2.deleted_at IS NULL)
+		FROM galleries g
+		LEFT JOIN media m ON g.cover_image_id = m.id AND m.deleted_at IS NULL
+		WHERE g.featured = TRUE AND g.published = TRUE AND g.deleted_at IS NULL
+		ORDER BY `+galleryListOrder+`
+		LIMIT $1`, limit)
+}
+
+// GetAncestors returns the collections a gallery sits in, outermost first.
+// The walk stops at a trashed collection.
+func (g *GalleryModel) GetAncestors(id int) ([]*GalleryLink, error) {
+	return g.links(`
+		WITH RECURSIVE ancestors (id, parent_id, depth) AS (
+			SELECT p.id, p.parent_id, 1
+			FROM galleries c JOIN galleries p ON p.id = c.parent_id
+			WHERE c.id = $1 AND p.deleted_at IS NULL
+			UNION ALL
+			SELECT p.id, p.parent_id, a.depth + 1
+			FROM ancestors a JOIN galleries p ON p.id = a.parent_id
+			WHERE p.deleted_at IS NULL AND a.depth < $2
+		)
+		SELECT g.id, g.title, g.slug, g.published, NULL::TEXT, 0
+		FROM ancestors a JOIN galleries g ON g.id = a.id
+		ORDER BY a.depth DESC`, id, maxNestingDepth)
+}
+
+// GetParentOptions lists the galleries that id could be moved into: every
+// live gallery except itself and its own sub-galleries
+func (g *GalleryModel) GetParentOptions(id int) ([]*GalleryLink, error) {
+	return g.links(`
+		WITH RECURSIVE subtree (id) AS (
+			SELECT $1::INTEGER
+			UNION
+			SELECT g.id FROM galleries g JOIN subtree s ON g.parent_id = s.id
+		)
+		SELECT g.id, g.title, g.slug, g.published, NULL::TEXT, 0
+		FROM galleries g
+		WHERE g.deleted_at IS NULL AND g.id NOT IN (SELECT id FROM subtree)
+		ORDER BY g.title ASC`, id)
+}
+
+func (g *GalleryModel) links(query string, args ...any) ([]*GalleryLink, error) {
+	rows, err := g.DB.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []*GalleryLink
+	for rows.Next() {
+		l := &GalleryLink{}
+		if err := rows.Scan(&l.ID, &l.Title, &l.Slug, &l.Published, &l.CoverImageURL, &l.MediaCount); err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
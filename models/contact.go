@@ -2,11 +2,36 @@ package models
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// Contact statuses, in the order the inbox lists them
+const (
+	ContactNew      = "new"
+	ContactRead     = "read"
+	ContactReplied  = "replied"
+	ContactArchived = "archived"
+	ContactSpam     = "spam"
+)
+
+// ContactStatuses are the statuses a contact can be in
+var ContactStatuses = []string{ContactNew, ContactRead, ContactReplied, ContactArchived, ContactSpam}
+
+// ValidContactStatus reports whether status is one of ContactStatuses
+func ValidContactStatus(status string) bool {
+	for _, s := range ContactStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
 type Contact struct {
 	ID        int
 	FirstName string
@@ -15,71 +40,264 @@ type Contact struct {
 	Subject   string
 	Message   string
 	CreatedAt time.Time
+
+	Status       string
+	AssignedTo   *int   // the team member looking after it, if any
+	AssigneeName string // their name, when AssignedTo is set
+	Notes        string // internal, never sent to the enquirer
+	SpamReason   string // why the spam filter caught it, if it did
+	UpdatedAt    time.Time
+}
+
+// AssigneeID is the ID of the team member the contact is assigned to, or 0
+func (c *Contact) AssigneeID() int {
+	if c.AssignedTo == nil {
+		return 0
+	}
+	return *c.AssignedTo
+}
+
+// ContactMessage is a reply sent to an enquirer from the admin, threaded
+// under the contact after the original message
+type ContactMessage struct {
+	ID         int
+	ContactID  int
+	UserID     *int
+	AuthorName string
+	Subject    string
+	Body       string
+	CreatedAt  time.Time
+
+	EmailID     *int   // the outbox email carrying the reply
+	EmailStatus string // its outbox status, or empty if it's gone
 }
 
 type ContactModel struct {
 	DB *pgxpool.Pool
 }
 
+const contactColumns = `
+	c.id, c.first_name, c.last_name, c.email, COALESCE(c.subject, ''), c.message, c.created_at,
+	c.status, c.assigned_to, COALESCE(u.fname || ' ' || u.lname, ''), COALESCE(c.notes, ''),
+	COALESCE(c.spam_reason, ''), c.updated_at`
+
+const contactFrom = `FROM contacts c LEFT JOIN users u ON u.id = c.assigned_to`
+
+func scanContact(row pgx.Row) (*Contact, error) {
+	c := &Contact{}
+	err := row.Scan(&c.ID, &c.FirstName, &c.LastName, &c.Email, &c.Subject, &c.Message, &c.CreatedAt,
+		&c.Status, &c.AssignedTo, &c.AssigneeName, &c.Notes, &c.SpamReason, &c.UpdatedAt)
+	return c, err
+}
+
+func (m *ContactModel) queryContacts(query string, args ...any) ([]*Contact, error) {
+	rows, err := m.DB.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contacts []*Contact
+	for rows.Next() {
+		c, err := scanContact(rows)
+		if err != nil {
+			return nil, err
+		}
+		contacts = append(contacts, c)
+	}
+	return contacts, rows.Err()
+}
+
 func (m *ContactModel) Insert(firstName, lastName, email, subject, message string) error {
 	_, err := m.DB.Exec(context.Background(),
-		`INSERT INTO contacts (first_name, last_name, email, subject, message) 
+		`INSERT INTO contacts (first_name, last_name, email, subject, message)
 		 VALUES ($1, $2, $3, $4, $5)`,
 		firstName, lastName, email, subject, message,
 	)
 	return err
 }
 
+// InsertSpam saves a submission the spam filter caught straight into the
+// spam status, with the filter's reason
+func (m *ContactModel) InsertSpam(firstName, lastName, email, subject, message, reason string) error {
+	_, err := m.DB.Exec(context.Background(),
+		`INSERT INTO contacts (first_name, last_name, email, subject, message, status, spam_reason)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		firstName, lastName, email, subject, message, ContactSpam, reason,
+	)
+	return err
+}
+
 func (m *ContactModel) GetAll() ([]*Contact, error) {
+	return m.queryContacts(`SELECT ` + contactColumns + ` ` + contactFrom + ` ORDER BY c.created_at ASC`)
+}
+
+// GetInbox returns contacts newest first. An empty status lists everything
+// still to deal with: new, read and replied, leaving out archived and spam.
+func (m *ContactModel) GetInbox(status string) ([]*Contact, error) {
+	if status == "" {
+		return m.queryContacts(`SELECT `+contactColumns+` `+contactFrom+`
+			WHERE c.status IN ($1, $2, $3) ORDER BY c.created_at DESC`,
+			ContactNew, ContactRead, ContactReplied)
+	}
+	if !ValidContactStatus(status) {
+		return nil, fmt.Errorf("unknown contact status %q", status)
+	}
+	return m.queryContacts(`SELECT `+contactColumns+` `+contactFrom+`
+		WHERE c.status = $1 ORDER BY c.created_at DESC`, status)
+}
+
+// StatusCounts returns how many contacts are in each status
+func (m *ContactModel) StatusCounts() (map[string]int, error) {
 	rows, err := m.DB.Query(context.Background(),
-		`SELECT id, first_name, last_name, email, subject, message, created_at
-		 FROM contacts ORDER BY created_at ASC`)
+		`SELECT status, COUNT(*) FROM contacts GROUP BY status`)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var contacts []*Contact
+	counts := make(map[string]int, len(ContactStatuses))
+	for _, s := range ContactStatuses {
+		counts[s] = 0
+	}
 	for rows.Next() {
-		c := &Contact{}
-		err := rows.Scan(&c.ID, &c.FirstName, &c.LastName, &c.Email, &c.Subject, &c.Message, &c.CreatedAt)
-		if err != nil {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
 			return nil, err
 		}
-		contacts = append(contacts, c)
+		counts[status] = n
 	}
-	return contacts, nil
+	return counts, rows.Err()
 }
 
-// Get Count of all contacts
-func (m *ContactModel) Count() (int, error) {
-	var count int
-	err := m.DB.QueryRow(context.Background(),
-		`SELECT COUNT(*) FROM contacts`).Scan(&count)
+func (m *ContactModel) GetByID(id int) (*Contact, error) {
+	c, err := scanContact(m.DB.QueryRow(context.Background(),
+		`SELECT `+contactColumns+` `+contactFrom+` WHERE c.id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("no contact found with ID %d", id)
+	}
+	return c, err
+}
+
+// SetStatus moves a contact to status
+func (m *ContactModel) SetStatus(id int, status string) error {
+	if !ValidContactStatus(status) {
+		return fmt.Errorf("unknown contact status %q", status)
+	}
+	return m.update(id, `status = $2`, status)
+}
+
+// MarkRead moves a new contact to read; contacts in any other status are
+// left alone, so opening a replied or archived one doesn't change it
+func (m *ContactModel) MarkRead(id int) error {
+	_, err := m.DB.Exec(context.Background(),
+		`UPDATE contacts SET status = $2, updated_at = NOW() WHERE id = $1 AND status = $3`,
+		id, ContactRead, ContactNew)
+	return err
+}
+
+// Assign hands a contact to a team member; a userID of 0 unassigns it
+func (m *ContactModel) Assign(id, userID int) error {
+	var assignee *int
+	if userID > 0 {
+		assignee = &userID
+	}
+	return m.update(id, `assigned_to = $2`, assignee)
+}
+
+// SetNotes replaces a contact's internal notes
+func (m *ContactModel) SetNotes(id int, notes string) error {
+	return m.update(id, `notes = $2`, notes)
+}
+
+func (m *ContactModel) update(id int, set string, value any) error {
+	tag, err := m.DB.Exec(context.Background(),
+		`UPDATE contacts SET `+set+`, updated_at = NOW() WHERE id = $1`, id, value)
 	if err != nil {
-		return 0, err
+		return err
 	}
-	return count, nil
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("no contact found with ID %d", id)
+	}
+	return nil
 }
 
-// Get 10 latest contacts
-func (m *ContactModel) GetLatest(limit int) ([]*Contact, error) {
-	rows, err := m.DB.Query(context.Background(),
-		`SELECT id, first_name, last_name, email, subject, message, created_at
-		 FROM contacts ORDER BY created_at DESC LIMIT $1`, limit)
+// AddReply records a reply sent by userID under a contact and marks the
+// contact replied. emailID is the outbox email carrying it, if any.
+func (m *ContactModel) AddReply(contactID, userID, emailID int, subject, body string) (*ContactMessage, error) {
+	ctx := context.Background()
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	msg := &ContactMessage{ContactID: contactID, Subject: subject, Body: body}
+	if userID > 0 {
+		msg.UserID = &userID
+	}
+	if emailID > 0 {
+		msg.EmailID = &emailID
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO contact_messages (contact_id, user_id, email_id, subject, body)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`,
+		contactID, msg.UserID, msg.EmailID, subject, body).Scan(&msg.ID, &msg.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx,
+		`UPDATE contacts SET status = $2, updated_at = NOW() WHERE id = $1`, contactID, ContactReplied)
+	if err != nil {
+		return nil, err
+	}
+	return msg, tx.Commit(ctx)
+}
+
+// GetMessages returns the replies sent to a contact, oldest first
+func (m *ContactModel) GetMessages(contactID int) ([]*ContactMessage, error) {
+	rows, err := m.DB.Query(context.Background(), `
+		SELECT cm.id, cm.contact_id, cm.user_id, COALESCE(u.fname || ' ' || u.lname, ''),
+		       cm.subject, cm.body, cm.created_at, cm.email_id, COALESCE(o.status, '')
+		FROM contact_messages cm
+		LEFT JOIN users u ON u.id = cm.user_id
+		LEFT JOIN outbox o ON o.id = cm.email_id
+		WHERE cm.contact_id = $1
+		ORDER BY cm.created_at ASC, cm.id ASC`, contactID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var contacts []*Contact
+	var messages []*ContactMessage
 	for rows.Next() {
-		c := &Contact{}
-		err := rows.Scan(&c.ID, &c.FirstName, &c.LastName, &c.Email, &c.Subject, &c.Message, &c.CreatedAt)
-		if err != nil {
+		msg := &ContactMessage{}
+		if err := rows.Scan(&msg.ID, &msg.ContactID, &msg.UserID, &msg.AuthorName,
+			&msg.Subject, &msg.Body, &msg.CreatedAt, &msg.EmailID, &msg.EmailStatus); err != nil {
 			return nil, err
 		}
-		contacts = append(contacts, c)
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
+
+// Get Count of all contacts
+func (m *ContactModel) Count() (int, error) {
+	var count int
+	err := m.DB.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM contacts`).Scan(&count)
+	if err != nil {
+		return 0, err
 	}
-	return contacts, nil
+	return count, nil
+}
+
+// Get 10 latest contacts
+func (m *ContactModel) GetLatest(limit int) ([]*Contact, error) {
+	return m.queryContacts(`SELECT `+contactColumns+` `+contactFrom+`
+		ORDER BY c.created_at DESC LIMIT $1`, limit)
 }
@@ -0,0 +1,100 @@
+package models
+
+import "testing"
+
+func TestContactModel_Workflow(t *testing.T) {
+	db := setupTestDB(t)
+	users := &UserModel{DB: db}
+	model := &ContactModel{DB: db}
+
+	if err := users.Create("Sam", "Lee", "sam@example.com", "secret"); err != nil {
+		t.Fatalf("create user failed: %v", err)
+	}
+	user, _ := users.Authenticate("sam@example.com", "secret")
+
+	if err := model.Insert("Ana", "Diaz", "ana@example.com", "Wedding", "Are you free in May?"); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	model.Insert("Bot", "", "bot@example.com", "Buy now", "Cheap pills")
+
+	all, _ := model.GetAll()
+	if len(all) != 2 {
+		t.Fatalf("Expected 2 contacts, got %d", len(all))
+	}
+	ana, bot := all[0], all[1]
+	if ana.Status != ContactNew {
+		t.Errorf("Expected new contacts to be %q, got %q", ContactNew, ana.Status)
+	}
+
+	cases := []struct {
+		name    string
+		edit    func() error
+		wantErr bool
+	}{
+		{"✅ mark read", func() error { return model.MarkRead(ana.ID) }, false},
+		{"✅ assign", func() error { return model.Assign(ana.ID, user.ID) }, false},
+		{"✅ notes", func() error { return model.SetNotes(ana.ID, "Call back Tuesday") }, false},
+		{"✅ mark spam", func() error { return model.SetStatus(bot.ID, ContactSpam) }, false},
+		{"❌ unknown status", func() error { return model.SetStatus(ana.ID, "deleted") }, true},
+		{"❌ unknown contact", func() error { return model.SetNotes(9999, "x") }, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.edit(); (err != nil) != tc.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+
+	got, err := model.GetByID(ana.ID)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if got.Status != ContactRead || got.AssigneeID() != user.ID || got.Notes != "Call back Tuesday" {
+		t.Errorf("Unexpected contact after edits: %+v", got)
+	}
+
+	// Opening a contact again doesn't undo a later status
+	if _, err := model.AddReply(ana.ID, user.ID, 0, "Re: Wedding", "Yes, I am!"); err != nil {
+		t.Fatalf("AddReply failed: %v", err)
+	}
+	model.MarkRead(ana.ID)
+	got, _ = model.GetByID(ana.ID)
+	if got.Status != ContactReplied {
+		t.Errorf("Expected %q, got %q", ContactReplied, got.Status)
+	}
+
+	messages, err := model.GetMessages(ana.ID)
+	if err != nil {
+		t.Fatalf("GetMessages failed: %v", err)
+	}
+	if len(messages) != 1 || messages[0].Body != "Yes, I am!" || messages[0].AuthorName != "Sam Lee" {
+		t.Errorf("Unexpected thread: %+v", messages)
+	}
+
+	inbox, _ := model.GetInbox("")
+	if len(inbox) != 1 || inbox[0].ID != ana.ID {
+		t.Errorf("Expected only Ana in the inbox, got %d contacts", len(inbox))
+	}
+	spam, _ := model.GetInbox(ContactSpam)
+	if len(spam) != 1 || spam[0].ID != bot.ID {
+		t.Errorf("Expected only the bot in spam, got %d contacts", len(spam))
+	}
+	counts, _ := model.StatusCounts()
+	if counts[ContactReplied] != 1 || counts[ContactSpam] != 1 || counts[ContactNew] != 0 {
+		t.Errorf("Unexpected counts: %v", counts)
+	}
+
+	// Caught by the spam filter on the way in
+	if err := model.InsertSpam("Eve", "", "eve@example.com", "SEO", "Cheap backlinks", "blocked term"); err != nil {
+		t.Fatalf("InsertSpam failed: %v", err)
+	}
+	spam, _ = model.GetInbox(ContactSpam)
+	if len(spam) != 2 || spam[0].Email != "eve@example.com" || spam[0].SpamReason != "blocked term" {
+		t.Errorf("Expected Eve first in spam with the filter's reason, got %+v", spam[0])
+	}
+	if inbox, _ := model.GetInbox(""); len(inbox) != 1 {
+		t.Errorf("Expected spam to stay out of the inbox, got %d contacts", len(inbox))
+	}
+}
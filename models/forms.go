@@ -1,10 +1,12 @@
 package models
 
 type ContactForm struct {
-	FirstName      string `validate:"required,max=50"`
-	LastName       string `validate:"required,max=50"`
-	Email          string `validate:"required,email"`
-	Subject        string `validate:"required,max=200"`
-	Message        string `validate:"required"`
-	RecaptchaToken string `form:"g-recaptcha-response" validate:"required"`
+	FirstName string `validate:"required,max=50"`
+	LastName  string `validate:"required,max=50"`
+	Email     string `validate:"required,email"`
+	Subject   string `validate:"required,max=200"`
+	Message   string `validate:"required"`
+	// The captcha widget's token; which field it comes from, and whether
+	// it's needed at all, depends on the configured verifier
+	CaptchaToken string
 }
@@ -2,11 +2,15 @@ package models
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type Gallery struct {
@@ -19,6 +23,25 @@ type Gallery struct {
 	CoverImageURL *string // ✅ Stores image URL (Joined from media)
 	MediaCount    int     // ✅ Count of Media Items
 	Published     bool
+	// WatermarkOptOut skips the watermark when rendering public derivatives
+	WatermarkOptOut bool
+	// ClientAccess galleries are unlisted and only open at their slug to
+	// visitors who have entered the gallery password
+	ClientAccess bool
+	PasswordHash string
+	// SelectionLimit caps how many favourites a client can pick; 0 is no limit
+	SelectionLimit int
+	// PublishAt and UnpublishAt flip Published when they pass; see ApplySchedule
+	PublishAt   *time.Time
+	UnpublishAt *time.Time
+	// ParentID is the collection this gallery is nested in; see SetParent
+	ParentID *int
+}
+
+// IsPublic reports whether anyone may see the gallery, without a share link
+// or password
+func (g *Gallery) IsPublic() bool {
+	return g.Published && !g.ClientAccess
 }
 
 type GalleryModel struct {
@@ -32,7 +55,7 @@ func (g *GalleryModel) GetByTitle(title string) (*Gallery, []*Media, error) {
 
 	// Fetch the gallery by title
 	err := g.DB.QueryRow(context.Background(),
-		"SELECT id, title, slug, description FROM galleries WHERE title=$1", title).
+		"SELECT id, title, slug, description FROM galleries WHERE title=$1 AND deleted_at IS NULL", title).
 		Scan(&gallery.ID, &gallery.Title, &gallery.Slug, &gallery.Description)
 
 	if err != nil {
@@ -46,7 +69,7 @@ func (g *GalleryModel) GetByTitle(title string) (*Gallery, []*Media, error) {
 		        gm.position
 		 FROM media m 
 		 JOIN gallery_media gm ON m.id = gm.media_id
-		 WHERE gm.gallery_id = $1
+		 WHERE gm.gallery_id = $1 AND m.deleted_at IS NULL
 		 ORDER BY gm.position ASC`, gallery.ID)
 	if err != nil {
 		return nil, nil, err
@@ -82,7 +105,7 @@ func (g *GalleryModel) SetFeatured(id int) error {
 	}
 
 	// Set the new featured gallery
-	res, err := g.DB.Exec(context.Background(), "UPDATE galleries SET featured = TRUE WHERE id = $1", id)
+	res, err := g.DB.Exec(context.Background(), "UPDATE galleries SET featured = TRUE WHERE id = $1 AND deleted_at IS NULL", id)
 	if err != nil {
 		return err
 	}
@@ -97,18 +120,93 @@ func (g *GalleryModel) Create(title, description, slug string) error {
 	if strings.TrimSpace(slug) == "" {
 		return fmt.Errorf("slug cannot be empty")
 	}
-	_, err := g.DB.Exec(context.Background(), "INSERT INTO galleries (title, description, slug) VALUES ($1, $2, $3)", title, description, slug)
+	ctx := context.Background()
+	slug, err := uniqueSlug(ctx, g.DB, "galleries", slug, 0)
+	if err != nil {
+		return err
+	}
+	_, err = g.DB.Exec(ctx,
+		"INSERT INTO galleries (title, description, slug, position) VALUES ($1, $2, $3, "+nextGalleryPosition+")",
+		title, description, slug)
 	return err
 }
 
+// CreateAndReturnID adds a new gallery and returns its ID
+func (g *GalleryModel) CreateAndReturnID(title, description, slug string) (int, error) {
+	if strings.TrimSpace(slug) == "" {
+		return 0, fmt.Errorf("slug cannot be empty")
+	}
+	ctx := context.Background()
+	slug, err := uniqueSlug(ctx, g.DB, "galleries", slug, 0)
+	if err != nil {
+		return 0, err
+	}
+	var id int
+	err = g.DB.QueryRow(ctx,
+		"INSERT INTO galleries (title, description, slug, position) VALUES ($1, $2, $3, "+nextGalleryPosition+") RETURNING id",
+		title, description, slug).Scan(&id)
+	return id, err
+}
+
+// CreateForImport is CreateAndReturnID for galleries named after an uploaded
+// archive, whose title may have nothing to make a slug from. Those get
+// "gallery-<id>" instead.
+func (g *GalleryModel) CreateForImport(title, slug string) (int, error) {
+	if strings.TrimSpace(slug) != "" {
+		return g.CreateAndReturnID(title, "", slug)
+	}
+
+	ctx := context.Background()
+	tx, err := g.DB.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var id int
+	err = tx.QueryRow(ctx, `SELECT nextval(pg_get_serial_sequence('galleries', 'id'))`).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	slug, err = uniqueSlug(ctx, tx, "galleries", fmt.Sprintf("gallery-%d", id), 0)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec(ctx,
+		"INSERT INTO galleries (id, title, description, slug, position) VALUES ($1, $2, '', $3, "+nextGalleryPosition+")",
+		id, title, slug)
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit(ctx)
+}
+
 func (g *GalleryModel) GetAllPublic() ([]map[string]interface{}, error) {
+	// Sub-galleries are listed on their collection's page while it's live
+	return g.getPublic(`AND NOT EXISTS (
+		SELECT 1 FROM galleries p
+		WHERE p.id = g.parent_id AND p.published = TRUE AND p.deleted_at IS NULL)`)
+}
+
+// GetPublicByCategory returns the published galleries in a category
+func (g *GalleryModel) GetPublicByCategory(categoryID int) ([]map[string]interface{}, error) {
+	return g.getPublic(`AND g.id IN (SELECT gallery_id FROM gallery_categories WHERE category_id = $1)`, categoryID)
+}
+
+// GetPublished returns every published gallery, sub-galleries included, for
+// pickers that may only offer public content
+func (g *GalleryModel) GetPublished() ([]map[string]interface{}, error) {
+	return g.getPublic(`AND COALESCE(g.client_access, FALSE) = FALSE`)
+}
+
+func (g *GalleryModel) getPublic(filter string, args ...any) ([]map[string]interface{}, error) {
 	rows, err := g.DB.Query(context.Background(),
 		`SELECT g.id, g.title, g.slug, g.description, g.cover_image_id, m.full_url AS cover_image_url,
-                (SELECT COUNT(*) FROM gallery_media WHERE gallery_media.gallery_id = g.id) AS media_count
+                (SELECT COUNT(*) FROM gallery_media gm2 JOIN media m2 ON m2.id = gm2.media_id WHERE gm2.gallery_id = g.id AND m2.deleted_at IS NULL) AS media_count
          FROM galleries g
-         LEFT JOIN media m ON g.cover_image_id = m.id
-         WHERE g.published = TRUE
-         ORDER BY g.id ASC`)
+         LEFT JOIN media m ON g.cover_image_id = m.id AND m.deleted_at IS NULL
+         WHERE g.published = TRUE AND g.deleted_at IS NULL `+filter+`
+         ORDER BY `+galleryListOrder, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -147,10 +245,12 @@ func (g *GalleryModel) GetAllPublic() ([]map[string]interface{}, error) {
 func (g *GalleryModel) GetAll() ([]map[string]interface{}, error) {
 	rows, err := g.DB.Query(context.Background(),
 		`SELECT g.id, g.title, g.slug, g.description, g.published, m.full_url AS cover_image_url,
-	       (SELECT COUNT(*) FROM gallery_media WHERE gallery_media.gallery_id = g.id) AS media_count
+	       (SELECT COUNT(*) FROM gallery_media gm2 JOIN media m2 ON m2.id = gm2.media_id WHERE gm2.gallery_id = g.id AND m2.deleted_at IS NULL) AS media_count,
+	       COALESCE(g.client_access, FALSE), g.publish_at, g.unpublish_at, COALESCE(g.featured, FALSE)
 		FROM galleries g
-		LEFT JOIN media m ON g.cover_image_id = m.id
-		ORDER BY g.id ASC`)
+		LEFT JOIN media m ON g.cover_image_id = m.id AND m.deleted_at IS NULL
+		WHERE g.deleted_at IS NULL
+		ORDER BY `+galleryListOrder)
 	if err != nil {
 		return nil, err
 	}
@@ -165,8 +265,11 @@ func (g *GalleryModel) GetAll() ([]map[string]interface{}, error) {
 		var coverImageURL *string
 		var mediaCount int
 		var description string
+		var clientAccess bool
+		var publishAt, unpublishAt *time.Time
+		var featured bool
 
-		err := rows.Scan(&id, &title, &slug, &description, &published, &coverImageURL, &mediaCount)
+		err := rows.Scan(&id, &title, &slug, &description, &published, &coverImageURL, &mediaCount, &clientAccess, &publishAt, &unpublishAt, &featured)
 		if err != nil {
 			return nil, err
 		}
@@ -178,6 +281,10 @@ func (g *GalleryModel) GetAll() ([]map[string]interface{}, error) {
 			"CoverImageURL": coverImageURL,
 			"MediaCount":    mediaCount, // ✅ Media count included
 			"Published":     published,  // ✅ Include Published field
+			"ClientAccess":  clientAccess,
+			"PublishAt":     publishAt,
+			"UnpublishAt":   unpublishAt,
+			"Featured":      featured,
 		}
 		galleries = append(galleries, gallery)
 	}
@@ -203,20 +310,28 @@ func (g *GalleryModel) GetByID(id int) (*Gallery, error) {
 	var gallery Gallery
 	err := g.DB.QueryRow(context.Background(), `
 		SELECT g.id, g.title, g.slug, g.description, g.published, g.cover_image_id, m.full_url AS cover_image_url,
-			   (SELECT COUNT(*) FROM gallery_media WHERE gallery_media.gallery_id = g.id) AS media_count
+			   (SELECT COUNT(*) FROM gallery_media gm2 JOIN media m2 ON m2.id = gm2.media_id WHERE gm2.gallery_id = g.id AND m2.deleted_at IS NULL) AS media_count,
+			   COALESCE(g.watermark_opt_out, FALSE), COALESCE(g.client_access, FALSE), COALESCE(g.password_hash, ''),
+			   COALESCE(g.selection_limit, 0), g.publish_at, g.unpublish_at, g.parent_id
 		FROM galleries g
-		LEFT JOIN media m ON g.cover_image_id = m.id
-		WHERE g.id = $1
+		LEFT JOIN media m ON g.cover_image_id = m.id AND m.deleted_at IS NULL
+		WHERE g.id = $1 AND g.deleted_at IS NULL
 		`, id).
-		Scan(&gallery.ID, &gallery.Title, &gallery.Slug, &gallery.Description, &gallery.Published, &gallery.CoverImageID, &gallery.CoverImageURL, &gallery.MediaCount)
+		Scan(&gallery.ID, &gallery.Title, &gallery.Slug, &gallery.Description, &gallery.Published, &gallery.CoverImageID, &gallery.CoverImageURL, &gallery.MediaCount,
+			&gallery.WatermarkOptOut, &gallery.ClientAccess, &gallery.PasswordHash, &gallery.SelectionLimit,
+			&gallery.PublishAt, &gallery.UnpublishAt, &gallery.ParentID)
 
 	if err != nil {
 		log.Printf("⚠️ Scan fallback due to broken cover_image_id: %v", err)
 
 		// fallback query without the join
 		err = g.DB.QueryRow(context.Background(),
-			`SELECT id, title, description, slug, published, cover_image_id FROM galleries WHERE id = $1`, id).
-			Scan(&gallery.ID, &gallery.Title, &gallery.Description, &gallery.Slug, &gallery.Published, &gallery.CoverImageID)
+			`SELECT id, title, description, slug, published, cover_image_id, COALESCE(watermark_opt_out, FALSE),
+			        COALESCE(client_access, FALSE), COALESCE(password_hash, ''), COALESCE(selection_limit, 0),
+			        publish_at, unpublish_at, parent_id
+			 FROM galleries WHERE id = $1 AND deleted_at IS NULL`, id).
+			Scan(&gallery.ID, &gallery.Title, &gallery.Description, &gallery.Slug, &gallery.Published, &gallery.CoverImageID, &gallery.WatermarkOptOut,
+				&gallery.ClientAccess, &gallery.PasswordHash, &gallery.SelectionLimit, &gallery.PublishAt, &gallery.UnpublishAt, &gallery.ParentID)
 
 		// set to nil manually
 		gallery.CoverImageURL = nil
@@ -229,38 +344,136 @@ func (g *GalleryModel) GetByID(id int) (*Gallery, error) {
 	return &gallery, nil
 }
 
-// Update updates the title of an existing gallery
+// Duplicate copies a gallery into a new unpublished draft with its
+// description, watermark and selection settings, media in the same order,
+// cover unless it has been trashed, collection and categories. Client
+// access, schedules and featuring are not copied.
+func (g *GalleryModel) Duplicate(id int) (int, error) {
+	ctx := context.Background()
+	tx, err := g.DB.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var slug string
+	err = tx.QueryRow(ctx, `SELECT slug FROM galleries WHERE id = $1 AND deleted_at IS NULL`, id).Scan(&slug)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("no gallery found with ID %d", id)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	newSlug, err := uniqueSlug(ctx, tx, "galleries", slug+"-copy", 0)
+	if err != nil {
+		return 0, err
+	}
+
+	// A trashed cover stays behind with the rest of the trashed media
+	var newID int
+	err = tx.QueryRow(ctx, `
+		INSERT INTO galleries (title, description, slug, cover_image_id, watermark_opt_out, selection_limit, parent_id, position)
+		SELECT g.title || ' (copy)', g.description, $2, cover.id, g.watermark_opt_out, g.selection_limit, g.parent_id, `+nextGalleryPosition+`
+		FROM galleries g
+		LEFT JOIN media cover ON cover.id = g.cover_image_id AND cover.deleted_at IS NULL
+		WHERE g.id = $1
+		RETURNING id`, id, newSlug).Scan(&newID)
+	if err != nil {
+		return 0, err
+	}
+
+	// Trashed media stays behind
+	_, err = tx.Exec(ctx, `
+		INSERT INTO gallery_media (gallery_id, media_id, position)
+		SELECT $2, gm.media_id, ROW_NUMBER() OVER (ORDER BY gm.position) - 1
+		FROM gallery_media gm
+		JOIN media m ON m.id = gm.media_id
+		WHERE gm.gallery_id = $1 AND m.deleted_at IS NULL`, id, newID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO gallery_categories (gallery_id, category_id)
+		SELECT $2, category_id FROM gallery_categories WHERE gallery_id = $1`, id, newID)
+	if err != nil {
+		return 0, err
+	}
+
+	return newID, tx.Commit(ctx)
+}
+
+// Update updates the title, description and slug of an existing gallery. A
+// taken slug gets a numeric suffix, and the old slug redirects to the new one.
 func (g *GalleryModel) Update(id int, title, description, slug string) error {
 	if strings.TrimSpace(title) == "" {
 		return fmt.Errorf("title cannot be empty")
 	}
+	if strings.TrimSpace(slug) == "" {
+		return fmt.Errorf("slug cannot be empty")
+	}
 
-	res, err := g.DB.Exec(context.Background(), "UPDATE galleries SET title=$1, description=$2, slug=$3 WHERE id=$4", title, description, slug, id)
+	ctx := context.Background()
+	tx, err := g.DB.Begin(ctx)
 	if err != nil {
 		return err
 	}
-	if res.RowsAffected() == 0 {
-		return fmt.Errorf("no gallery found with ID %d", id)
+	defer tx.Rollback(ctx)
+
+	if err := renameSlug(ctx, tx, "galleries", id, slug); err != nil {
+		return err
 	}
-	return nil
-}
 
-// Delete removes a gallery from the database
-func (g *GalleryModel) Delete(id int) error {
-	result, err := g.DB.Exec(context.Background(), "DELETE FROM galleries WHERE id=$1", id)
+	_, err = tx.Exec(ctx, "UPDATE galleries SET title=$1, description=$2 WHERE id=$3", title, description, id)
 	if err != nil {
 		return err
 	}
+	return tx.Commit(ctx)
+}
 
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("no gallery found with ID %d", id)
-	}
+// CurrentSlug returns the current slug of the gallery that used to be at
+// oldSlug, or pgx.ErrNoRows if none did
+func (g *GalleryModel) CurrentSlug(oldSlug string) (string, error) {
+	return currentSlug(g.DB, "galleries", oldSlug)
+}
 
-	return nil
+// Delete moves a gallery to the trash
+func (g *GalleryModel) Delete(id int) error {
+	return trashRow(g.DB, "galleries", id)
+}
+
+// Restore takes a gallery out of the trash with its media and order intact
+func (g *GalleryModel) Restore(id int) error {
+	return restoreRow(g.DB, "galleries", id)
+}
+
+// Purge permanently deletes a trashed gallery. Its media stays in the library.
+func (g *GalleryModel) Purge(id int) error {
+	return purgeRow(g.DB, "galleries", id)
+}
+
+// PurgeTrashedBefore permanently deletes galleries trashed before cutoff
+func (g *GalleryModel) PurgeTrashedBefore(cutoff time.Time) (int64, error) {
+	return purgeRowsBefore(g.DB, "galleries", cutoff)
+}
+
+// GetTrashed lists trashed galleries, most recently deleted first
+func (g *GalleryModel) GetTrashed() ([]*TrashedItem, error) {
+	return trashedRows(g.DB, `
+		SELECT g.id, g.title, m.thumbnail_url, g.deleted_at
+		FROM galleries g
+		LEFT JOIN media m ON g.cover_image_id = m.id
+		WHERE g.deleted_at IS NOT NULL
+		ORDER BY g.deleted_at DESC`)
 }
 
 func (g *GalleryModel) SetPublished(id int, published bool) error {
-	result, err := g.DB.Exec(context.Background(), "UPDATE galleries SET published=$1 WHERE id=$2", published, id)
+	// Publishing a client gallery makes it public, so drop the password gate
+	result, err := g.DB.Exec(context.Background(), `
+		UPDATE galleries
+		SET published = $1, client_access = CASE WHEN $1 THEN FALSE ELSE client_access END
+		WHERE id = $2`, published, id)
 	if err != nil {
 		return fmt.Errorf("failed to set published status: %w", err)
 	}
@@ -272,122 +485,151 @@ func (g *GalleryModel) SetPublished(id int, published bool) error {
 	return nil
 }
 
-// GetMedia returns all media linked to a gallery via the gallery_media join table
+// SetSchedule sets when a gallery publishes and unpublishes itself. A nil
+// time clears that part of the schedule.
+func (g *GalleryModel) SetSchedule(id int, publishAt, unpublishAt *time.Time) error {
+	if err := validateSchedule(publishAt, unpublishAt); err != nil {
+		return err
+	}
 
-func (g *GalleryModel) GetMediaPaginated(galleryID, limit, offset int) ([]*Media, error) {
-	rows, err := g.DB.Query(context.Background(), `
-		SELECT m.id, m.file_name, m.thumbnail_url, m.full_url,
-			   COALESCE(m.mime_type, '') AS mime_type,
-			   gm.position
-		FROM gallery_media gm
-		JOIN media m ON gm.media_id = m.id
-		WHERE gm.gallery_id = $1
-		ORDER BY gm.position ASC
-		LIMIT $2 OFFSET $3
-	`, galleryID, limit, offset)
+	res, err := g.DB.Exec(context.Background(),
+		"UPDATE galleries SET publish_at = $1, unpublish_at = $2 WHERE id = $3", publishAt, unpublishAt, id)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
-
-	var media []*Media
-	for rows.Next() {
-		var m Media
-		if err := rows.Scan(&m.ID, &m.FileName, &m.ThumbnailURL, &m.FullURL, &m.MimeType, &m.Position); err != nil {
-			return nil, err
-		}
-		media = append(media, &m)
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("no gallery found with ID %d", id)
 	}
-
-	return media, nil
-}
-
-// AttachMedia links a media item to a gallery with an optional position
-func (g *GalleryModel) AttachMedia(galleryID, mediaID, position int) error {
-	_, err := g.DB.Exec(context.Background(), `
-		INSERT INTO gallery_media (gallery_id, media_id, position)
-		VALUES ($1, $2, $3)
-		ON CONFLICT (gallery_id, media_id) DO UPDATE
-		SET position = EXCLUDED.position
-	`, galleryID, mediaID, position)
-	return err
+	return nil
 }
 
-func (g *GalleryModel) ReorderMedia(galleryID, mediaID, newPosition int) error {
-	tx, err := g.DB.Begin(context.Background())
+// ApplySchedule publishes and unpublishes galleries whose scheduled time has
+// passed, and returns how many changed. Like SetPublished, publishing a
+// client gallery drops its password gate. Galleries in the trash are left
+// alone so restoring one doesn't bring it back published.
+func (g *GalleryModel) ApplySchedule() (int64, error) {
+	res, err := g.DB.Exec(context.Background(), `
+		UPDATE galleries SET
+			client_access = CASE
+				WHEN publish_at <= NOW() AND (unpublish_at IS NULL OR unpublish_at > NOW()) THEN FALSE
+				ELSE client_access END,`+scheduleSet+`
+		WHERE deleted_at IS NULL AND (`+scheduleDue+`)`)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	defer tx.Rollback(context.Background())
-
-	var currentPosition int
-	err = tx.QueryRow(context.Background(), `
-		SELECT position FROM gallery_media
-		WHERE gallery_id = $1 AND media_id = $2
-	`, galleryID, mediaID).Scan(&currentPosition)
+	return res.RowsAffected(), nil
+}
 
+// SetWatermarkOptOut toggles whether a gallery's public derivatives are watermarked
+func (g *GalleryModel) SetWatermarkOptOut(id int, optOut bool) error {
+	result, err := g.DB.Exec(context.Background(), "UPDATE galleries SET watermark_opt_out=$1 WHERE id=$2", optOut, id)
 	if err != nil {
-		return fmt.Errorf("failed to fetch current position: %w", err)
+		return fmt.Errorf("failed to set watermark opt-out: %w", err)
 	}
 
-	// No position change
-	if currentPosition == newPosition {
-		return nil
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("no rows affected, gallery with ID %d may not exist", id)
 	}
 
-	// Move everything else to make room
-	if currentPosition < newPosition {
-		_, err = tx.Exec(context.Background(), `
-			UPDATE gallery_media
-			SET position = position - 1
-			WHERE gallery_id = $1 AND position > $2 AND position <= $3
-		`, galleryID, currentPosition, newPosition)
-	} else {
-		_, err = tx.Exec(context.Background(), `
-			UPDATE gallery_media
-			SET position = position + 1
-			WHERE gallery_id = $1 AND position >= $3 AND position < $2
-		`, galleryID, newPosition, currentPosition)
+	return nil
+}
+
+// SetClientPassword turns a gallery into a private client gallery, or
+// changes the password of one. Client galleries are never published.
+func (g *GalleryModel) SetClientPassword(id int, password string) error {
+	if strings.TrimSpace(password) == "" {
+		return fmt.Errorf("password cannot be empty")
 	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return err
 	}
 
-	// Move the target
-	_, err = tx.Exec(context.Background(), `
-		UPDATE gallery_media
-		SET position = $1
-		WHERE gallery_id = $2 AND media_id = $3
-	`, newPosition, galleryID, mediaID)
+	result, err := g.DB.Exec(context.Background(), `
+		UPDATE galleries SET client_access = TRUE, published = FALSE, password_hash = $1
+		WHERE id = $2`, string(hash), id)
+	if err != nil {
+		return fmt.Errorf("failed to set client password: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("no gallery found with ID %d", id)
+	}
+	return nil
+}
 
+// ClearClientAccess removes the password gate, leaving the gallery unpublished
+func (g *GalleryModel) ClearClientAccess(id int) error {
+	result, err := g.DB.Exec(context.Background(),
+		"UPDATE galleries SET client_access = FALSE, password_hash = NULL WHERE id = $1", id)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to clear client access: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("no gallery found with ID %d", id)
 	}
+	return nil
+}
 
-	return tx.Commit(context.Background())
+// SetSelectionLimit caps how many favourites a client can pick (0 for no limit)
+func (g *GalleryModel) SetSelectionLimit(id, limit int) error {
+	if limit < 0 {
+		return fmt.Errorf("selection limit cannot be negative")
+	}
+	result, err := g.DB.Exec(context.Background(), "UPDATE galleries SET selection_limit = $1 WHERE id = $2", limit, id)
+	if err != nil {
+		return fmt.Errorf("failed to set selection limit: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("no gallery found with ID %d", id)
+	}
+	return nil
 }
 
-func (g *GalleryModel) GetNextPosition(galleryID int) (int, error) {
-	var maxPosition int
+// CheckPassword reports whether password unlocks a client gallery
+func (gallery *Gallery) CheckPassword(password string) bool {
+	if !gallery.ClientAccess || gallery.PasswordHash == "" {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(gallery.PasswordHash), []byte(password)) == nil
+}
 
-	err := g.DB.QueryRow(context.Background(), `
-		SELECT COALESCE(MAX(position), -1)
-		FROM gallery_media
-		WHERE gallery_id = $1
-	`, galleryID).Scan(&maxPosition)
+// GetMedia returns all media linked to a gallery via the gallery_media join table
 
+func (g *GalleryModel) GetMediaPaginated(galleryID, limit, offset int) ([]*Media, error) {
+	rows, err := g.DB.Query(context.Background(), `
+		SELECT m.id, m.file_name, m.thumbnail_url, m.full_url,
+			   COALESCE(m.mime_type, '') AS mime_type,
+			   gm.position,
+			   COALESCE(m.blurhash, ''), COALESCE(m.dominant_color, '')
+		FROM gallery_media gm
+		JOIN media m ON gm.media_id = m.id
+		WHERE gm.gallery_id = $1 AND m.deleted_at IS NULL
+		ORDER BY gm.position ASC
+		LIMIT $2 OFFSET $3
+	`, galleryID, limit, offset)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
+	defer rows.Close()
 
-	return maxPosition + 1, nil
+	var media []*Media
+	for rows.Next() {
+		var m Media
+		if err := rows.Scan(&m.ID, &m.FileName, &m.ThumbnailURL, &m.FullURL, &m.MimeType, &m.Position, &m.BlurHash, &m.DominantColor); err != nil {
+			return nil, err
+		}
+		media = append(media, &m)
+	}
+
+	return media, nil
 }
 
 // Get Count of galleries in galleries table
 func (g *GalleryModel) Count() (int, error) {
 	var count int
 	err := g.DB.QueryRow(context.Background(), `
-		SELECT COUNT(*) FROM galleries
+		SELECT COUNT(*) FROM galleries WHERE deleted_at IS NULL
 	`).Scan(&count)
 	if err != nil {
 		return 0, err
@@ -400,7 +642,7 @@ func (g *GalleryModel) GetLatest(limit int) ([]*Gallery, error) {
 	rows, err := g.DB.Query(context.Background(), `
 		SELECT id, title, slug, description, cover_image_id, featured
 		FROM galleries
-		WHERE published = TRUE
+		WHERE published = TRUE AND deleted_at IS NULL
 		ORDER BY id DESC
 		LIMIT $1`, limit)
 	if err != nil {
@@ -425,7 +667,9 @@ func (g *GalleryModel) GetLatest(limit int) ([]*Gallery, error) {
 func (g *GalleryModel) GetMediaCount(galleryID int) (int, error) {
 	var count int
 	err := g.DB.QueryRow(context.Background(),
-		`SELECT COUNT(*) FROM gallery_media WHERE gallery_id = $1`, galleryID).Scan(&count)
+		`SELECT COUNT(*) FROM gallery_media gm
+		 JOIN media m ON m.id = gm.media_id
+		 WHERE gm.gallery_id = $1 AND m.deleted_at IS NULL`, galleryID).Scan(&count)
 	if err != nil {
 		return 0, err
 	}
@@ -435,9 +679,11 @@ func (g *GalleryModel) GetMediaCount(galleryID int) (int, error) {
 func (g *GalleryModel) GetBySlug(slug string) (*Gallery, error) {
 	var gallery Gallery
 	err := g.DB.QueryRow(context.Background(), `
-		SELECT id, title, slug, description, cover_image_id, published
-		FROM galleries WHERE slug = $1`, slug).Scan(
+		SELECT id, title, slug, description, cover_image_id, published,
+		       COALESCE(client_access, FALSE), COALESCE(password_hash, ''), COALESCE(selection_limit, 0), parent_id
+		FROM galleries WHERE slug = $1 AND deleted_at IS NULL`, slug).Scan(
 		&gallery.ID, &gallery.Title, &gallery.Slug, &gallery.Description, &gallery.CoverImageID, &gallery.Published,
+		&gallery.ClientAccess, &gallery.PasswordHash, &gallery.SelectionLimit, &gallery.ParentID,
 	)
 	if err != nil {
 		return nil, err
@@ -1,6 +1,12 @@
 package models
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
 
 func TestGalleryModel_CRUD(t *testing.T) {
 	db := setupTestDB(t)
@@ -35,11 +41,11 @@ func TestGalleryModel_CRUD(t *testing.T) {
 			wantErr:     true,
 		},
 		{
-			name:        "❌ duplicate slug",
+			name:        "✅ duplicate slug gets a suffix",
 			title:       "Another Nature Gallery",
-			description: "Should conflict with earlier slug",
+			description: "Lands on summer-gallery-2",
 			slug:        "summer-gallery", // same as the first one
-			wantErr:     true,
+			wantErr:     false,
 		},
 	}
 
@@ -66,10 +72,11 @@ func TestGalleryModel_Update(t *testing.T) {
 	model := &GalleryModel{DB: db}
 
 	cases := []struct {
-		name    string  // descriptive name for t.Run()
-		initial Gallery // initial gallery we insert
-		updated Gallery // new values to apply in Update()
-		wantErr bool    // do we expect Update() to fail?
+		name     string  // descriptive name for t.Run()
+		initial  Gallery // initial gallery we insert
+		updated  Gallery // new values to apply in Update()
+		wantSlug string  // slug we expect when the requested one is taken
+		wantErr  bool    // do we expect Update() to fail?
 	}{
 		{
 			name: "✅ successful update",
@@ -100,7 +107,7 @@ func TestGalleryModel_Update(t *testing.T) {
 			wantErr: true,
 		},
 		{
-			name: "❌ update with duplicate slug",
+			name: "✅ update with duplicate slug gets a suffix",
 			initial: Gallery{
 				Title:       "Initial Title",
 				Description: "Initial Description",
@@ -111,7 +118,8 @@ func TestGalleryModel_Update(t *testing.T) {
 				Description: "Updated Description",
 				Slug:        "initial-slug-2", // same as the initial slug
 			},
-			wantErr: true,
+			wantSlug: "initial-slug-2-2",
+			wantErr:  false,
 		},
 	}
 
@@ -158,8 +166,12 @@ func TestGalleryModel_Update(t *testing.T) {
 			if updated.Description != tc.updated.Description {
 				t.Errorf("Expected description %q, got %q", tc.updated.Description, updated.Description)
 			}
-			if updated.Slug != tc.updated.Slug {
-				t.Errorf("Expected slug %q, got %q", tc.updated.Slug, updated.Slug)
+			wantSlug := tc.updated.Slug
+			if tc.wantSlug != "" {
+				wantSlug = tc.wantSlug
+			}
+			if updated.Slug != wantSlug {
+				t.Errorf("Expected slug %q, got %q", wantSlug, updated.Slug)
 			}
 
 		})
@@ -376,3 +388,561 @@ func TestGalleryModel_SetCoverImage(t *testing.T) {
 		})
 	}
 }
+
+// TESTING SETWATERMARKOPTOUT FUNCTION
+func TestGalleryModel_SetWatermarkOptOut(t *testing.T) {
+	db := setupTestDB(t)
+	model := &GalleryModel{DB: db}
+
+	err := model.Create("Client Shoot", "No watermark", "client-shoot")
+	if err != nil {
+		t.Fatalf("create gallery failed: %v", err)
+	}
+	gallery, _ := model.GetBySlug("client-shoot")
+
+	cases := []struct {
+		name    string
+		id      int
+		optOut  bool
+		wantErr bool
+	}{
+		{"✅ opt out", gallery.ID, true, false},
+		{"✅ opt back in", gallery.ID, false, false},
+		{"❌ invalid ID", 9999, true, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := model.SetWatermarkOptOut(tc.id, tc.optOut)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("SetWatermarkOptOut() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+
+			g, err := model.GetByID(tc.id)
+			if err != nil {
+				t.Fatalf("GetByID failed: %v", err)
+			}
+			if g.WatermarkOptOut != tc.optOut {
+				t.Errorf("Expected WatermarkOptOut %v, got %v", tc.optOut, g.WatermarkOptOut)
+			}
+		})
+	}
+}
+
+// TESTING CREATEANDRETURNID FUNCTION
+func TestGalleryModel_CreateAndReturnID(t *testing.T) {
+	db := setupTestDB(t)
+	model := &GalleryModel{DB: db}
+
+	cases := []struct {
+		name     string
+		title    string
+		slug     string
+		wantSlug string
+		wantErr  bool
+	}{
+		{"✅ imported gallery", "Wedding 2024", "wedding-2024", "wedding-2024", false},
+		{"✅ duplicate slug gets a suffix", "Wedding 2024", "wedding-2024", "wedding-2024-2", false},
+		{"❌ missing slug", "No Slug", "", "", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			id, err := model.CreateAndReturnID(tc.title, "", tc.slug)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("CreateAndReturnID() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+
+			g, err := model.GetByID(id)
+			if err != nil {
+				t.Fatalf("GetByID failed: %v", err)
+			}
+			if g.Slug != tc.wantSlug {
+				t.Errorf("Expected slug %q, got %q", tc.wantSlug, g.Slug)
+			}
+		})
+	}
+}
+
+// TESTING CLIENT ACCESS FUNCTIONS
+func TestGalleryModel_ClientAccess(t *testing.T) {
+	db := setupTestDB(t)
+	model := &GalleryModel{DB: db}
+
+	err := model.Create("Smith Wedding", "Proofs", "smith-wedding")
+	if err != nil {
+		t.Fatalf("create gallery failed: %v", err)
+	}
+	gallery, _ := model.GetBySlug("smith-wedding")
+	if err := model.SetPublished(gallery.ID, true); err != nil {
+		t.Fatalf("SetPublished failed: %v", err)
+	}
+
+	cases := []struct {
+		name     string
+		id       int
+		password string
+		wantErr  bool
+	}{
+		{"✅ set password", gallery.ID, "sunflower", false},
+		{"✅ change password", gallery.ID, "daffodil", false},
+		{"❌ empty password", gallery.ID, "  ", true},
+		{"❌ invalid ID", 9999, "sunflower", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := model.SetClientPassword(tc.id, tc.password)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("SetClientPassword() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+
+			g, err := model.GetBySlug("smith-wedding")
+			if err != nil {
+				t.Fatalf("GetBySlug failed: %v", err)
+			}
+			if !g.ClientAccess || g.Published {
+				t.Errorf("Expected unpublished client gallery, got client=%v published=%v", g.ClientAccess, g.Published)
+			}
+			if !g.CheckPassword(tc.password) {
+				t.Errorf("Expected password %q to unlock the gallery", tc.password)
+			}
+			if g.CheckPassword("wrong") {
+				t.Error("Expected wrong password to be rejected")
+			}
+		})
+	}
+
+	// Publishing makes it public again, without the password gate
+	if err := model.SetPublished(gallery.ID, true); err != nil {
+		t.Fatalf("SetPublished failed: %v", err)
+	}
+	g, _ := model.GetByID(gallery.ID)
+	if g.ClientAccess {
+		t.Error("Expected publishing to clear client access")
+	}
+
+	if err := model.SetClientPassword(gallery.ID, "sunflower"); err != nil {
+		t.Fatalf("SetClientPassword failed: %v", err)
+	}
+	if err := model.ClearClientAccess(gallery.ID); err != nil {
+		t.Fatalf("ClearClientAccess failed: %v", err)
+	}
+	g, _ = model.GetByID(gallery.ID)
+	if g.ClientAccess || g.CheckPassword("sunflower") {
+		t.Error("Expected client access to be cleared")
+	}
+}
+
+func TestGalleryModel_CreateForImport(t *testing.T) {
+	db := setupTestDB(t)
+	model := &GalleryModel{DB: db}
+
+	cases := []struct {
+		name     string
+		title    string
+		slug     string
+		wantSlug func(id int) string
+	}{
+		{"✅ slug from title", "Holiday", "holiday", func(int) string { return "holiday" }},
+		{"✅ nothing to slugify", "🎉🎉", "", func(id int) string { return fmt.Sprintf("gallery-%d", id) }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			id, err := model.CreateForImport(tc.title, tc.slug)
+			if err != nil {
+				t.Fatalf("CreateForImport failed: %v", err)
+			}
+
+			g, err := model.GetByID(id)
+			if err != nil {
+				t.Fatalf("GetByID failed: %v", err)
+			}
+			if want := tc.wantSlug(id); g.Slug != want {
+				t.Errorf("Expected slug %q, got %q", want, g.Slug)
+			}
+			if g.Title != tc.title {
+				t.Errorf("Expected title %q, got %q", tc.title, g.Title)
+			}
+		})
+	}
+}
+
+// TESTING SCHEDULED PUBLISHING
+func TestGalleryModel_ApplySchedule(t *testing.T) {
+	db := setupTestDB(t)
+	model := &GalleryModel{DB: db}
+
+	hourAgo := time.Now().Add(-time.Hour)
+	minuteAgo := time.Now().Add(-time.Minute)
+	inAnHour := time.Now().Add(time.Hour)
+
+	cases := []struct {
+		name          string
+		published     bool
+		publishAt     *time.Time
+		unpublishAt   *time.Time
+		wantPublished bool
+		wantPending   bool // a scheduled time is still to come
+	}{
+		{"✅ publish time passed", false, &hourAgo, nil, true, false},
+		{"✅ unpublish time passed", true, nil, &hourAgo, false, false},
+		{"✅ both passed, unpublish wins", false, &hourAgo, &minuteAgo, false, false},
+		{"✅ published, unpublish still to come", false, &hourAgo, &inAnHour, true, true},
+		{"✅ nothing due yet", false, &inAnHour, nil, false, true},
+	}
+
+	for i, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			slug := fmt.Sprintf("scheduled-%d", i)
+			id, err := model.CreateAndReturnID(slug, "", slug)
+			if err != nil {
+				t.Fatalf("create gallery failed: %v", err)
+			}
+			if err := model.SetPublished(id, tc.published); err != nil {
+				t.Fatalf("SetPublished failed: %v", err)
+			}
+			if err := model.SetSchedule(id, tc.publishAt, tc.unpublishAt); err != nil {
+				t.Fatalf("SetSchedule failed: %v", err)
+			}
+
+			if _, err := model.ApplySchedule(); err != nil {
+				t.Fatalf("ApplySchedule failed: %v", err)
+			}
+
+			g, err := model.GetByID(id)
+			if err != nil {
+				t.Fatalf("GetByID failed: %v", err)
+			}
+			if g.Published != tc.wantPublished {
+				t.Errorf("Expected published=%v, got %v", tc.wantPublished, g.Published)
+			}
+			pending := g.PublishAt != nil || g.UnpublishAt != nil
+			if pending != tc.wantPending {
+				t.Errorf("Expected pending schedule=%v, got publish_at=%v unpublish_at=%v", tc.wantPending, g.PublishAt, g.UnpublishAt)
+			}
+		})
+	}
+
+	id, _ := model.CreateAndReturnID("Backwards", "", "backwards")
+	if err := model.SetSchedule(id, &inAnHour, &hourAgo); !errors.Is(err, ErrScheduleOrder) {
+		t.Errorf("Expected ErrScheduleOrder, got %v", err)
+	}
+	if err := model.SetSchedule(9999, &inAnHour, nil); err == nil {
+		t.Error("Expected error for invalid ID, got nil")
+	}
+}
+
+// TESTING TRASH FUNCTIONS
+func TestGalleryModel_TrashAndPurgeBefore(t *testing.T) {
+	db := setupTestDB(t)
+	model := &GalleryModel{DB: db}
+
+	id, err := model.CreateAndReturnID("Old Gallery", "", "old-gallery")
+	if err != nil {
+		t.Fatalf("create gallery failed: %v", err)
+	}
+	if err := model.Delete(id); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := model.GetByID(id); err == nil {
+		t.Error("Expected trashed gallery to be hidden")
+	}
+
+	// Nothing was trashed before an hour ago, so nothing goes
+	n, err := model.PurgeTrashedBefore(time.Now().Add(-time.Hour))
+	if err != nil || n != 0 {
+		t.Fatalf("PurgeTrashedBefore(past) = %d, %v; want 0", n, err)
+	}
+	n, err = model.PurgeTrashedBefore(time.Now().Add(time.Minute))
+	if err != nil || n != 1 {
+		t.Fatalf("PurgeTrashedBefore(now) = %d, %v; want 1", n, err)
+	}
+	if err := model.Restore(id); err == nil {
+		t.Error("Expected purged gallery to be gone for good")
+	}
+}
+
+// TESTING DUPLICATE FUNCTION
+func TestGalleryModel_Duplicate(t *testing.T) {
+	db := setupTestDB(t)
+	model := &GalleryModel{DB: db}
+	media := &MediaModel{DB: db}
+
+	srcID, err := model.CreateAndReturnID("Portraits", "Studio work", "portraits")
+	if err != nil {
+		t.Fatalf("create gallery failed: %v", err)
+	}
+	first, _ := media.InsertAndReturnID("a.jpg", "full_a.jpg", "thumb_a.jpg")
+	second, _ := media.InsertAndReturnID("b.jpg", "full_b.jpg", "thumb_b.jpg")
+	trashed, _ := media.InsertAndReturnID("c.jpg", "full_c.jpg", "thumb_c.jpg")
+	model.Media().Append(srcID, second, first, trashed)
+	media.Delete(trashed)
+	model.SetCoverImage(srcID, first)
+	model.SetPublished(srcID, true)
+	model.SetSelectionLimit(srcID, 5)
+	categories := &CategoryModel{DB: db}
+	categories.Create("Studio", "studio", "")
+	categories.Create("People", "people", "")
+	studio, _ := categories.GetBySlug("studio")
+	people, _ := categories.GetBySlug("people")
+	categories.SetGalleryCategories(srcID, []int{studio.ID, people.ID})
+
+	cases := []struct {
+		name     string
+		id       int
+		wantSlug string
+		wantErr  bool
+	}{
+		{"✅ first copy", srcID, "portraits-copy", false},
+		{"✅ second copy gets a suffix", srcID, "portraits-copy-2", false},
+		{"❌ invalid ID", 9999, "", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			newID, err := model.Duplicate(tc.id)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Duplicate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+
+			g, err := model.GetByID(newID)
+			if err != nil {
+				t.Fatalf("GetByID failed: %v", err)
+			}
+			if g.Slug != tc.wantSlug || g.Title != "Portraits (copy)" || g.Description != "Studio work" {
+				t.Errorf("Unexpected copy: slug=%q title=%q description=%q", g.Slug, g.Title, g.Description)
+			}
+			if g.Published {
+				t.Error("Expected the copy to be an unpublished draft")
+			}
+			if g.CoverImageID == nil || *g.CoverImageID != first || g.SelectionLimit != 5 {
+				t.Errorf("Expected cover %d and selection limit 5, got %v and %d", first, g.CoverImageID, g.SelectionLimit)
+			}
+
+			items, _ := model.GetMediaPaginated(newID, 10, 0)
+			if len(items) != 2 || items[0].ID != second || items[1].ID != first {
+				t.Errorf("Expected media [%d %d] in order, got %+v", second, first, items)
+			}
+
+			copied, _ := categories.GetForGallery(newID)
+			if len(copied) != 2 {
+				t.Errorf("Expected the copy in 2 categories, got %d", len(copied))
+			}
+		})
+	}
+
+	// Restoring the trashed media doesn't add it to the copies
+	media.Restore(trashed)
+	copyGallery, _ := model.GetBySlug("portraits-copy")
+	if count, _ := model.GetMediaCount(copyGallery.ID); count != 2 {
+		t.Errorf("Expected 2 media in the copy, got %d", count)
+	}
+
+	// A trashed cover stays behind too
+	media.Delete(first)
+	newID, err := model.Duplicate(srcID)
+	if err != nil {
+		t.Fatalf("Duplicate failed: %v", err)
+	}
+	if g, _ := model.GetByID(newID); g == nil || g.CoverImageID != nil {
+		t.Errorf("Expected no cover on a copy whose cover was trashed, got %+v", g)
+	}
+}
+
+func TestGalleryModel_SetParent(t *testing.T) {
+	db := setupTestDB(t)
+	model := &GalleryModel{DB: db}
+
+	wedding, _ := model.CreateAndReturnID("Wedding", "", "wedding")
+	reception, _ := model.CreateAndReturnID("Reception", "", "reception")
+	speeches, _ := model.CreateAndReturnID("Speeches", "", "speeches")
+	portraits, _ := model.CreateAndReturnID("Portraits", "", "portraits")
+
+	ptr := func(id int) *int { return &id }
+
+	cases := []struct {
+		name    string
+		id      int
+		parent  *int
+		wantErr error
+		anyErr  bool
+	}{
+		{"✅ nest reception in wedding", reception, ptr(wedding), nil, false},
+		{"✅ nest speeches in reception", speeches, ptr(reception), nil, false},
+		{"❌ gallery inside itself", wedding, ptr(wedding), ErrGalleryCycle, true},
+		{"❌ gallery inside its grandchild", wedding, ptr(speeches), ErrGalleryCycle, true},
+		{"❌ missing parent", portraits, ptr(9999), nil, true},
+		{"✅ move back to top level", portraits, nil, nil, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := model.SetParent(tc.id, tc.parent)
+			if (err != nil) != tc.anyErr {
+				t.Fatalf("SetParent() error = %v, wantErr %v", err, tc.anyErr)
+			}
+			if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+				t.Errorf("Expected %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+
+	ancestors, err := model.GetAncestors(speeches)
+	if err != nil {
+		t.Fatalf("GetAncestors failed: %v", err)
+	}
+	if len(ancestors) != 2 || ancestors[0].ID != wedding || ancestors[1].ID != reception {
+		t.Errorf("Expected ancestors [%d %d], got %+v", wedding, reception, ancestors)
+	}
+
+	// Only published sub-galleries are listed on the collection
+	if children, _ := model.GetChildren(wedding); len(children) != 0 {
+		t.Errorf("Expected no published children yet, got %d", len(children))
+	}
+	model.SetPublished(reception, true)
+	if children, _ := model.GetChildren(wedding); len(children) != 1 || children[0].ID != reception {
+		t.Errorf("Expected reception as the only child, got %+v", children)
+	}
+
+	// A gallery's own subtree is never offered as a parent
+	options, _ := model.GetParentOptions(wedding)
+	for _, o := range options {
+		if o.ID == wedding || o.ID == reception || o.ID == speeches {
+			t.Errorf("Unexpected parent option %d for wedding", o.ID)
+		}
+	}
+}
+
+func TestGalleryModel_UpdatePositions(t *testing.T) {
+	db := setupTestDB(t)
+	model := &GalleryModel{DB: db}
+
+	first, _ := model.CreateAndReturnID("First", "", "order-first")
+	second, _ := model.CreateAndReturnID("Second", "", "order-second")
+	third, _ := model.CreateAndReturnID("Third", "", "order-third")
+	for _, id := range []int{first, second, third} {
+		model.SetPublished(id, true)
+	}
+
+	order := func() []int {
+		galleries, err := model.GetAllPublic()
+		if err != nil {
+			t.Fatalf("GetAllPublic failed: %v", err)
+		}
+		var ids []int
+		for _, g := range galleries {
+			if id := g["ID"].(int); id == first || id == second || id == third {
+				ids = append(ids, id)
+			}
+		}
+		return ids
+	}
+
+	if got := order(); fmt.Sprint(got) != fmt.Sprint([]int{first, second, third}) {
+		t.Errorf("Expected new galleries in creation order, got %v", got)
+	}
+
+	if err := model.UpdatePositions([]int{third, first, second}); err != nil {
+		t.Fatalf("UpdatePositions failed: %v", err)
+	}
+	if got := order(); fmt.Sprint(got) != fmt.Sprint([]int{third, first, second}) {
+		t.Errorf("Expected the saved order, got %v", got)
+	}
+
+	// A new gallery goes to the end of a rearranged list
+	fourth, _ := model.CreateAndReturnID("Fourth", "", "order-fourth")
+	model.SetPublished(fourth, true)
+	galleries, _ := model.GetAllPublic()
+	if last := galleries[len(galleries)-1]["ID"].(int); last != fourth {
+		t.Errorf("Expected the new gallery last, got %d", last)
+	}
+
+	// Galleries a stale list leaves out follow the ones it orders
+	if err := model.UpdatePositions([]int{fourth, first}); err != nil {
+		t.Fatalf("UpdatePositions failed: %v", err)
+	}
+	galleries, _ = model.GetAllPublic()
+	var got []int
+	for _, g := range galleries {
+		got = append(got, g["ID"].(int))
+	}
+	if want := []int{fourth, first, third, second}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	var positions string
+	db.QueryRow(context.Background(),
+		`SELECT string_agg(position::text, ',' ORDER BY position) FROM galleries`).Scan(&positions)
+	if positions != "0,1,2,3" {
+		t.Errorf("Expected dense positions 0-3, got %s", positions)
+	}
+}
+
+func TestGalleryModel_GetPublished(t *testing.T) {
+	db := setupTestDB(t)
+	model := &GalleryModel{DB: db}
+
+	public, _ := model.CreateAndReturnID("Public", "", "published-public")
+	model.SetPublished(public, true)
+	draft, _ := model.CreateAndReturnID("Draft", "", "published-draft")
+	client, _ := model.CreateAndReturnID("Client", "", "published-client")
+	model.SetClientPassword(client, "sunflower")
+
+	// A sub-gallery is still a public gallery in its own right
+	collection, _ := model.CreateAndReturnID("Collection", "", "published-collection")
+	model.SetPublished(collection, true)
+	child, _ := model.CreateAndReturnID("Child", "", "published-child")
+	model.SetPublished(child, true)
+	if err := model.SetParent(child, &collection); err != nil {
+		t.Fatalf("SetParent failed: %v", err)
+	}
+
+	galleries, err := model.GetPublished()
+	if err != nil {
+		t.Fatalf("GetPublished failed: %v", err)
+	}
+	listed := map[int]bool{}
+	for _, g := range galleries {
+		listed[g["ID"].(int)] = true
+	}
+
+	cases := []struct {
+		name string
+		id   int
+		want bool
+	}{
+		{"✅ published", public, true},
+		{"✅ sub-gallery", child, true},
+		{"❌ draft", draft, false},
+		{"❌ client gallery", client, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if listed[tc.id] != tc.want {
+				t.Errorf("Expected listed %v, got %v", tc.want, listed[tc.id])
+			}
+			g, err := model.GetByID(tc.id)
+			if err != nil {
+				t.Fatalf("GetByID failed: %v", err)
+			}
+			if g.IsPublic() != tc.want {
+				t.Errorf("Expected IsPublic %v, got %v", tc.want, g.IsPublic())
+			}
+		})
+	}
+}
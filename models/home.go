@@ -0,0 +1,147 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// The home page is a list of sections stored as JSON in the settings table.
+// Each section has a type and the few fields that type uses.
+
+const homeSectionsKey = "home_sections"
+
+// Home section types
+const (
+	SectionHero           = "hero"
+	SectionFeatured       = "featured"
+	SectionLatestProjects = "latest_projects"
+	SectionTestimonial    = "testimonial"
+	SectionCTA            = "cta"
+)
+
+// HomeSectionTypes lists the section types in the order the admin offers them
+var HomeSectionTypes = []string{SectionHero, SectionFeatured, SectionLatestProjects, SectionTestimonial, SectionCTA}
+
+// ErrUnknownSection is returned for a home section of a type we can't render
+var ErrUnknownSection = errors.New("unknown home page section")
+
+// maxSectionLimit caps how many items a list section shows
+const maxSectionLimit = 12
+
+type HomeSection struct {
+	Type string `json:"type"`
+	// GalleryID picks the hero gallery; 0 uses the featured gallery, or the
+	// gallery titled "Home" when none is featured
+	GalleryID   int    `json:"gallery_id,omitempty"`
+	Heading     string `json:"heading,omitempty"`
+	Text        string `json:"text,omitempty"`
+	Limit       int    `json:"limit,omitempty"`
+	Quote       string `json:"quote,omitempty"`
+	Author      string `json:"author,omitempty"`
+	ButtonLabel string `json:"button_label,omitempty"`
+	ButtonURL   string `json:"button_url,omitempty"`
+}
+
+// DefaultHomeSections is the layout used until one is saved: the featured
+// gallery, or else the "Home" gallery, as the hero, then the latest projects
+func DefaultHomeSections() []HomeSection {
+	return []HomeSection{
+		{Type: SectionHero},
+		{Type: SectionLatestProjects, Heading: "Latest Projects", Limit: 3},
+	}
+}
+
+// NewHomeSection returns a section of type kind with sensible starting values
+func NewHomeSection(kind string) (HomeSection, error) {
+	s := HomeSection{Type: kind}
+	switch kind {
+	case SectionFeatured:
+		s.Heading = "Featured Galleries"
+		s.Limit = 3
+	case SectionLatestProjects:
+		s.Heading = "Latest Projects"
+		s.Limit = 3
+	case SectionCTA:
+		s.Heading = "Let's work together"
+		s.ButtonLabel = "Get in touch"
+		s.ButtonURL = "/contact"
+	}
+	return s, s.Validate()
+}
+
+// Label is the admin-facing name of the section type
+func (s HomeSection) Label() string {
+	switch s.Type {
+	case SectionHero:
+		return "Hero slideshow"
+	case SectionFeatured:
+		return "Featured galleries"
+	case SectionLatestProjects:
+		return "Latest projects"
+	case SectionTestimonial:
+		return "Testimonial"
+	case SectionCTA:
+		return "Call to action"
+	}
+	return s.Type
+}
+
+// Validate checks the section type and the fields that type relies on
+func (s HomeSection) Validate() error {
+	switch s.Type {
+	case SectionHero, SectionFeatured, SectionLatestProjects, SectionTestimonial:
+	case SectionCTA:
+		// Links stay on this site or go out over http(s)
+		if s.ButtonURL != "" && !strings.HasPrefix(s.ButtonURL, "/") &&
+			!strings.HasPrefix(s.ButtonURL, "https://") && !strings.HasPrefix(s.ButtonURL, "http://") {
+			return fmt.Errorf("button link must start with /, http:// or https://")
+		}
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownSection, s.Type)
+	}
+
+	if s.Limit < 0 || s.Limit > maxSectionLimit {
+		return fmt.Errorf("limit must be between 0 and %d", maxSectionLimit)
+	}
+	return nil
+}
+
+// GetHomeSections returns the saved home page layout, or the default layout
+// if none has been saved
+func (s *SettingsModel) GetHomeSections() ([]HomeSection, error) {
+	value, err := s.Get(homeSectionsKey)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return DefaultHomeSections(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var sections []HomeSection
+	if err := json.Unmarshal([]byte(value), &sections); err != nil {
+		return nil, fmt.Errorf("invalid %s setting: %w", homeSectionsKey, err)
+	}
+	return sections, nil
+}
+
+// SetHomeSections saves the home page layout in order
+func (s *SettingsModel) SetHomeSections(sections []HomeSection) error {
+	for _, section := range sections {
+		if err := section.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if sections == nil {
+		sections = []HomeSection{}
+	}
+	value, err := json.Marshal(sections)
+	if err != nil {
+		return err
+	}
+	return s.Set(homeSectionsKey, string(value))
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSettingsModel_HomeSections(t *testing.T) {
+	db := setupTestDB(t)
+	model := &SettingsModel{DB: db}
+
+	// Nothing saved yet gives the default layout
+	sections, err := model.GetHomeSections()
+	if err != nil {
+		t.Fatalf("GetHomeSections failed: %v", err)
+	}
+	if len(sections) != len(DefaultHomeSections()) || sections[0].Type != SectionHero {
+		t.Errorf("Expected the default layout, got %+v", sections)
+	}
+
+	cases := []struct {
+		name     string
+		sections []HomeSection
+		wantErr  error
+		anyErr   bool
+	}{
+		{"✅ ordered sections", []HomeSection{
+			{Type: SectionCTA, Heading: "Book a shoot", ButtonLabel: "Contact", ButtonURL: "/contact"},
+			{Type: SectionHero, GalleryID: 4},
+			{Type: SectionTestimonial, Quote: "Wonderful", Author: "Sam"},
+		}, nil, false},
+		{"✅ empty page", []HomeSection{}, nil, false},
+		{"❌ unknown type", []HomeSection{{Type: "carousel"}}, ErrUnknownSection, true},
+		{"❌ script link", []HomeSection{{Type: SectionCTA, ButtonURL: "javascript:alert(1)"}}, nil, true},
+		{"❌ limit too high", []HomeSection{{Type: SectionFeatured, Limit: 500}}, nil, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := model.SetHomeSections(tc.sections)
+			if (err != nil) != tc.anyErr {
+				t.Fatalf("SetHomeSections() error = %v, wantErr %v", err, tc.anyErr)
+			}
+			if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+				t.Errorf("Expected %v, got %v", tc.wantErr, err)
+			}
+			if tc.anyErr {
+				return
+			}
+
+			got, err := model.GetHomeSections()
+			if err != nil {
+				t.Fatalf("GetHomeSections failed: %v", err)
+			}
+			if len(got) != len(tc.sections) {
+				t.Fatalf("Expected %d sections, got %d", len(tc.sections), len(got))
+			}
+			for i := range got {
+				if got[i] != tc.sections[i] {
+					t.Errorf("Section %d: expected %+v, got %+v", i, tc.sections[i], got[i])
+				}
+			}
+		})
+	}
+}
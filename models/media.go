@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -16,6 +17,23 @@ type Media struct {
 	MimeType     *string
 	EmbedURL     *string
 	Position     int
+	OriginalKey  string // S3 key of the private original; empty for legacy uploads
+
+	// Low-quality placeholder shown while the image loads
+	BlurHash      string
+	DominantColor string
+}
+
+// MediaVersion is a previous file of a media item, kept so a replacement
+// can be rolled back
+type MediaVersion struct {
+	ID           int
+	MediaID      int
+	FileName     string
+	FullURL      string
+	ThumbnailURL string
+	OriginalKey  string
+	CreatedAt    time.Time
 }
 
 type MediaModel struct {
@@ -33,54 +51,128 @@ func (m *MediaModel) InsertAndReturnID(fileName, fullURL, thumbURL string) (int,
 	return id, err
 }
 
-func (m *MediaModel) UploadAndLinkProjectMedia(fileName, url, thumbURL string, projectID, position int) (int, error) {
-	var mediaID int
+// InsertWithOriginal stores a media row whose public URLs point at derived
+// renditions, keeping a reference to the private original in S3.
+func (m *MediaModel) InsertWithOriginal(fileName, fullURL, thumbURL, originalKey string) (int, error) {
+	var id int
 	err := m.DB.QueryRow(context.Background(),
-		`INSERT INTO media (file_name, full_url, thumbnail_url, position, project_id)
-		 VALUES ($1, $2, $3, $4, $5)
-		 RETURNING id`,
-		fileName, url, thumbURL, position, projectID).Scan(&mediaID)
-	if err != nil {
-		return 0, err
-	}
+		`INSERT INTO media (file_name, full_url, thumbnail_url, original_key)
+		 VALUES ($1, $2, $3, $4) RETURNING id`,
+		fileName, fullURL, thumbURL, originalKey).Scan(&id)
+	return id, err
+}
 
-	_, err = m.DB.Exec(context.Background(),
-		`INSERT INTO project_media (project_id, media_id)
-		 VALUES ($1, $2) ON CONFLICT DO NOTHING`,
-		projectID, mediaID)
+// Insert stores a processed upload with its placeholder, setting m.ID
+func (m *MediaModel) Insert(media *Media) error {
+	return insertMedia(context.Background(), m.DB, media)
+}
 
-	return mediaID, err
+func insertMedia(ctx context.Context, q querier, m *Media) error {
+	return q.QueryRow(ctx, `
+		INSERT INTO media (file_name, full_url, thumbnail_url, original_key, blurhash, dominant_color)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, '')) RETURNING id`,
+		m.FileName, m.FullURL, m.ThumbnailURL, m.OriginalKey, m.BlurHash, m.DominantColor).Scan(&m.ID)
 }
 
-func (m *MediaModel) InsertProjectMedia(projectID, mediaID int) error {
-	query := `
-		INSERT INTO project_media (project_id, media_id, position)
-		VALUES ($1, $2, (
-			SELECT COALESCE(MAX(position), 0) + 1 FROM project_media WHERE project_id = $1
-		))
-		ON CONFLICT DO NOTHING;
-	`
-	_, err := m.DB.Exec(context.Background(), query, projectID, mediaID)
+// SetOriginalKey records where the private original of a media item is stored
+func (m *MediaModel) SetOriginalKey(id int, key string) error {
+	_, err := m.DB.Exec(context.Background(),
+		`UPDATE media SET original_key = $1 WHERE id = $2`, key, id)
 	return err
 }
 
-func (m *MediaModel) InsertGalleryMedia(galleryID, mediaID int) error {
-	query := `
-		INSERT INTO gallery_media (gallery_id, media_id, position)
-		VALUES ($1, $2, (
-			SELECT COALESCE(MAX(position), 0) + 1 FROM gallery_media WHERE gallery_id = $1
-		))
-		ON CONFLICT DO NOTHING;
-	`
-	_, err := m.DB.Exec(context.Background(), query, galleryID, mediaID)
+// SetPlaceholder stores the BlurHash and dominant colour of a media item
+func (m *MediaModel) SetPlaceholder(id int, blurHash, dominantColor string) error {
+	_, err := m.DB.Exec(context.Background(),
+		`UPDATE media SET blurhash = $1, dominant_color = $2 WHERE id = $3`,
+		blurHash, dominantColor, id)
 	return err
 }
 
+// GetMissingPlaceholders returns image media that has no placeholder yet
+func (m *MediaModel) GetMissingPlaceholders(limit int) ([]*Media, error) {
+	rows, err := m.DB.Query(context.Background(), `
+		SELECT id, file_name, full_url, thumbnail_url
+		FROM media
+		WHERE blurhash IS NULL
+		  AND deleted_at IS NULL
+		  AND embed_url IS NULL
+		  AND COALESCE(mime_type, '') NOT LIKE 'video/%'
+		ORDER BY id ASC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var media []*Media
+	for rows.Next() {
+		m := &Media{}
+		if err := rows.Scan(&m.ID, &m.FileName, &m.FullURL, &m.ThumbnailURL); err != nil {
+			return nil, err
+		}
+		media = append(media, m)
+	}
+	return media, nil
+}
+
+// GetWithoutOriginal returns image media whose original is still the public
+// upload. Trashed media is included: its files stay public until purged.
+func (m *MediaModel) GetWithoutOriginal(limit int) ([]*Media, error) {
+	rows, err := m.DB.Query(context.Background(), `
+		SELECT id, file_name
+		FROM media
+		WHERE COALESCE(original_key, '') = ''
+		  AND embed_url IS NULL
+		  AND COALESCE(mime_type, '') NOT LIKE 'video/%'
+		ORDER BY id ASC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var media []*Media
+	for rows.Next() {
+		m := &Media{}
+		if err := rows.Scan(&m.ID, &m.FileName); err != nil {
+			return nil, err
+		}
+		media = append(media, m)
+	}
+	return media, rows.Err()
+}
+
+// WatermarkOptOut reports whether media should be published without a
+// watermark: only when every gallery it's in has opted out and no project
+// shows it.
+func (m *MediaModel) WatermarkOptOut(mediaID int) (bool, error) {
+	var optOut bool
+	err := m.DB.QueryRow(context.Background(), `
+		SELECT EXISTS (
+		         SELECT 1 FROM gallery_media WHERE media_id = $1
+		       )
+		   AND NOT EXISTS (
+		         SELECT 1
+		         FROM gallery_media gm
+		         JOIN galleries g ON g.id = gm.gallery_id
+		         WHERE gm.media_id = $1 AND NOT COALESCE(g.watermark_opt_out, FALSE)
+		       )
+		   AND NOT EXISTS (
+		         SELECT 1 FROM project_media WHERE media_id = $1
+		       )
+	`, mediaID).Scan(&optOut)
+	return optOut, err
+}
+
 // --- Get methods ---
 func (m *MediaModel) GetAll() ([]*Media, error) {
 	rows, err := m.DB.Query(context.Background(), `
 		SELECT id, file_name, full_url, thumbnail_url, COALESCE(embed_url, '')
 		FROM media
+		WHERE deleted_at IS NULL
 		ORDER BY id DESC
 	`)
 	if err != nil {
@@ -109,10 +201,11 @@ func (m *MediaModel) GetAll() ([]*Media, error) {
 
 func (m *MediaModel) GetByGalleryID(galleryID int) ([]*Media, error) {
 	rows, err := m.DB.Query(context.Background(),
-		`SELECT m.id, m.file_name, m.full_url, m.thumbnail_url, m.embed_url, m.mime_type, gm.position
+		`SELECT m.id, m.file_name, m.full_url, m.thumbnail_url, m.embed_url, m.mime_type, gm.position,
+		        COALESCE(m.original_key, '')
 		 FROM gallery_media gm
 		 JOIN media m ON gm.media_id = m.id
-		 WHERE gm.gallery_id = $1
+		 WHERE gm.gallery_id = $1 AND m.deleted_at IS NULL
 		 ORDER BY gm.position ASC`, galleryID)
 	if err != nil {
 		return nil, err
@@ -122,7 +215,7 @@ func (m *MediaModel) GetByGalleryID(galleryID int) ([]*Media, error) {
 	var media []*Media
 	for rows.Next() {
 		var m Media
-		err := rows.Scan(&m.ID, &m.FileName, &m.FullURL, &m.ThumbnailURL, &m.EmbedURL, &m.MimeType, &m.Position)
+		err := rows.Scan(&m.ID, &m.FileName, &m.FullURL, &m.ThumbnailURL, &m.EmbedURL, &m.MimeType, &m.Position, &m.OriginalKey)
 		if err != nil {
 			return nil, err
 		}
@@ -132,11 +225,14 @@ func (m *MediaModel) GetByGalleryID(galleryID int) ([]*Media, error) {
 }
 
 func (m *MediaModel) GetByID(id int) (*Media, error) {
-	query := `SELECT id, file_name, full_url, thumbnail_url, mime_type, embed_url FROM media WHERE id = $1`
+	query := `SELECT id, file_name, full_url, thumbnail_url, mime_type, embed_url, COALESCE(original_key, ''),
+	                 COALESCE(blurhash, ''), COALESCE(dominant_color, '')
+	          FROM media WHERE id = $1 AND deleted_at IS NULL`
 	row := m.DB.QueryRow(context.Background(), query, id)
 
 	var media Media
-	err := row.Scan(&media.ID, &media.FileName, &media.FullURL, &media.ThumbnailURL, &media.MimeType, &media.EmbedURL)
+	err := row.Scan(&media.ID, &media.FileName, &media.FullURL, &media.ThumbnailURL, &media.MimeType, &media.EmbedURL, &media.OriginalKey,
+		&media.BlurHash, &media.DominantColor)
 	if err != nil {
 		return nil, err
 	}
@@ -149,7 +245,7 @@ func (m *MediaModel) GetByIDAndGallery(id, galleryID int) (*Media, error) {
 		`SELECT m.id, m.file_name, m.full_url, m.thumbnail_url, m.embed_url, m.mime_type, gm.position
 		 FROM gallery_media gm
 		 JOIN media m ON gm.media_id = m.id
-		 WHERE m.id = $1 AND gm.gallery_id = $2`,
+		 WHERE m.id = $1 AND gm.gallery_id = $2 AND m.deleted_at IS NULL`,
 		id, galleryID).Scan(
 		&media.ID, &media.FileName, &media.FullURL, &media.ThumbnailURL, &media.EmbedURL, &media.MimeType, &media.Position,
 	)
@@ -159,167 +255,103 @@ func (m *MediaModel) GetByIDAndGallery(id, galleryID int) (*Media, error) {
 	return &media, nil
 }
 
-// --- Position helpers ---
-func (m *MediaModel) GetNextPosition(galleryID int) (int, error) {
-	var pos int
+// --- Misc ---
+func (m *MediaModel) MediaExists(mediaID, galleryID int) (bool, error) {
+	var exists bool
 	err := m.DB.QueryRow(context.Background(),
-		`SELECT COALESCE(MAX(position), -1) + 1
-		 FROM gallery_media
-		 WHERE gallery_id = $1`, galleryID).Scan(&pos)
-	return pos, err
+		`SELECT EXISTS (
+			SELECT 1 FROM gallery_media
+			WHERE media_id = $1 AND gallery_id = $2
+		)`, mediaID, galleryID).Scan(&exists)
+	return exists, err
 }
 
-func (m *MediaModel) GetNextProjectPosition(projectID int) (int, error) {
-	var pos int
-	err := m.DB.QueryRow(context.Background(), `
-		SELECT COALESCE(MAX(position), 0) + 1 FROM project_media WHERE project_id=$1
-	`, projectID).Scan(&pos)
-	return pos, err
+// Delete moves a media item to the trash. It drops out of its galleries and
+// projects until restored; the files stay in S3 until it is purged.
+func (m *MediaModel) Delete(id int) error {
+	return trashRow(m.DB, "media", id)
 }
 
-func (m *MediaModel) ReorderPositions(galleryID, mediaID, newPosition int) error {
-	tx, err := m.DB.Begin(context.Background())
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback(context.Background())
-
-	var current int
-	err = tx.QueryRow(context.Background(),
-		`SELECT position FROM gallery_media
-		 WHERE gallery_id = $1 AND media_id = $2`,
-		galleryID, mediaID).Scan(&current)
-	if err != nil {
-		return err
-	}
+// Restore takes a media item out of the trash, back into the galleries and
+// projects it was in, at the same positions
+func (m *MediaModel) Restore(id int) error {
+	return restoreRow(m.DB, "media", id)
+}
 
-	if current == newPosition {
-		return nil
-	}
+// GetTrashed lists trashed media, most recently deleted first
+func (m *MediaModel) GetTrashed() ([]*TrashedItem, error) {
+	return trashedRows(m.DB, `
+		SELECT id, file_name, thumbnail_url, deleted_at
+		FROM media
+		WHERE deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC`)
+}
 
-	if current < newPosition {
-		_, err = tx.Exec(context.Background(),
-			`UPDATE gallery_media
-			 SET position = position - 1
-			 WHERE gallery_id = $1 AND position > $2 AND position <= $3`,
-			galleryID, current, newPosition)
-	} else {
-		_, err = tx.Exec(context.Background(),
-			`UPDATE gallery_media
-			 SET position = position + 1
-			 WHERE gallery_id = $1 AND position >= $3 AND position < $2`,
-			galleryID, current, newPosition)
-	}
+// Purge permanently deletes a trashed media item and returns the files that
+// backed it, versions included, for the caller to remove from S3
+func (m *MediaModel) Purge(id int) ([]StoredFile, error) {
+	files, n, err := m.purge(`id = $1 AND deleted_at IS NOT NULL`, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	_, err = tx.Exec(context.Background(),
-		`UPDATE gallery_media
-		 SET position = $1
-		 WHERE gallery_id = $2 AND media_id = $3`,
-		newPosition, galleryID, mediaID)
-	if err != nil {
-		return err
+	if n == 0 {
+		return nil, fmt.Errorf("no trashed media found with ID %d", id)
 	}
+	return files, nil
+}
 
-	return tx.Commit(context.Background())
+// PurgeTrashedBefore permanently deletes media trashed before cutoff and
+// returns the files to remove from S3
+func (m *MediaModel) PurgeTrashedBefore(cutoff time.Time) ([]StoredFile, error) {
+	files, _, err := m.purge(`deleted_at < $1`, cutoff)
+	return files, err
 }
 
-func (m *MediaModel) UpdatePositionsForProject(projectID int, mediaIDs []int) error {
+// purge deletes the media rows matching where, collecting their files first.
+// Versions go with the row through the cascade.
+func (m *MediaModel) purge(where string, arg any) ([]StoredFile, int64, error) {
 	ctx := context.Background()
 	tx, err := m.DB.Begin(ctx)
 	if err != nil {
-		return err
+		return nil, 0, err
 	}
 	defer tx.Rollback(ctx)
 
-	for position, mediaID := range mediaIDs {
-		_, err := tx.Exec(ctx,
-			`UPDATE media SET position = $1
-			 WHERE id = $2 AND project_id = $3`,
-			position, mediaID, projectID)
-		if err != nil {
-			return err
-		}
-	}
-
-	return tx.Commit(ctx)
-}
-
-func (m *MediaModel) UpdatePositionsForGallery(galleryID int, mediaIDs []int) error {
-	tx, err := m.DB.Begin(context.Background())
+	rows, err := tx.Query(ctx, `
+		SELECT file_name, COALESCE(original_key, '') FROM media WHERE `+where+`
+		UNION ALL
+		SELECT v.file_name, COALESCE(v.original_key, '')
+		FROM media_versions v
+		WHERE v.media_id IN (SELECT id FROM media WHERE `+where+`)`, arg)
 	if err != nil {
-		return err
+		return nil, 0, err
 	}
-	defer tx.Rollback(context.Background())
-
-	for i, mediaID := range mediaIDs {
-		_, err := tx.Exec(context.Background(), `
-			UPDATE gallery_media
-			SET position = $1
-			WHERE gallery_id = $2 AND media_id = $3
-		`, i, galleryID, mediaID)
-		if err != nil {
-			return err
+	var files []StoredFile
+	for rows.Next() {
+		var f StoredFile
+		if err := rows.Scan(&f.FileName, &f.OriginalKey); err != nil {
+			rows.Close()
+			return nil, 0, err
 		}
+		files = append(files, f)
 	}
-
-	return tx.Commit(context.Background())
-}
-
-func (m *MediaModel) UpdatePositionsInBulk(galleryID int, mediaIDs []int) error {
-	ctx := context.Background()
-	tx, err := m.DB.Begin(ctx)
-	if err != nil {
-		return err
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
 	}
-	defer tx.Rollback(ctx)
 
-	for position, mediaID := range mediaIDs {
-		_, err := tx.Exec(ctx,
-			`UPDATE gallery_media SET position = $1
-			 WHERE gallery_id = $2 AND media_id = $3`,
-			position, galleryID, mediaID)
-		if err != nil {
-			return err
-		}
+	res, err := tx.Exec(ctx, `DELETE FROM media WHERE `+where, arg)
+	if err != nil {
+		return nil, 0, err
 	}
-
-	return tx.Commit(ctx)
-}
-
-// --- Misc ---
-func (m *MediaModel) MediaExists(mediaID, galleryID int) (bool, error) {
-	var exists bool
-	err := m.DB.QueryRow(context.Background(),
-		`SELECT EXISTS (
-			SELECT 1 FROM gallery_media
-			WHERE media_id = $1 AND gallery_id = $2
-		)`, mediaID, galleryID).Scan(&exists)
-	return exists, err
-}
-
-func (m *MediaModel) Delete(id int) error {
-	_, err := m.DB.Exec(context.Background(), `DELETE FROM media WHERE id = $1`, id)
-	return err
-}
-
-func (m *MediaModel) AttachToProject(projectID, mediaID, position int) error {
-	_, err := m.DB.Exec(context.Background(), `
-		INSERT INTO project_media (project_id, media_id, position)
-		VALUES ($1, $2, $3)
-		ON CONFLICT (project_id, media_id) DO UPDATE
-		SET position = EXCLUDED.position
-	`, projectID, mediaID, position)
-	return err
+	return files, res.RowsAffected(), tx.Commit(ctx)
 }
 
 func (m *MediaModel) GetUnlinkedMedia(joinTable, foreignKey string, id int) ([]*Media, error) {
 	query := fmt.Sprintf(`
 		SELECT id, file_name, full_url, thumbnail_url, COALESCE(mime_type, ''), COALESCE(embed_url, '')
 		FROM media
-		WHERE id NOT IN (
+		WHERE deleted_at IS NULL AND id NOT IN (
 			SELECT media_id FROM %s WHERE %s = $1
 		)
 		ORDER BY id DESC
@@ -359,27 +391,13 @@ func (m *MediaModel) GetUnlinkedMedia(joinTable, foreignKey string, id int) ([]*
 	return media, nil
 }
 
-func (m *MediaModel) UnlinkMediaFromGallery(galleryID, mediaID int) error {
-	_, err := m.DB.Exec(context.Background(),
-		`DELETE FROM gallery_media WHERE gallery_id = $1 AND media_id = $2`,
-		galleryID, mediaID)
-	return err
-}
-
-func (m *MediaModel) UnlinkMediaFromProject(projectID, mediaID int) error {
-	_, err := m.DB.Exec(context.Background(),
-		`DELETE FROM project_media WHERE project_id = $1 AND media_id = $2`,
-		projectID, mediaID)
-	return err
-}
-
 func (m *MediaModel) GetByIDUnsafe(id int) (*Media, error) {
 	query := `
 	SELECT id, file_name, full_url, thumbnail_url,
 	       COALESCE(mime_type, '') AS mime_type,
 	       COALESCE(embed_url, '') AS embed_url
 	FROM media
-	WHERE id = $1`
+	WHERE id = $1 AND deleted_at IS NULL`
 
 	var media Media
 
@@ -396,6 +414,7 @@ func (m *MediaModel) GetPaginated(limit, offset int) ([]*Media, error) {
 	rows, err := m.DB.Query(context.Background(), `
 	SELECT id, file_name, thumbnail_url, full_url, mime_type, embed_url
 	FROM media
+	WHERE deleted_at IS NULL
 	ORDER BY id DESC
 	LIMIT $1 OFFSET $2
 `, limit, offset)
@@ -429,7 +448,7 @@ func (m *MediaModel) GetPaginated(limit, offset int) ([]*Media, error) {
 
 func (m *MediaModel) Count() (int, error) {
 	var count int
-	err := m.DB.QueryRow(context.Background(), `SELECT COUNT(*) FROM media`).Scan(&count)
+	err := m.DB.QueryRow(context.Background(), `SELECT COUNT(*) FROM media WHERE deleted_at IS NULL`).Scan(&count)
 	return count, err
 }
 
@@ -438,6 +457,7 @@ func (m *MediaModel) GetLatest(limit int) ([]*Media, error) {
 	rows, err := m.DB.Query(context.Background(), `
 	SELECT id, file_name, thumbnail_url, full_url, mime_type, embed_url
 	FROM media
+	WHERE deleted_at IS NULL
 	ORDER BY id DESC
 	LIMIT $1
 `, limit)
@@ -473,7 +493,7 @@ func (m *MediaModel) GetUnlinkedMediaPaginated(joinTable, foreignKey string, id,
 	query := fmt.Sprintf(`
 		SELECT id, file_name, full_url, thumbnail_url
 		FROM media
-		WHERE id NOT IN (
+		WHERE deleted_at IS NULL AND id NOT IN (
 			SELECT media_id FROM %s WHERE %s = $1
 		)
 		ORDER BY id DESC
@@ -498,8 +518,112 @@ func (m *MediaModel) GetUnlinkedMediaPaginated(joinTable, foreignKey string, id,
 	countQuery := fmt.Sprintf(`
 		SELECT COUNT(*)
 		FROM media
-		WHERE id NOT IN (SELECT media_id FROM %s WHERE %s = $1)`, joinTable, foreignKey)
+		WHERE deleted_at IS NULL AND id NOT IN (SELECT media_id FROM %s WHERE %s = $1)`, joinTable, foreignKey)
 	err = m.DB.QueryRow(context.Background(), countQuery, id).Scan(&total)
 
 	return media, total, err
 }
+
+// archiveCurrentVersion copies the current file columns of a media item into
+// media_versions
+const archiveCurrentVersion = `
+	INSERT INTO media_versions (media_id, file_name, full_url, thumbnail_url, original_key, blurhash, dominant_color)
+	SELECT id, file_name, full_url, thumbnail_url, original_key, blurhash, dominant_color
+	FROM media WHERE id = $1`
+
+// ReplaceFile swaps the file behind a media item while keeping its ID, so
+// gallery/project links, positions and cover references stay intact. The
+// previous file is archived as a version.
+func (m *MediaModel) ReplaceFile(id int, fileName, fullURL, thumbURL, originalKey, blurHash, dominantColor string) error {
+	ctx := context.Background()
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	res, err := tx.Exec(ctx, archiveCurrentVersion, id)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("no media found with ID %d", id)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE media
+		SET file_name = $1, full_url = $2, thumbnail_url = $3, original_key = $4,
+		    blurhash = NULLIF($5, ''), dominant_color = NULLIF($6, '')
+		WHERE id = $7`,
+		fileName, fullURL, thumbURL, originalKey, blurHash, dominantColor, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetVersions returns the archived files of a media item, newest first
+func (m *MediaModel) GetVersions(mediaID int) ([]*MediaVersion, error) {
+	rows, err := m.DB.Query(context.Background(), `
+		SELECT id, media_id, file_name, full_url, COALESCE(thumbnail_url, ''), COALESCE(original_key, ''), created_at
+		FROM media_versions
+		WHERE media_id = $1
+		ORDER BY created_at DESC, id DESC`, mediaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []*MediaVersion
+	for rows.Next() {
+		v := &MediaVersion{}
+		if err := rows.Scan(&v.ID, &v.MediaID, &v.FileName, &v.FullURL, &v.ThumbnailURL, &v.OriginalKey, &v.CreatedAt); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+// RestoreVersion makes an archived version current again. The file being
+// replaced is archived in turn, so a restore can itself be undone.
+func (m *MediaModel) RestoreVersion(mediaID, versionID int) error {
+	ctx := context.Background()
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var exists bool
+	err = tx.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM media_versions WHERE id = $1 AND media_id = $2)`,
+		versionID, mediaID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("no version %d found for media %d", versionID, mediaID)
+	}
+
+	if _, err := tx.Exec(ctx, archiveCurrentVersion, mediaID); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE media m
+		SET file_name = v.file_name, full_url = v.full_url, thumbnail_url = v.thumbnail_url,
+		    original_key = v.original_key, blurhash = v.blurhash, dominant_color = v.dominant_color
+		FROM media_versions v
+		WHERE v.id = $1 AND m.id = $2`, versionID, mediaID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM media_versions WHERE id = $1`, versionID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
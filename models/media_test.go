@@ -60,3 +60,205 @@ func TestMediaModel_InsertAndFetch(t *testing.T) {
 		})
 	}
 }
+
+func TestMediaModel_InsertWithOriginal(t *testing.T) {
+	db := setupTestDB(t)
+	model := &MediaModel{DB: db}
+
+	id, err := model.InsertWithOriginal("photo.jpg", "https://cdn.com/photo.jpg", "https://cdn.com/thumb_photo.jpg", "Originals/photo.jpg")
+	if err != nil {
+		t.Fatalf("InsertWithOriginal failed: %v", err)
+	}
+
+	media, err := model.GetByID(id)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if media.OriginalKey != "Originals/photo.jpg" {
+		t.Errorf("Expected original key %q, got %q", "Originals/photo.jpg", media.OriginalKey)
+	}
+
+	// Legacy rows have no original until one is recorded
+	legacyID, err := model.InsertAndReturnID("legacy.jpg", "full.jpg", "thumb.jpg")
+	if err != nil {
+		t.Fatalf("InsertAndReturnID failed: %v", err)
+	}
+	if err := model.SetOriginalKey(legacyID, "Originals/legacy.jpg"); err != nil {
+		t.Fatalf("SetOriginalKey failed: %v", err)
+	}
+	legacy, _ := model.GetByID(legacyID)
+	if legacy.OriginalKey != "Originals/legacy.jpg" {
+		t.Errorf("Expected original key %q, got %q", "Originals/legacy.jpg", legacy.OriginalKey)
+	}
+}
+
+func TestMediaModel_SetPlaceholder(t *testing.T) {
+	db := setupTestDB(t)
+	model := &MediaModel{DB: db}
+
+	id, err := model.InsertAndReturnID("blur.jpg", "full.jpg", "thumb.jpg")
+	if err != nil {
+		t.Fatalf("InsertAndReturnID failed: %v", err)
+	}
+
+	missing, err := model.GetMissingPlaceholders(10)
+	if err != nil {
+		t.Fatalf("GetMissingPlaceholders failed: %v", err)
+	}
+	if len(missing) != 1 || missing[0].ID != id {
+		t.Fatalf("Expected media %d to be missing a placeholder, got %v", id, missing)
+	}
+
+	if err := model.SetPlaceholder(id, "LEHV6nWB2yk8pyo0adR*.7kCMdnj", "#7e766e"); err != nil {
+		t.Fatalf("SetPlaceholder failed: %v", err)
+	}
+
+	media, _ := model.GetByID(id)
+	if media.BlurHash != "LEHV6nWB2yk8pyo0adR*.7kCMdnj" || media.DominantColor != "#7e766e" {
+		t.Errorf("Unexpected placeholder %q / %q", media.BlurHash, media.DominantColor)
+	}
+
+	missing, _ = model.GetMissingPlaceholders(10)
+	if len(missing) != 0 {
+		t.Errorf("Expected no media missing placeholders, got %d", len(missing))
+	}
+}
+
+func TestMediaModel_ReplaceFileAndRestore(t *testing.T) {
+	db := setupTestDB(t)
+	model := &MediaModel{DB: db}
+
+	id, err := model.InsertWithOriginal("v1.jpg", "full_v1.jpg", "thumb_v1.jpg", "Originals/v1.jpg")
+	if err != nil {
+		t.Fatalf("InsertWithOriginal failed: %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		id      int
+		file    string
+		wantErr bool
+	}{
+		{"✅ replace with v2", id, "v2.jpg", false},
+		{"✅ replace with v3", id, "v3.jpg", false},
+		{"❌ invalid ID", 9999, "nope.jpg", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := model.ReplaceFile(tc.id, tc.file, "full_"+tc.file, "thumb_"+tc.file, "Originals/"+tc.file, "", "")
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ReplaceFile() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+
+			media, _ := model.GetByID(tc.id)
+			if media.FileName != tc.file {
+				t.Errorf("Expected file name %q, got %q", tc.file, media.FileName)
+			}
+		})
+	}
+
+	versions, err := model.GetVersions(id)
+	if err != nil {
+		t.Fatalf("GetVersions failed: %v", err)
+	}
+	if len(versions) != 2 || versions[0].FileName != "v2.jpg" || versions[1].FileName != "v1.jpg" {
+		t.Fatalf("Expected versions [v2.jpg v1.jpg], got %v", versions)
+	}
+
+	// Restoring v1 keeps the ID and archives v3 in its place
+	if err := model.RestoreVersion(id, versions[1].ID); err != nil {
+		t.Fatalf("RestoreVersion failed: %v", err)
+	}
+	media, _ := model.GetByID(id)
+	if media.FileName != "v1.jpg" || media.OriginalKey != "Originals/v1.jpg" {
+		t.Errorf("Expected v1.jpg restored, got %q (%q)", media.FileName, media.OriginalKey)
+	}
+
+	versions, _ = model.GetVersions(id)
+	if len(versions) != 2 || versions[0].FileName != "v3.jpg" {
+		t.Errorf("Expected v3.jpg to be the newest version, got %v", versions)
+	}
+
+	if err := model.RestoreVersion(id, 9999); err == nil {
+		t.Error("Expected error restoring unknown version, got nil")
+	}
+}
+
+// TESTING TRASH FUNCTIONS
+func TestMediaModel_TrashRestoreAndPurge(t *testing.T) {
+	db := setupTestDB(t)
+	model := &MediaModel{DB: db}
+	galleries := &GalleryModel{DB: db}
+
+	galleryID, err := galleries.CreateAndReturnID("Trash Test", "", "trash-test")
+	if err != nil {
+		t.Fatalf("create gallery failed: %v", err)
+	}
+	keepID, _ := model.InsertWithOriginal("keep.jpg", "full_keep.jpg", "thumb_keep.jpg", "Originals/keep.jpg")
+	id, err := model.InsertWithOriginal("v1.jpg", "full_v1.jpg", "thumb_v1.jpg", "Originals/v1.jpg")
+	if err != nil {
+		t.Fatalf("InsertWithOriginal failed: %v", err)
+	}
+	galleries.Media().Append(galleryID, keepID, id)
+	if err := model.ReplaceFile(id, "v2.jpg", "full_v2.jpg", "thumb_v2.jpg", "Originals/v2.jpg", "", ""); err != nil {
+		t.Fatalf("ReplaceFile failed: %v", err)
+	}
+
+	cases := []struct {
+		name      string
+		action    func() error
+		wantErr   bool
+		wantCount int // media shown in the gallery afterwards
+	}{
+		{"✅ trash media", func() error { return model.Delete(id) }, false, 1},
+		{"❌ trash it again", func() error { return model.Delete(id) }, true, 1},
+		{"✅ restore media", func() error { return model.Restore(id) }, false, 2},
+		{"❌ restore media not in trash", func() error { return model.Restore(id) }, true, 2},
+		{"❌ purge media not in trash", func() error { _, err := model.Purge(id); return err }, true, 2},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.action()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tc.wantErr)
+			}
+			count, err := galleries.GetMediaCount(galleryID)
+			if err != nil {
+				t.Fatalf("GetMediaCount failed: %v", err)
+			}
+			if count != tc.wantCount {
+				t.Errorf("Expected %d media in gallery, got %d", tc.wantCount, count)
+			}
+		})
+	}
+
+	// Restored media is back at its old position
+	media, _ := galleries.GetMediaPaginated(galleryID, 10, 0)
+	if len(media) != 2 || media[1].ID != id || media[1].Position != 1 {
+		t.Errorf("Expected restored media at position 1, got %+v", media)
+	}
+
+	if err := model.Delete(id); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	trashed, _ := model.GetTrashed()
+	if len(trashed) != 1 || trashed[0].ID != id {
+		t.Fatalf("Expected media %d in trash, got %+v", id, trashed)
+	}
+
+	files, err := model.Purge(id)
+	if err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+	if len(files) != 2 {
+		t.Errorf("Expected current file and one version to purge, got %+v", files)
+	}
+	if err := model.Restore(id); err == nil {
+		t.Error("Expected purged media to be gone for good")
+	}
+}
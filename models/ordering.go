@@ -0,0 +1,67 @@
+package models
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Galleries and projects are listed in a hand-set order. New galleries go to
+// the end of their list and new projects to the top, the way each list was
+// ordered before it could be rearranged. Ties fall back to that same order.
+
+const (
+	nextGalleryPosition = `(SELECT COALESCE(MAX(position), -1) + 1 FROM galleries)`
+	nextProjectPosition = `(SELECT COALESCE(MIN(position), 1) - 1 FROM projects)`
+
+	galleryListOrder = `g.position ASC, g.id ASC`
+	projectListOrder = `pr.position ASC, pr.id DESC`
+)
+
+// UpdatePositions puts galleries in the order of ids
+func (g *GalleryModel) UpdatePositions(ids []int) error {
+	return updateListPositions(g.DB, "galleries g", galleryListOrder, ids)
+}
+
+// UpdatePositions puts projects in the order of ids
+func (p *ProjectModel) UpdatePositions(ids []int) error {
+	return updateListPositions(p.DB, "projects pr", projectListOrder, ids)
+}
+
+// updateListPositions renumbers every row of table 0 to n-1 in one
+// transaction: the rows in ids first, in that order, then any the list left
+// out in their current order, so a stale or partial list can't leave gaps or
+// ties. table includes the alias listOrder refers to; neither is user input.
+func updateListPositions(db *pgxpool.Pool, table, listOrder string, ids []int) error {
+	ctx := context.Background()
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Hold the rows so a concurrent reorder can't interleave with this one
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM `+table+` FOR UPDATE`); err != nil {
+		return err
+	}
+
+	name := strings.Fields(table)[0]
+	_, err = tx.Exec(ctx, `
+		UPDATE `+name+` t SET position = r.pos
+		FROM (
+			SELECT id, ROW_NUMBER() OVER (ORDER BY o.ord NULLS LAST, `+listOrder+`) - 1 AS pos
+			FROM `+table+`
+			LEFT JOIN (
+				SELECT id, MIN(ord) AS ord
+				FROM unnest($1::int[]) WITH ORDINALITY AS u(id, ord)
+				GROUP BY id
+			) o USING (id)
+		) r
+		WHERE t.id = r.id AND t.position IS DISTINCT FROM r.pos`, ids)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,277 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Outgoing email goes through the outbox rather than straight to SMTP, so a
+// mail server being down doesn't lose it or fail the request that sent it.
+// A background sender claims due emails, and a failed attempt is retried
+// with exponential backoff until it's sent or runs out of attempts, when it
+// becomes dead and waits for an admin to resend it.
+
+// Outbox statuses
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	OutboxDead    = "dead"
+)
+
+// ErrRateLimited is returned when an address has already been sent as many
+// emails of a kind as it may be within the window
+var ErrRateLimited = errors.New("too many emails to this address")
+
+// OutboxStatuses are the statuses an outbox email can be in
+var OutboxStatuses = []string{OutboxPending, OutboxDead, OutboxSent}
+
+const (
+	// OutboxMaxAttempts is how many times an email is tried before it's dead
+	OutboxMaxAttempts = 8
+
+	// The first retry waits outboxBaseDelay, doubling each time up to
+	// outboxMaxDelay
+	outboxBaseDelay = time.Minute
+	outboxMaxDelay  = 6 * time.Hour
+
+	// OutboxLease is how long a claimed email is left to its sender before
+	// someone else may try it, in case the sender died mid-send
+	OutboxLease = 5 * time.Minute
+)
+
+// OutboxEmail is an email waiting to be sent, or one that was
+type OutboxEmail struct {
+	ID            int
+	Kind          string // the email template it was rendered from
+	From          string
+	To            string
+	Subject       string
+	HTMLBody      string
+	TextBody      string
+	Status        string
+	Attempts      int
+	LastError     string
+	NextAttemptAt time.Time
+	SentAt        *time.Time
+	CreatedAt     time.Time
+}
+
+type OutboxModel struct {
+	DB *pgxpool.Pool
+}
+
+// OutboxBackoff is how long to wait before retrying an email that has failed
+// attempts times
+func OutboxBackoff(attempts int) time.Duration {
+	if attempts < 1 {
+		return 0
+	}
+	delay := outboxBaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= outboxMaxDelay {
+			return outboxMaxDelay
+		}
+	}
+	return delay
+}
+
+const outboxColumns = `id, COALESCE(kind, ''), from_addr, to_addr, subject, html_body, COALESCE(text_body, ''), status, attempts,
+	COALESCE(last_error, ''), next_attempt_at, sent_at, created_at`
+
+func scanOutboxEmail(row pgx.Row) (*OutboxEmail, error) {
+	e := &OutboxEmail{}
+	err := row.Scan(&e.ID, &e.Kind, &e.From, &e.To, &e.Subject, &e.HTMLBody, &e.TextBody, &e.Status, &e.Attempts,
+		&e.LastError, &e.NextAttemptAt, &e.SentAt, &e.CreatedAt)
+	return e, err
+}
+
+func (m *OutboxModel) queryEmails(query string, args ...any) ([]*OutboxEmail, error) {
+	rows, err := m.DB.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var emails []*OutboxEmail
+	for rows.Next() {
+		e, err := scanOutboxEmail(rows)
+		if err != nil {
+			return nil, err
+		}
+		emails = append(emails, e)
+	}
+	return emails, rows.Err()
+}
+
+// Enqueue adds an email of kind to the outbox, due straight away
+func (m *OutboxModel) Enqueue(kind, from, to, subject, htmlBody, textBody string) (int, error) {
+	var id int
+	err := m.DB.QueryRow(context.Background(), `
+		INSERT INTO outbox (kind, from_addr, to_addr, subject, html_body, text_body)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id`, kind, from, to, subject, htmlBody, textBody).Scan(&id)
+	return id, err
+}
+
+// EnqueueLimited is Enqueue, unless to has already been queued limit emails
+// of kind within window, when it returns ErrRateLimited. Addresses are
+// compared without case, and emails count whether or not they were sent.
+func (m *OutboxModel) EnqueueLimited(kind, from, to, subject, htmlBody, textBody string, limit int, window time.Duration) (int, error) {
+	ctx := context.Background()
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Serialise sends to the same address so two at once can't both pass
+	_, err = tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('outbox:' || $1 || ':' || LOWER($2)))`, kind, to)
+	if err != nil {
+		return 0, err
+	}
+
+	var recent int
+	err = tx.QueryRow(ctx, `
+		SELECT COUNT(*) FROM outbox
+		WHERE kind = $1 AND LOWER(to_addr) = LOWER($2) AND created_at > NOW() - make_interval(secs => $3)`,
+		kind, to, window.Seconds()).Scan(&recent)
+	if err != nil {
+		return 0, err
+	}
+	if recent >= limit {
+		return 0, ErrRateLimited
+	}
+
+	var id int
+	err = tx.QueryRow(ctx, `
+		INSERT INTO outbox (kind, from_addr, to_addr, subject, html_body, text_body)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id`, kind, from, to, subject, htmlBody, textBody).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit(ctx)
+}
+
+// ClaimDue returns up to limit pending emails that are due and leases them
+// to the caller, so concurrent senders never get the same email. The caller
+// reports back with MarkSent or MarkFailed; if it doesn't, the lease runs
+// out and the email is due again.
+func (m *OutboxModel) ClaimDue(limit int) ([]*OutboxEmail, error) {
+	return m.queryEmails(`
+		UPDATE outbox SET next_attempt_at = NOW() + make_interval(secs => $3)
+		WHERE id IN (
+			SELECT id FROM outbox
+			WHERE status = $1 AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at ASC, id ASC
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+outboxColumns,
+		OutboxPending, limit, OutboxLease.Seconds())
+}
+
+// MarkSent records that an email went out
+func (m *OutboxModel) MarkSent(id int) error {
+	return m.update(id, `
+		UPDATE outbox SET status = $2, attempts = attempts + 1, last_error = NULL, sent_at = NOW()
+		WHERE id = $1`, OutboxSent)
+}
+
+// MarkFailed records a failed attempt and schedules the next one, or marks
+// the email dead once it has used up its attempts
+func (m *OutboxModel) MarkFailed(id int, sendErr error) error {
+	ctx := context.Background()
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var attempts int
+	err = tx.QueryRow(ctx, `SELECT attempts + 1 FROM outbox WHERE id = $1 FOR UPDATE`, id).Scan(&attempts)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("no outbox email found with ID %d", id)
+	}
+	if err != nil {
+		return err
+	}
+
+	status := OutboxPending
+	if attempts >= OutboxMaxAttempts {
+		status = OutboxDead
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE outbox SET status = $2, attempts = $3, last_error = $4,
+		       next_attempt_at = NOW() + make_interval(secs => $5)
+		WHERE id = $1`,
+		id, status, attempts, sendErr.Error(), OutboxBackoff(attempts).Seconds())
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Resend puts a dead or sent email back in the queue with a fresh set of
+// attempts, due straight away
+func (m *OutboxModel) Resend(id int) error {
+	return m.update(id, `
+		UPDATE outbox SET status = $2, attempts = 0, next_attempt_at = NOW(), sent_at = NULL
+		WHERE id = $1`, OutboxPending)
+}
+
+func (m *OutboxModel) update(id int, query string, status string) error {
+	tag, err := m.DB.Exec(context.Background(), query, id, status)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("no outbox email found with ID %d", id)
+	}
+	return nil
+}
+
+func (m *OutboxModel) GetByID(id int) (*OutboxEmail, error) {
+	e, err := scanOutboxEmail(m.DB.QueryRow(context.Background(),
+		`SELECT `+outboxColumns+` FROM outbox WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("no outbox email found with ID %d", id)
+	}
+	return e, err
+}
+
+// GetByStatus returns up to limit emails in status, newest first
+func (m *OutboxModel) GetByStatus(status string, limit int) ([]*OutboxEmail, error) {
+	return m.queryEmails(`SELECT `+outboxColumns+` FROM outbox
+		WHERE status = $1 ORDER BY created_at DESC, id DESC LIMIT $2`, status, limit)
+}
+
+// StatusCounts returns how many emails are in each status
+func (m *OutboxModel) StatusCounts() (map[string]int, error) {
+	rows, err := m.DB.Query(context.Background(),
+		`SELECT status, COUNT(*) FROM outbox GROUP BY status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int, len(OutboxStatuses))
+	for _, s := range OutboxStatuses {
+		counts[s] = 0
+	}
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		counts[status] = n
+	}
+	return counts, rows.Err()
+}
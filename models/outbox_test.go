@@ -0,0 +1,146 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestOutboxBackoff(t *testing.T) {
+	cases := []struct {
+		name     string
+		attempts int
+		want     time.Duration
+	}{
+		{"✅ not failed yet", 0, 0},
+		{"✅ first failure", 1, time.Minute},
+		{"✅ doubles", 2, 2 * time.Minute},
+		{"✅ keeps doubling", 5, 16 * time.Minute},
+		{"✅ capped", 20, 6 * time.Hour},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := OutboxBackoff(tc.attempts); got != tc.want {
+				t.Errorf("Expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestOutboxModel_Lifecycle(t *testing.T) {
+	db := setupTestDB(t)
+	model := &OutboxModel{DB: db}
+
+	id, err := model.Enqueue("test", "studio@example.com", "ana@example.com", "Hello", "<p>Hi</p>", "Hi")
+	if err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	claimed, err := model.ClaimDue(10)
+	if err != nil {
+		t.Fatalf("ClaimDue failed: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].ID != id {
+		t.Fatalf("Expected to claim email %d, got %d emails", id, len(claimed))
+	}
+
+	// A claimed email isn't handed out again while its lease lasts
+	again, _ := model.ClaimDue(10)
+	if len(again) != 0 {
+		t.Errorf("Expected nothing to claim, got %d emails", len(again))
+	}
+
+	if err := model.MarkFailed(id, errors.New("connection refused")); err != nil {
+		t.Fatalf("MarkFailed failed: %v", err)
+	}
+	e, _ := model.GetByID(id)
+	if e.Status != OutboxPending || e.Attempts != 1 || e.LastError != "connection refused" {
+		t.Errorf("Unexpected email after one failure: %+v", e)
+	}
+	if !e.NextAttemptAt.After(time.Now().Add(30 * time.Second)) {
+		t.Errorf("Expected the retry to be backed off, got %v", e.NextAttemptAt)
+	}
+
+	// Use up the remaining attempts
+	for i := 1; i < OutboxMaxAttempts; i++ {
+		model.MarkFailed(id, errors.New("connection refused"))
+	}
+	e, _ = model.GetByID(id)
+	if e.Status != OutboxDead {
+		t.Errorf("Expected %q after %d attempts, got %q", OutboxDead, OutboxMaxAttempts, e.Status)
+	}
+
+	if err := model.Resend(id); err != nil {
+		t.Fatalf("Resend failed: %v", err)
+	}
+	claimed, _ = model.ClaimDue(10)
+	if len(claimed) != 1 || claimed[0].Attempts != 0 {
+		t.Fatalf("Expected the resent email to be due with fresh attempts, got %+v", claimed)
+	}
+
+	if err := model.MarkSent(id); err != nil {
+		t.Fatalf("MarkSent failed: %v", err)
+	}
+	e, _ = model.GetByID(id)
+	if e.Status != OutboxSent || e.SentAt == nil || e.LastError != "" {
+		t.Errorf("Unexpected email after sending: %+v", e)
+	}
+
+	counts, _ := model.StatusCounts()
+	if counts[OutboxSent] != 1 || counts[OutboxPending] != 0 || counts[OutboxDead] != 0 {
+		t.Errorf("Unexpected counts: %v", counts)
+	}
+
+	// An expired lease makes the email due again
+	id, _ = model.Enqueue("test", "studio@example.com", "bo@example.com", "Hello", "<p>Hi</p>", "Hi")
+	model.ClaimDue(10)
+	db.Exec(context.Background(), `UPDATE outbox SET next_attempt_at = NOW() - INTERVAL '1 second' WHERE id = $1`, id)
+	claimed, _ = model.ClaimDue(10)
+	if len(claimed) != 1 || claimed[0].ID != id {
+		t.Errorf("Expected to reclaim email %d after its lease ran out", id)
+	}
+
+	if err := model.Resend(9999); err == nil {
+		t.Error("Expected an error resending an unknown email")
+	}
+}
+
+func TestOutboxModel_EnqueueLimited(t *testing.T) {
+	db := setupTestDB(t)
+	model := &OutboxModel{DB: db}
+
+	enqueue := func(kind, to string) error {
+		_, err := model.EnqueueLimited(kind, "studio@example.com", to, "Thanks", "<p>Thanks</p>", "Thanks", 2, time.Hour)
+		return err
+	}
+
+	cases := []struct {
+		name    string
+		kind    string
+		to      string
+		wantErr error
+	}{
+		{"✅ first email", "auto_reply", "ana@example.com", nil},
+		{"✅ second email", "auto_reply", "ana@example.com", nil},
+		{"❌ over the limit", "auto_reply", "ana@example.com", ErrRateLimited},
+		{"❌ case doesn't matter", "auto_reply", "ANA@example.com", ErrRateLimited},
+		{"✅ another address", "auto_reply", "bo@example.com", nil},
+		{"✅ another kind", "contact_reply", "ana@example.com", nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := enqueue(tc.kind, tc.to); !errors.Is(err, tc.wantErr) {
+				t.Errorf("Expected %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+
+	// Emails older than the window don't count
+	db.Exec(context.Background(), `UPDATE outbox SET created_at = NOW() - INTERVAL '2 hours'`)
+	if err := enqueue("auto_reply", "ana@example.com"); err != nil {
+		t.Errorf("Expected the limit to reset after the window, got %v", err)
+	}
+}
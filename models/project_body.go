@@ -0,0 +1,157 @@
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"ikm/utils"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// A project body's rendered HTML is cached with a stamp of the media it
+// embeds. Media that is replaced, trashed or purged after the body was saved
+// changes the stamp, and the body is rendered again the next time the
+// project is loaded, so pages never point at stale or deleted images.
+
+// bodyFigures loads the library media that body embeds, keyed by ID, and a
+// stamp of what was found. Trashed or unknown media is left out.
+func (p *ProjectModel) bodyFigures(body string) (map[int]utils.Figure, string, error) {
+	figures := make(map[int]utils.Figure)
+
+	ids := utils.MediaRefs(body)
+	if len(ids) == 0 {
+		return figures, "", nil
+	}
+
+	rows, err := p.DB.Query(context.Background(), `
+		SELECT id, file_name, COALESCE(thumbnail_url, full_url), full_url
+		FROM media
+		WHERE id = ANY($1) AND deleted_at IS NULL`, ids)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var fig utils.Figure
+		if err := rows.Scan(&id, &fig.Alt, &fig.ThumbnailURL, &fig.FullURL); err != nil {
+			return nil, "", err
+		}
+		figures[id] = fig
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	return figures, figureStamp(figures), nil
+}
+
+// figureStamp fingerprints the figures a body was rendered with
+func figureStamp(figures map[int]utils.Figure) string {
+	ids := make([]int, 0, len(figures))
+	for id := range figures {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	h := sha256.New()
+	for _, id := range ids {
+		fig := figures[id]
+		fmt.Fprintf(h, "%d\x00%s\x00%s\x00%s\n", id, fig.Alt, fig.ThumbnailURL, fig.FullURL)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// RenderBody renders a Markdown project body to sanitized HTML, turning
+// [media:ID caption] paragraphs into figures of media from the library.
+// Trashed or unknown media is left out.
+func (p *ProjectModel) RenderBody(body string) (template.HTML, error) {
+	figures, _, err := p.bodyFigures(body)
+	if err != nil {
+		return "", err
+	}
+	return renderBody(body, figures)
+}
+
+func renderBody(body string, figures map[int]utils.Figure) (template.HTML, error) {
+	out, err := utils.RenderMarkdown(body, figures)
+	if err != nil {
+		return "", err
+	}
+	// Safe to mark as HTML: the Markdown output has been sanitized
+	return template.HTML(out), nil
+}
+
+// SetBody saves a project's Markdown body along with its rendered HTML, so
+// project pages don't render Markdown on every view
+func (p *ProjectModel) SetBody(id int, body string) error {
+	rendered, stamp, err := p.renderForSave(body)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	tx, err := p.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := setBody(ctx, tx, id, body, rendered, stamp); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// renderForSave renders body for caching, with the stamp of its media
+func (p *ProjectModel) renderForSave(body string) (template.HTML, string, error) {
+	figures, stamp, err := p.bodyFigures(body)
+	if err != nil {
+		return "", "", err
+	}
+	rendered, err := renderBody(body, figures)
+	return rendered, stamp, err
+}
+
+func setBody(ctx context.Context, tx pgx.Tx, id int, body string, rendered template.HTML, stamp string) error {
+	res, err := tx.Exec(ctx, `
+		UPDATE projects SET body = $1, body_html = $2, body_media_stamp = $3
+		WHERE id = $4 AND deleted_at IS NULL`, body, string(rendered), stamp, id)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("no project found with ID %d", id)
+	}
+	return nil
+}
+
+// refreshBody re-renders project.BodyHTML when the media its body embeds
+// has changed since stamp was taken, and caches the new rendering
+func (p *ProjectModel) refreshBody(project *Project, stamp string) error {
+	figures, current, err := p.bodyFigures(project.Body)
+	if err != nil {
+		return err
+	}
+	if current == stamp {
+		return nil
+	}
+
+	rendered, err := renderBody(project.Body, figures)
+	if err != nil {
+		return err
+	}
+	project.BodyHTML = rendered
+
+	// Only replace the rendering of the body we read, in case it was just
+	// edited
+	_, err = p.DB.Exec(context.Background(), `
+		UPDATE projects SET body_html = $1, body_media_stamp = $2
+		WHERE id = $3 AND body = $4`, string(rendered), current, project.ID, project.Body)
+	return err
+}
@@ -0,0 +1,311 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Projects carry structured details about the shoot alongside their story:
+// who it was for, when and where, what was delivered and who worked on it.
+
+// ErrInvalidLink is returned for a project link that isn't an http(s) URL
+var ErrInvalidLink = errors.New("links must be http:// or https:// URLs")
+
+// Credit names a collaborator and their role on a project
+type Credit struct {
+	Role string
+	Name string
+}
+
+// ProjectLink is an external link shown on a project, like a published
+// campaign or a magazine feature
+type ProjectLink struct {
+	Label string
+	URL   string
+}
+
+// ProjectMeta is the editable set of project details
+type ProjectMeta struct {
+	Client    string
+	ShootDate *time.Time
+	Location  string
+	Services  []string
+	Credits   []Credit
+	Links     []ProjectLink
+}
+
+// ProjectFilter narrows the public project list. Zero fields don't filter.
+type ProjectFilter struct {
+	Client  string
+	Service string
+	Year    int
+}
+
+// ProjectFilterOptions are the values visitors can filter projects by,
+// taken from the published projects
+type ProjectFilterOptions struct {
+	Clients  []string
+	Services []string
+	Years    []int
+}
+
+// SetMeta replaces a project's details, credits and links
+func (p *ProjectModel) SetMeta(id int, meta ProjectMeta) error {
+	ctx := context.Background()
+	tx, err := p.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := setMeta(ctx, tx, id, meta); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func setMeta(ctx context.Context, tx pgx.Tx, id int, meta ProjectMeta) error {
+	for _, l := range meta.Links {
+		u, err := url.Parse(l.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: %q", ErrInvalidLink, l.URL)
+		}
+	}
+	if meta.Services == nil {
+		meta.Services = []string{}
+	}
+
+	res, err := tx.Exec(ctx, `
+		UPDATE projects SET client = $1, shoot_date = $2, location = $3, services = $4
+		WHERE id = $5 AND deleted_at IS NULL`,
+		meta.Client, meta.ShootDate, meta.Location, meta.Services, id)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("no project found with ID %d", id)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM project_credits WHERE project_id = $1`, id); err != nil {
+		return err
+	}
+	for i, c := range meta.Credits {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO project_credits (project_id, role, name, position) VALUES ($1, $2, $3, $4)`,
+			id, c.Role, c.Name, i)
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM project_links WHERE project_id = $1`, id); err != nil {
+		return err
+	}
+	for i, l := range meta.Links {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO project_links (project_id, label, url, position) VALUES ($1, $2, $3, $4)`,
+			id, l.Label, l.URL, i)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadCredits fills in a project's credits and links
+func (p *ProjectModel) loadCredits(project *Project) error {
+	ctx := context.Background()
+
+	rows, err := p.DB.Query(ctx,
+		`SELECT role, name FROM project_credits WHERE project_id = $1 ORDER BY position ASC`, project.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	project.Credits = nil
+	for rows.Next() {
+		var c Credit
+		if err := rows.Scan(&c.Role, &c.Name); err != nil {
+			return err
+		}
+		project.Credits = append(project.Credits, c)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = p.DB.Query(ctx,
+		`SELECT label, url FROM project_links WHERE project_id = $1 ORDER BY position ASC`, project.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	project.Links = nil
+	for rows.Next() {
+		var l ProjectLink
+		if err := rows.Scan(&l.Label, &l.URL); err != nil {
+			return err
+		}
+		project.Links = append(project.Links, l)
+	}
+	return rows.Err()
+}
+
+// GetFilterOptions lists the clients, services and shoot years of published
+// projects, for the filters on the projects page
+func (p *ProjectModel) GetFilterOptions() (*ProjectFilterOptions, error) {
+	ctx := context.Background()
+	opts := &ProjectFilterOptions{}
+
+	rows, err := p.DB.Query(ctx, `
+		SELECT DISTINCT client FROM projects
+		WHERE published = TRUE AND deleted_at IS NULL AND COALESCE(client, '') <> ''
+		ORDER BY client ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var client string
+		if err := rows.Scan(&client); err != nil {
+			return nil, err
+		}
+		opts.Clients = append(opts.Clients, client)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = p.DB.Query(ctx, `
+		SELECT DISTINCT s FROM projects, UNNEST(services) AS s
+		WHERE published = TRUE AND deleted_at IS NULL
+		ORDER BY s ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var service string
+		if err := rows.Scan(&service); err != nil {
+			return nil, err
+		}
+		opts.Services = append(opts.Services, service)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = p.DB.Query(ctx, `
+		SELECT DISTINCT EXTRACT(YEAR FROM shoot_date)::INTEGER AS year FROM projects
+		WHERE published = TRUE AND deleted_at IS NULL AND shoot_date IS NOT NULL
+		ORDER BY year DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var year int
+		if err := rows.Scan(&year); err != nil {
+			return nil, err
+		}
+		opts.Years = append(opts.Years, year)
+	}
+	return opts, rows.Err()
+}
+
+// ParseServices splits a comma-separated list of services
+func ParseServices(text string) []string {
+	var services []string
+	seen := make(map[string]bool)
+	for _, s := range strings.Split(text, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" || seen[strings.ToLower(s)] {
+			continue
+		}
+		seen[strings.ToLower(s)] = true
+		services = append(services, s)
+	}
+	return services
+}
+
+// ParseCredits reads one "Role: Name" credit per line. A line without a
+// role is taken as just a name.
+func ParseCredits(text string) []Credit {
+	var credits []Credit
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		role, name, ok := strings.Cut(line, ":")
+		if !ok {
+			role, name = "", line
+		}
+		credits = append(credits, Credit{Role: strings.TrimSpace(role), Name: strings.TrimSpace(name)})
+	}
+	return credits
+}
+
+// ParseLinks reads one "Label | URL" link per line. A bare URL is labelled
+// with its host, and https:// is assumed when no scheme is given.
+func ParseLinks(text string) []ProjectLink {
+	var links []ProjectLink
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		label, link, ok := strings.Cut(line, "|")
+		if !ok {
+			label, link = "", line
+		}
+		label, link = strings.TrimSpace(label), strings.TrimSpace(link)
+		if !strings.Contains(link, "://") {
+			link = "https://" + link
+		}
+		if label == "" {
+			if u, err := url.Parse(link); err == nil && u.Host != "" {
+				label = strings.TrimPrefix(u.Host, "www.")
+			} else {
+				label = link
+			}
+		}
+		links = append(links, ProjectLink{Label: label, URL: link})
+	}
+	return links
+}
+
+// ServicesText is the services as the edit form shows them
+func (project *Project) ServicesText() string {
+	return strings.Join(project.Services, ", ")
+}
+
+// CreditsText is the credits as the edit form shows them
+func (project *Project) CreditsText() string {
+	lines := make([]string, len(project.Credits))
+	for i, c := range project.Credits {
+		if c.Role == "" {
+			lines[i] = c.Name
+		} else {
+			lines[i] = c.Role + ": " + c.Name
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// LinksText is the links as the edit form shows them
+func (project *Project) LinksText() string {
+	lines := make([]string, len(project.Links))
+	for i, l := range project.Links {
+		lines[i] = l.Label + " | " + l.URL
+	}
+	return strings.Join(lines, "\n")
+}
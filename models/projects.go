@@ -2,9 +2,13 @@ package models
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"html/template"
 	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -18,6 +22,20 @@ type Project struct {
 	CoverImageURL *string
 	MediaCount    int // ✅ Count of Media Items
 	Published     bool
+	PublishAt     *time.Time
+	UnpublishAt   *time.Time
+	// Body is the Markdown story of the project; BodyHTML is its sanitized
+	// rendering, cached when the body is saved and refreshed when media it
+	// embeds changes. See SetBody.
+	Body     string
+	BodyHTML template.HTML
+	// Structured details shown alongside the story. See SetMeta.
+	Client    string
+	ShootDate *time.Time
+	Location  string
+	Services  []string
+	Credits   []Credit
+	Links     []ProjectLink
 }
 
 type ProjectModel struct {
@@ -25,15 +43,22 @@ type ProjectModel struct {
 }
 
 // GetAllPublic returns all published projects with optional cover image
-
 func (p *ProjectModel) GetAllPublic() ([]*Project, error) {
+	return p.GetPublicFiltered(ProjectFilter{})
+}
+
+// GetPublicFiltered returns the published projects matching filter
+func (p *ProjectModel) GetPublicFiltered(filter ProjectFilter) ([]*Project, error) {
 	rows, err := p.DB.Query(context.Background(), `
-		SELECT pr.id, pr.title, pr.slug, pr.description, pr.cover_image_id, m.thumbnail_url
+		SELECT pr.id, pr.title, pr.slug, pr.description, pr.cover_image_id, m.thumbnail_url,
+		       COALESCE(pr.client, ''), pr.shoot_date, COALESCE(pr.services, '{}')
 		FROM projects pr
-		LEFT JOIN media m ON pr.cover_image_id = m.id
-		WHERE pr.published = TRUE
-		ORDER BY pr.id DESC
-	`)
+		LEFT JOIN media m ON pr.cover_image_id = m.id AND m.deleted_at IS NULL
+		WHERE pr.published = TRUE AND pr.deleted_at IS NULL
+		  AND ($1 = '' OR LOWER(pr.client) = LOWER($1))
+		  AND ($2 = '' OR EXISTS (SELECT 1 FROM UNNEST(pr.services) s WHERE LOWER(s) = LOWER($2)))
+		  AND ($3 = 0 OR EXTRACT(YEAR FROM pr.shoot_date) = $3)
+		ORDER BY `+projectListOrder, filter.Client, filter.Service, filter.Year)
 	if err != nil {
 		return nil, err
 	}
@@ -42,14 +67,15 @@ func (p *ProjectModel) GetAllPublic() ([]*Project, error) {
 	var projects []*Project
 	for rows.Next() {
 		var project Project
-		err := rows.Scan(&project.ID, &project.Title, &project.Slug, &project.Description, &project.CoverImageID, &project.CoverImageURL)
+		err := rows.Scan(&project.ID, &project.Title, &project.Slug, &project.Description, &project.CoverImageID, &project.CoverImageURL,
+			&project.Client, &project.ShootDate, &project.Services)
 		if err != nil {
 			return nil, err
 		}
 		projects = append(projects, &project)
 	}
 
-	return projects, nil
+	return projects, rows.Err()
 }
 
 func (p *ProjectModel) SetPublished(id int, published bool) error {
@@ -64,9 +90,40 @@ func (p *ProjectModel) SetPublished(id int, published bool) error {
 	return nil
 }
 
+// SetSchedule sets when a project publishes and unpublishes itself. A nil
+// time clears that part of the schedule.
+func (p *ProjectModel) SetSchedule(id int, publishAt, unpublishAt *time.Time) error {
+	if err := validateSchedule(publishAt, unpublishAt); err != nil {
+		return err
+	}
+
+	res, err := p.DB.Exec(context.Background(),
+		"UPDATE projects SET publish_at = $1, unpublish_at = $2 WHERE id = $3", publishAt, unpublishAt, id)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("no project found with ID %d", id)
+	}
+	return nil
+}
+
+// ApplySchedule publishes and unpublishes projects whose scheduled time has
+// passed, and returns how many changed. Projects in the trash are left alone
+// so restoring one doesn't bring it back published.
+func (p *ProjectModel) ApplySchedule() (int64, error) {
+	res, err := p.DB.Exec(context.Background(),
+		`UPDATE projects SET`+scheduleSet+` WHERE deleted_at IS NULL AND (`+scheduleDue+`)`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
+
 // GetByID returns a specific project by its ID
 func (p *ProjectModel) GetByID(id int) (*Project, error) {
 	var project Project
+	var stamp string
 	err := p.DB.QueryRow(context.Background(), `
 		SELECT
 		  pr.id,
@@ -76,10 +133,19 @@ func (p *ProjectModel) GetByID(id int) (*Project, error) {
 		  pr.published,
 		  pr.cover_image_id,
 		  m.thumbnail_url,
-		  (SELECT COUNT(*) FROM project_media WHERE project_id = pr.id) as media_count
+		  (SELECT COUNT(*) FROM project_media pm JOIN media m2 ON m2.id = pm.media_id WHERE pm.project_id = pr.id AND m2.deleted_at IS NULL) as media_count,
+		  pr.publish_at,
+		  pr.unpublish_at,
+		  COALESCE(pr.body, ''),
+		  COALESCE(pr.body_html, ''),
+		  COALESCE(pr.body_media_stamp, ''),
+		  COALESCE(pr.client, ''),
+		  pr.shoot_date,
+		  COALESCE(pr.location, ''),
+		  COALESCE(pr.services, '{}')
 		FROM projects pr
-		LEFT JOIN media m ON pr.cover_image_id = m.id
-		WHERE pr.id = $1
+		LEFT JOIN media m ON pr.cover_image_id = m.id AND m.deleted_at IS NULL
+		WHERE pr.id = $1 AND pr.deleted_at IS NULL
 
 	`, id).Scan(
 		&project.ID,
@@ -90,10 +156,25 @@ func (p *ProjectModel) GetByID(id int) (*Project, error) {
 		&project.CoverImageID,
 		&project.CoverImageURL,
 		&project.MediaCount,
+		&project.PublishAt,
+		&project.UnpublishAt,
+		&project.Body,
+		&project.BodyHTML,
+		&stamp,
+		&project.Client,
+		&project.ShootDate,
+		&project.Location,
+		&project.Services,
 	)
 	if err != nil {
 		return nil, err
 	}
+	if err := p.refreshBody(&project, stamp); err != nil {
+		return nil, err
+	}
+	if err := p.loadCredits(&project); err != nil {
+		return nil, err
+	}
 	return &project, nil
 }
 
@@ -101,10 +182,11 @@ func (p *ProjectModel) GetByID(id int) (*Project, error) {
 
 func (p *ProjectModel) GetMediaPaginated(projectID, limit, offset int) ([]*Media, error) {
 	rows, err := p.DB.Query(context.Background(), `
-		SELECT m.id, m.file_name, m.thumbnail_url, m.full_url, m.mime_type, m.embed_url
+		SELECT m.id, m.file_name, m.thumbnail_url, m.full_url, m.mime_type, m.embed_url,
+		       COALESCE(m.blurhash, ''), COALESCE(m.dominant_color, '')
 		FROM project_media pm
 		JOIN media m ON pm.media_id = m.id
-		WHERE pm.project_id = $1
+		WHERE pm.project_id = $1 AND m.deleted_at IS NULL
 		ORDER BY pm.position ASC
 		LIMIT $2 OFFSET $3
 	`, projectID, limit, offset)
@@ -119,7 +201,7 @@ func (p *ProjectModel) GetMediaPaginated(projectID, limit, offset int) ([]*Media
 		var embed pgtype.Text
 		var mime pgtype.Text
 
-		if err := rows.Scan(&m.ID, &m.FileName, &m.ThumbnailURL, &m.FullURL, &mime, &embed); err != nil {
+		if err := rows.Scan(&m.ID, &m.FileName, &m.ThumbnailURL, &m.FullURL, &mime, &embed, &m.BlurHash, &m.DominantColor); err != nil {
 			return nil, err
 		}
 
@@ -139,11 +221,12 @@ func (p *ProjectModel) GetMediaPaginated(projectID, limit, offset int) ([]*Media
 
 func (p *ProjectModel) GetAll() ([]map[string]interface{}, error) {
 	rows, err := p.DB.Query(context.Background(), `
-		SELECT pr.id, pr.title, pr.slug, pr.description, pr.cover_image_id, pr.published, m.thumbnail_url
+		SELECT pr.id, pr.title, pr.slug, pr.description, pr.cover_image_id, pr.published, m.thumbnail_url,
+		       pr.publish_at, pr.unpublish_at
 		FROM projects pr
-		LEFT JOIN media m ON pr.cover_image_id = m.id
-		ORDER BY pr.id DESC
-	`)
+		LEFT JOIN media m ON pr.cover_image_id = m.id AND m.deleted_at IS NULL
+		WHERE pr.deleted_at IS NULL
+		ORDER BY `+projectListOrder)
 	if err != nil {
 		return nil, err
 	}
@@ -156,8 +239,9 @@ func (p *ProjectModel) GetAll() ([]map[string]interface{}, error) {
 		var coverImageID *int
 		var coverImageURL *string
 		var published bool
+		var publishAt, unpublishAt *time.Time
 
-		if err := rows.Scan(&id, &title, &slug, &description, &coverImageID, &published, &coverImageURL); err != nil {
+		if err := rows.Scan(&id, &title, &slug, &description, &coverImageID, &published, &coverImageURL, &publishAt, &unpublishAt); err != nil {
 			return nil, err
 		}
 
@@ -169,6 +253,8 @@ func (p *ProjectModel) GetAll() ([]map[string]interface{}, error) {
 			"CoverImageID":  coverImageID,
 			"CoverImageURL": coverImageURL,
 			"Published":     published,
+			"PublishAt":     publishAt,
+			"UnpublishAt":   unpublishAt,
 		})
 	}
 	return projects, nil
@@ -178,8 +264,13 @@ func (p *ProjectModel) Create(title, description, slug string) error {
 	if strings.TrimSpace(slug) == "" {
 		return fmt.Errorf("slug cannot be empty")
 	}
-	_, err := p.DB.Exec(context.Background(),
-		`INSERT INTO projects (title, description, slug) VALUES ($1, $2, $3)`,
+	ctx := context.Background()
+	slug, err := uniqueSlug(ctx, p.DB, "projects", slug, 0)
+	if err != nil {
+		return err
+	}
+	_, err = p.DB.Exec(ctx,
+		`INSERT INTO projects (title, description, slug, position) VALUES ($1, $2, $3, `+nextProjectPosition+`)`,
 		title, description, slug,
 	)
 	return err
@@ -199,28 +290,156 @@ func (p *ProjectModel) SetCoverImage(projectID, mediaID int) error {
 	return nil
 }
 
+// UpdateBasicInfo updates a project's title, description and slug. A taken
+// slug gets a numeric suffix, and the old slug redirects to the new one.
 func (m *ProjectModel) UpdateBasicInfo(id int, title, description, slug string) error {
+	ctx := context.Background()
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := setBasicInfo(ctx, tx, id, title, description, slug); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// SaveInfo saves everything the project info form edits, its title,
+// description, slug, details and body, in one transaction, so a failure
+// part way leaves the project as it was
+func (m *ProjectModel) SaveInfo(id int, title, description, slug string, meta ProjectMeta, body string) error {
+	// Rendering only reads, so it's done before anything is locked
+	rendered, stamp, err := m.renderForSave(body)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := setMeta(ctx, tx, id, meta); err != nil {
+		return err
+	}
+	if err := setBasicInfo(ctx, tx, id, title, description, slug); err != nil {
+		return err
+	}
+	if err := setBody(ctx, tx, id, body, rendered, stamp); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func setBasicInfo(ctx context.Context, tx pgx.Tx, id int, title, description, slug string) error {
 	if strings.TrimSpace(slug) == "" {
 		return fmt.Errorf("slug cannot be empty")
 	}
 
-	res, err := m.DB.Exec(context.Background(), `
-		UPDATE projects SET title=$1, description=$2, slug=$3 WHERE id=$4
-	`, title, description, slug, id)
-	if err != nil {
+	if err := renameSlug(ctx, tx, "projects", id, slug); err != nil {
 		return err
 	}
-	if res.RowsAffected() == 0 {
-		return fmt.Errorf("no project found with ID %d", id)
+
+	_, err := tx.Exec(ctx, `
+		UPDATE projects SET title=$1, description=$2 WHERE id=$3
+	`, title, description, id)
+	return err
+}
+
+// CurrentSlug returns the current slug of the project that used to be at
+// oldSlug, or pgx.ErrNoRows if none did
+func (p *ProjectModel) CurrentSlug(oldSlug string) (string, error) {
+	return currentSlug(p.DB, "projects", oldSlug)
+}
+
+// Duplicate copies a project into a new unpublished draft with its
+// description, body, details, credits, links, linked galleries, related
+// projects, media in the same order, and cover. Schedules are not copied.
+func (p *ProjectModel) Duplicate(id int) (int, error) {
+	ctx := context.Background()
+	tx, err := p.DB.Begin(ctx)
+	if err != nil {
+		return 0, err
 	}
-	return nil
+	defer tx.Rollback(ctx)
+
+	var slug string
+	err = tx.QueryRow(ctx, `SELECT slug FROM projects WHERE id = $1 AND deleted_at IS NULL`, id).Scan(&slug)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("no project found with ID %d", id)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	newSlug, err := uniqueSlug(ctx, tx, "projects", slug+"-copy", 0)
+	if err != nil {
+		return 0, err
+	}
+
+	var newID int
+	err = tx.QueryRow(ctx, `
+		INSERT INTO projects (title, description, slug, cover_image_id, body, body_html, body_media_stamp,
+		                      client, shoot_date, location, services, position)
+		SELECT title || ' (copy)', description, $2, cover_image_id, body, body_html, body_media_stamp,
+		       client, shoot_date, location, services, `+nextProjectPosition+`
+		FROM projects WHERE id = $1
+		RETURNING id`, id, newSlug).Scan(&newID)
+	if err != nil {
+		return 0, err
+	}
+
+	// Trashed media stays behind
+	_, err = tx.Exec(ctx, `
+		INSERT INTO project_media (project_id, media_id, position)
+		SELECT $2, pm.media_id, ROW_NUMBER() OVER (ORDER BY pm.position) - 1
+		FROM project_media pm
+		JOIN media m ON m.id = pm.media_id
+		WHERE pm.project_id = $1 AND m.deleted_at IS NULL`, id, newID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO project_credits (project_id, role, name, position)
+		SELECT $2, role, name, position FROM project_credits WHERE project_id = $1`, id, newID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO project_links (project_id, label, url, position)
+		SELECT $2, label, url, position FROM project_links WHERE project_id = $1`, id, newID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO project_galleries (project_id, gallery_id, position)
+		SELECT $2, gallery_id, position FROM project_galleries WHERE project_id = $1`, id, newID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO related_projects (project_id, related_id, position)
+		SELECT $2, related_id, position FROM related_projects WHERE project_id = $1`, id, newID)
+	if err != nil {
+		return 0, err
+	}
+
+	return newID, tx.Commit(ctx)
 }
 
 // Get Count of Projects
 func (p *ProjectModel) Count() (int, error) {
 	var count int
 	err := p.DB.QueryRow(context.Background(), `
-		SELECT COUNT(*) FROM projects
+		SELECT COUNT(*) FROM projects WHERE deleted_at IS NULL
 	`).Scan(&count)
 	if err != nil {
 		return 0, err
@@ -233,8 +452,8 @@ func (p *ProjectModel) GetLatest(limit int) ([]map[string]interface{}, error) {
 	rows, err := p.DB.Query(context.Background(), `
 		SELECT pr.id, pr.title, pr.slug, pr.description, pr.cover_image_id, m.thumbnail_url
 		FROM projects pr
-		LEFT JOIN media m ON pr.cover_image_id = m.id
-		WHERE pr.published = TRUE
+		LEFT JOIN media m ON pr.cover_image_id = m.id AND m.deleted_at IS NULL
+		WHERE pr.published = TRUE AND pr.deleted_at IS NULL
 		ORDER BY pr.id DESC
 		LIMIT $1
 	`, limit)
@@ -257,6 +476,7 @@ func (p *ProjectModel) GetLatest(limit int) ([]map[string]interface{}, error) {
 		projects = append(projects, map[string]interface{}{
 			"ID":            id,
 			"Title":         title,
+			"Slug":          slug,
 			"Description":   description,
 			"CoverImageID":  coverImageID,
 			"CoverImageURL": coverImageURL,
@@ -268,26 +488,52 @@ func (p *ProjectModel) GetLatest(limit int) ([]map[string]interface{}, error) {
 
 func (p *ProjectModel) GetBySlug(slug string) (*Project, error) {
 	var project Project
+	var stamp string
 	err := p.DB.QueryRow(context.Background(), `
-		SELECT id, title, slug, description, cover_image_id, published
-		FROM projects WHERE slug = $1`, slug).Scan(
+		SELECT id, title, slug, description, cover_image_id, published, COALESCE(body, ''), COALESCE(body_html, ''),
+		       COALESCE(body_media_stamp, ''), COALESCE(client, ''), shoot_date, COALESCE(location, ''), COALESCE(services, '{}')
+		FROM projects WHERE slug = $1 AND deleted_at IS NULL`, slug).Scan(
 		&project.ID, &project.Title, &project.Slug, &project.Description, &project.CoverImageID, &project.Published,
+		&project.Body, &project.BodyHTML, &stamp, &project.Client, &project.ShootDate, &project.Location, &project.Services,
 	)
 	if err != nil {
 		return nil, err
 	}
+	if err := p.refreshBody(&project, stamp); err != nil {
+		return nil, err
+	}
+	if err := p.loadCredits(&project); err != nil {
+		return nil, err
+	}
 	return &project, nil
 }
 
+// Delete moves a project to the trash
 func (p *ProjectModel) Delete(id int) error {
-	res, err := p.DB.Exec(context.Background(),
-		`DELETE FROM projects WHERE id = $1`, id,
-	)
-	if err != nil {
-		return err
-	}
-	if res.RowsAffected() == 0 {
-		return fmt.Errorf("no project found with ID %d", id)
-	}
-	return nil
+	return trashRow(p.DB, "projects", id)
+}
+
+// Restore takes a project out of the trash with its media and order intact
+func (p *ProjectModel) Restore(id int) error {
+	return restoreRow(p.DB, "projects", id)
+}
+
+// Purge permanently deletes a trashed project. Its media stays in the library.
+func (p *ProjectModel) Purge(id int) error {
+	return purgeRow(p.DB, "projects", id)
+}
+
+// PurgeTrashedBefore permanently deletes projects trashed before cutoff
+func (p *ProjectModel) PurgeTrashedBefore(cutoff time.Time) (int64, error) {
+	return purgeRowsBefore(p.DB, "projects", cutoff)
+}
+
+// GetTrashed lists trashed projects, most recently deleted first
+func (p *ProjectModel) GetTrashed() ([]*TrashedItem, error) {
+	return trashedRows(p.DB, `
+		SELECT pr.id, pr.title, m.thumbnail_url, pr.deleted_at
+		FROM projects pr
+		LEFT JOIN media m ON pr.cover_image_id = m.id
+		WHERE pr.deleted_at IS NOT NULL
+		ORDER BY pr.deleted_at DESC`)
 }
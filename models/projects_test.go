@@ -1,7 +1,12 @@
 package models
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestProjectModel_Create(t *testing.T) {
@@ -37,11 +42,11 @@ func TestProjectModel_Create(t *testing.T) {
 			wantErr:     true,
 		},
 		{
-			name:        "❌ duplicate slug",
+			name:        "✅ duplicate slug gets a suffix",
 			title:       "Duplicate Slug",
 			description: "Second project",
 			slug:        "project-alpha",
-			wantErr:     true,
+			wantErr:     false,
 		},
 	}
 
@@ -178,6 +183,60 @@ func TestProjectModel_SetPublished(t *testing.T) {
 	}
 }
 
+func TestProjectModel_ApplySchedule(t *testing.T) {
+	db := setupTestDB(t)
+	model := &ProjectModel{DB: db}
+
+	hourAgo := time.Now().Add(-time.Hour)
+
+	cases := []struct {
+		name          string
+		trashed       bool
+		wantPublished bool
+	}{
+		{"✅ due project is published", false, true},
+		{"✅ trashed project is left alone", true, false},
+	}
+
+	for i, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			slug := fmt.Sprintf("scheduled-%d", i)
+			if err := model.Create(slug, "desc", slug); err != nil {
+				t.Fatalf("Create failed: %v", err)
+			}
+			project, _ := model.GetBySlug(slug)
+			if err := model.SetSchedule(project.ID, &hourAgo, nil); err != nil {
+				t.Fatalf("SetSchedule failed: %v", err)
+			}
+			if tc.trashed {
+				if err := model.Delete(project.ID); err != nil {
+					t.Fatalf("Delete failed: %v", err)
+				}
+			}
+
+			if _, err := model.ApplySchedule(); err != nil {
+				t.Fatalf("ApplySchedule failed: %v", err)
+			}
+
+			if tc.trashed {
+				if err := model.Restore(project.ID); err != nil {
+					t.Fatalf("Restore failed: %v", err)
+				}
+			}
+			p, err := model.GetByID(project.ID)
+			if err != nil {
+				t.Fatalf("GetByID failed: %v", err)
+			}
+			if p.Published != tc.wantPublished {
+				t.Errorf("Expected published=%v, got %v", tc.wantPublished, p.Published)
+			}
+			if pending := p.PublishAt != nil; pending == tc.wantPublished {
+				t.Errorf("Expected pending schedule=%v, got publish_at=%v", !tc.wantPublished, p.PublishAt)
+			}
+		})
+	}
+}
+
 func TestProjectModel_Delete(t *testing.T) {
 	db := setupTestDB(t)
 	model := &ProjectModel{DB: db}
@@ -288,3 +347,368 @@ func TestProjectModel_SetCoverImage(t *testing.T) {
 		})
 	}
 }
+
+func TestProjectModel_SetBody(t *testing.T) {
+	db := setupTestDB(t)
+	model := &ProjectModel{DB: db}
+	media := &MediaModel{DB: db}
+
+	if err := model.Create("Lookbook", "", "lookbook"); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	project, _ := model.GetBySlug("lookbook")
+	shot, _ := media.InsertAndReturnID("shot.jpg", "full_shot.jpg", "thumb_shot.jpg")
+	trashed, _ := media.InsertAndReturnID("old.jpg", "full_old.jpg", "thumb_old.jpg")
+	media.Delete(trashed)
+
+	cases := []struct {
+		name    string
+		id      int
+		body    string
+		want    []string
+		notWant []string
+		wantErr bool
+	}{
+		{"✅ markdown", project.ID, "## Brief\n\nShot on *film*.", []string{"<h2>Brief</h2>", "<em>film</em>"}, nil, false},
+		{"✅ media figure", project.ID, fmt.Sprintf("[media:%d Backstage]", shot), []string{"<figure>", "thumb_shot.jpg 500w", "<figcaption>Backstage</figcaption>"}, nil, false},
+		{"✅ trashed media is left out", project.ID, fmt.Sprintf("[media:%d]", trashed), nil, []string{"full_old.jpg", "[media:"}, false},
+		{"✅ scripts are stripped", project.ID, "<script>alert(1)</script>\n\n[x](javascript:alert(1))", nil, []string{"<script", "javascript:"}, false},
+		{"❌ invalid ID", 9999, "Hello", nil, nil, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := model.SetBody(tc.id, tc.body)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("SetBody() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+
+			p, err := model.GetByID(tc.id)
+			if err != nil {
+				t.Fatalf("GetByID failed: %v", err)
+			}
+			if p.Body != tc.body {
+				t.Errorf("Expected body %q, got %q", tc.body, p.Body)
+			}
+			for _, s := range tc.want {
+				if !strings.Contains(string(p.BodyHTML), s) {
+					t.Errorf("Expected %q in %q", s, p.BodyHTML)
+				}
+			}
+			for _, s := range tc.notWant {
+				if strings.Contains(string(p.BodyHTML), s) {
+					t.Errorf("Did not expect %q in %q", s, p.BodyHTML)
+				}
+			}
+		})
+	}
+}
+
+func TestProjectModel_BodyFollowsMedia(t *testing.T) {
+	db := setupTestDB(t)
+	model := &ProjectModel{DB: db}
+	media := &MediaModel{DB: db}
+
+	if err := model.Create("Editorial", "", "editorial"); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	project, _ := model.GetBySlug("editorial")
+	shot, _ := media.InsertAndReturnID("shot.jpg", "full_shot.jpg", "thumb_shot.jpg")
+	if err := model.SetBody(project.ID, fmt.Sprintf("Intro\n\n[media:%d]", shot)); err != nil {
+		t.Fatalf("SetBody failed: %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		change  func() error
+		want    string
+		notWant string
+	}{
+		{"✅ saved body", func() error { return nil }, "full_shot.jpg", ""},
+		{"✅ replaced media", func() error {
+			return media.ReplaceFile(shot, "new.jpg", "full_new.jpg", "thumb_new.jpg", "", "", "")
+		}, "full_new.jpg", "full_shot.jpg"},
+		{"✅ trashed media", func() error { return media.Delete(shot) }, "Intro", "full_new.jpg"},
+		{"✅ restored media", func() error { return media.Restore(shot) }, "full_new.jpg", ""},
+		{"✅ purged media", func() error {
+			media.Delete(shot)
+			_, err := media.Purge(shot)
+			return err
+		}, "Intro", "full_new.jpg"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.change(); err != nil {
+				t.Fatalf("change failed: %v", err)
+			}
+
+			p, err := model.GetBySlug("editorial")
+			if err != nil {
+				t.Fatalf("GetBySlug failed: %v", err)
+			}
+			if !strings.Contains(string(p.BodyHTML), tc.want) {
+				t.Errorf("Expected %q in %q", tc.want, p.BodyHTML)
+			}
+			if tc.notWant != "" && strings.Contains(string(p.BodyHTML), tc.notWant) {
+				t.Errorf("Did not expect %q in %q", tc.notWant, p.BodyHTML)
+			}
+
+			// The fresh rendering is cached for the next view
+			var cached string
+			db.QueryRow(context.Background(), `SELECT body_html FROM projects WHERE id = $1`, project.ID).Scan(&cached)
+			if cached != string(p.BodyHTML) {
+				t.Errorf("Expected the new rendering to be cached, got %q", cached)
+			}
+		})
+	}
+}
+
+func TestProjectModel_SetMeta(t *testing.T) {
+	db := setupTestDB(t)
+	model := &ProjectModel{DB: db}
+
+	if err := model.Create("Campaign", "", "campaign"); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	project, _ := model.GetBySlug("campaign")
+	model.SetPublished(project.ID, true)
+
+	shot := time.Date(2024, 5, 12, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name    string
+		id      int
+		meta    ProjectMeta
+		wantErr bool
+	}{
+		{
+			name: "✅ full details",
+			id:   project.ID,
+			meta: ProjectMeta{
+				Client:    "Acme",
+				ShootDate: &shot,
+				Location:  "Canberra",
+				Services:  []string{"Photography", "Retouching"},
+				Credits:   []Credit{{Role: "Styling", Name: "Jo"}, {Role: "MUA", Name: "Sam"}},
+				Links:     []ProjectLink{{Label: "Feature", URL: "https://example.com/feature"}},
+			},
+		},
+		{
+			name: "✅ cleared details",
+			id:   project.ID,
+			meta: ProjectMeta{},
+		},
+		{
+			name:    "❌ link without http scheme",
+			id:      project.ID,
+			meta:    ProjectMeta{Links: []ProjectLink{{Label: "Bad", URL: "javascript:alert(1)"}}},
+			wantErr: true,
+		},
+		{
+			name:    "❌ invalid ID",
+			id:      9999,
+			meta:    ProjectMeta{Client: "Acme"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := model.SetMeta(tc.id, tc.meta)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("SetMeta() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+
+			p, err := model.GetByID(tc.id)
+			if err != nil {
+				t.Fatalf("GetByID failed: %v", err)
+			}
+			if p.Client != tc.meta.Client || p.Location != tc.meta.Location {
+				t.Errorf("Expected client %q and location %q, got %q and %q", tc.meta.Client, tc.meta.Location, p.Client, p.Location)
+			}
+			if (p.ShootDate == nil) != (tc.meta.ShootDate == nil) {
+				t.Errorf("Expected shoot date %v, got %v", tc.meta.ShootDate, p.ShootDate)
+			}
+			if len(p.Services) != len(tc.meta.Services) || len(p.Credits) != len(tc.meta.Credits) || len(p.Links) != len(tc.meta.Links) {
+				t.Errorf("Expected %d services, %d credits and %d links, got %v, %v and %v",
+					len(tc.meta.Services), len(tc.meta.Credits), len(tc.meta.Links), p.Services, p.Credits, p.Links)
+			}
+			if len(p.Credits) > 0 && p.Credits[0] != tc.meta.Credits[0] {
+				t.Errorf("Expected credits in order, got %v", p.Credits)
+			}
+		})
+	}
+}
+
+func TestProjectModel_GetPublicFiltered(t *testing.T) {
+	db := setupTestDB(t)
+	model := &ProjectModel{DB: db}
+
+	shot2023 := time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)
+	shot2024 := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	for _, p := range []struct {
+		slug string
+		meta ProjectMeta
+	}{
+		{"filter-acme", ProjectMeta{Client: "Acme", ShootDate: &shot2024, Services: []string{"Photography"}}},
+		{"filter-globex", ProjectMeta{Client: "Globex", ShootDate: &shot2023, Services: []string{"Photography", "Video"}}},
+	} {
+		if err := model.Create(p.slug, "", p.slug); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		project, _ := model.GetBySlug(p.slug)
+		model.SetPublished(project.ID, true)
+		if err := model.SetMeta(project.ID, p.meta); err != nil {
+			t.Fatalf("SetMeta failed: %v", err)
+		}
+	}
+
+	cases := []struct {
+		name   string
+		filter ProjectFilter
+		want   []string
+	}{
+		{"✅ no filter", ProjectFilter{}, []string{"filter-globex", "filter-acme"}},
+		{"✅ by client, any case", ProjectFilter{Client: "acme"}, []string{"filter-acme"}},
+		{"✅ by service", ProjectFilter{Service: "Video"}, []string{"filter-globex"}},
+		{"✅ by year", ProjectFilter{Year: 2024}, []string{"filter-acme"}},
+		{"✅ no match", ProjectFilter{Client: "Acme", Year: 2023}, nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			projects, err := model.GetPublicFiltered(tc.filter)
+			if err != nil {
+				t.Fatalf("GetPublicFiltered failed: %v", err)
+			}
+			var got []string
+			for _, p := range projects {
+				if strings.HasPrefix(p.Slug, "filter-") {
+					got = append(got, p.Slug)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Errorf("Expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestParseCreditsAndLinks(t *testing.T) {
+	credits := ParseCredits("Styling: Jo\n\n  Sam  \nHair & Makeup: Lee: Jr")
+	want := []Credit{{"Styling", "Jo"}, {"", "Sam"}, {"Hair & Makeup", "Lee: Jr"}}
+	if fmt.Sprint(credits) != fmt.Sprint(want) {
+		t.Errorf("Expected credits %v, got %v", want, credits)
+	}
+
+	links := ParseLinks("Feature | https://example.com/a\nwww.vogue.com/shoot")
+	wantLinks := []ProjectLink{{"Feature", "https://example.com/a"}, {"vogue.com", "https://www.vogue.com/shoot"}}
+	if fmt.Sprint(links) != fmt.Sprint(wantLinks) {
+		t.Errorf("Expected links %v, got %v", wantLinks, links)
+	}
+
+	services := ParseServices("Photography, retouching,, photography ")
+	if strings.Join(services, "|") != "Photography|retouching" {
+		t.Errorf("Expected deduplicated services, got %v", services)
+	}
+}
+
+func TestProjectModel_UpdatePositions(t *testing.T) {
+	db := setupTestDB(t)
+	model := &ProjectModel{DB: db}
+
+	var ids []int
+	for _, slug := range []string{"order-a", "order-b", "order-c"} {
+		model.Create(slug, "", slug)
+		p, _ := model.GetBySlug(slug)
+		model.SetPublished(p.ID, true)
+		ids = append(ids, p.ID)
+	}
+
+	order := func() []int {
+		projects, err := model.GetAllPublic()
+		if err != nil {
+			t.Fatalf("GetAllPublic failed: %v", err)
+		}
+		var got []int
+		for _, p := range projects {
+			if strings.HasPrefix(p.Slug, "order-") {
+				got = append(got, p.ID)
+			}
+		}
+		return got
+	}
+
+	// New projects go to the top
+	if got, want := order(), []int{ids[2], ids[1], ids[0]}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Expected newest first %v, got %v", want, got)
+	}
+
+	if err := model.UpdatePositions([]int{ids[1], ids[0], ids[2]}); err != nil {
+		t.Fatalf("UpdatePositions failed: %v", err)
+	}
+	if got, want := order(), []int{ids[1], ids[0], ids[2]}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Expected the saved order %v, got %v", want, got)
+	}
+}
+
+func TestProjectModel_SaveInfo(t *testing.T) {
+	db := setupTestDB(t)
+	model := &ProjectModel{DB: db}
+
+	if err := model.Create("Portraits", "Before", "portraits"); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	project, _ := model.GetBySlug("portraits")
+	if err := model.SaveInfo(project.ID, "Portraits", "Before", "portraits",
+		ProjectMeta{Client: "Acme"}, "Old story"); err != nil {
+		t.Fatalf("SaveInfo failed: %v", err)
+	}
+
+	cases := []struct {
+		name      string
+		slug      string
+		meta      ProjectMeta
+		wantErr   error
+		wantSaved bool
+	}{
+		{"❌ bad link saves nothing", "studio-portraits",
+			ProjectMeta{Client: "Other", Links: []ProjectLink{{Label: "Bad", URL: "javascript:alert(1)"}}},
+			ErrInvalidLink, false},
+		{"❌ failure after the details saves nothing", "  ",
+			ProjectMeta{Client: "Other"}, nil, false},
+		{"✅ everything saved", "studio-portraits",
+			ProjectMeta{Client: "Other"}, nil, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := model.SaveInfo(project.ID, "Studio Portraits", "After", tc.slug, tc.meta, "New story")
+			if (err == nil) != tc.wantSaved {
+				t.Fatalf("SaveInfo() error = %v, wantSaved %v", err, tc.wantSaved)
+			}
+			if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+				t.Errorf("Expected %v, got %v", tc.wantErr, err)
+			}
+
+			p, err := model.GetByID(project.ID)
+			if err != nil {
+				t.Fatalf("GetByID failed: %v", err)
+			}
+			want := []string{"Portraits", "Before", "portraits", "Acme", "Old story"}
+			if tc.wantSaved {
+				want = []string{"Studio Portraits", "After", "studio-portraits", "Other", "New story"}
+			}
+			got := []string{p.Title, p.Description, p.Slug, p.Client, p.Body}
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("Expected %q, got %q", want, got)
+			}
+		})
+	}
+}
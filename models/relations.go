@@ -0,0 +1,165 @@
+package models
+
+import (
+	"context"
+	"fmt"
+)
+
+// Projects link to the galleries they were drawn from and to related
+// projects. Related projects are picked by hand; a project without any picks
+// falls back to published projects sharing a service or its client.
+
+// SetGalleries replaces the galleries linked to a project, in the given order
+func (p *ProjectModel) SetGalleries(projectID int, galleryIDs []int) error {
+	return p.replaceLinks(projectID, "project_galleries", "gallery_id", galleryIDs)
+}
+
+// SetRelated replaces the hand-picked related projects of a project, in the
+// given order. A project can't be related to itself.
+func (p *ProjectModel) SetRelated(projectID int, relatedIDs []int) error {
+	for _, id := range relatedIDs {
+		if id == projectID {
+			return fmt.Errorf("project %d can't be related to itself", projectID)
+		}
+	}
+	return p.replaceLinks(projectID, "related_projects", "related_id", relatedIDs)
+}
+
+// replaceLinks swaps the rows of a project link table for ids. table and
+// column are never user input.
+func (p *ProjectModel) replaceLinks(projectID int, table, column string, ids []int) error {
+	ctx := context.Background()
+	tx, err := p.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var exists bool
+	err = tx.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM projects WHERE id = $1 AND deleted_at IS NULL)`, projectID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("no project found with ID %d", projectID)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM `+table+` WHERE project_id = $1`, projectID); err != nil {
+		return err
+	}
+	for i, id := range ids {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO `+table+` (project_id, `+column+`, position) VALUES ($1, $2, $3)
+			 ON CONFLICT DO NOTHING`, projectID, id, i)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// GetGalleryIDs returns the IDs of the galleries linked to a project,
+// including unpublished ones, for the admin checkboxes
+func (p *ProjectModel) GetGalleryIDs(projectID int) ([]int, error) {
+	return p.linkedIDs(`SELECT gallery_id FROM project_galleries WHERE project_id = $1 ORDER BY position ASC`, projectID)
+}
+
+// GetRelatedIDs returns the IDs of the hand-picked related projects
+func (p *ProjectModel) GetRelatedIDs(projectID int) ([]int, error) {
+	return p.linkedIDs(`SELECT related_id FROM related_projects WHERE project_id = $1 ORDER BY position ASC`, projectID)
+}
+
+func (p *ProjectModel) linkedIDs(query string, projectID int) ([]int, error) {
+	rows, err := p.DB.Query(context.Background(), query, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// GetGalleries lists the published galleries linked to a project with their
+// covers
+func (p *ProjectModel) GetGalleries(projectID int) ([]*GalleryLink, error) {
+	g := &GalleryModel{DB: p.DB}
+	return g.links(`
+		SELECT g.id, g.title, g.slug, g.published, m.full_url,
+		       (SELECT COUNT(*) FROM gallery_media gm2 JOIN media m2 ON m2.id = gm2.media_id WHERE gm2.gallery_id = g.id AND m2.deleted_at IS NULL)
+		FROM project_galleries pg
+		JOIN galleries g ON g.id = pg.gallery_id
+		LEFT JOIN media m ON g.cover_image_id = m.id AND m.deleted_at IS NULL
+		WHERE pg.project_id = $1 AND g.published = TRUE AND g.deleted_at IS NULL AND g.client_access = FALSE
+		ORDER BY pg.position ASC`, projectID)
+}
+
+// GetProjects lists the published projects a gallery is linked to
+func (g *GalleryModel) GetProjects(galleryID int) ([]*Project, error) {
+	p := &ProjectModel{DB: g.DB}
+	return p.cards(`
+		SELECT pr.id, pr.title, pr.slug, pr.description, pr.cover_image_id, m.thumbnail_url
+		FROM project_galleries pg
+		JOIN projects pr ON pr.id = pg.project_id
+		LEFT JOIN media m ON pr.cover_image_id = m.id AND m.deleted_at IS NULL
+		WHERE pg.gallery_id = $1 AND pr.published = TRUE AND pr.deleted_at IS NULL
+		ORDER BY `+projectListOrder, galleryID)
+}
+
+// GetRelated lists up to limit published projects related to a project: the
+// hand-picked ones if there are any, otherwise those sharing the most
+// services, or the same client
+func (p *ProjectModel) GetRelated(projectID, limit int) ([]*Project, error) {
+	related, err := p.cards(`
+		SELECT pr.id, pr.title, pr.slug, pr.description, pr.cover_image_id, m.thumbnail_url
+		FROM related_projects rp
+		JOIN projects pr ON pr.id = rp.related_id
+		LEFT JOIN media m ON pr.cover_image_id = m.id AND m.deleted_at IS NULL
+		WHERE rp.project_id = $1 AND pr.published = TRUE AND pr.deleted_at IS NULL
+		ORDER BY rp.position ASC
+		LIMIT $2`, projectID, limit)
+	if err != nil || len(related) > 0 {
+		return related, err
+	}
+
+	return p.cards(`
+		SELECT pr.id, pr.title, pr.slug, pr.description, pr.cover_image_id, m.thumbnail_url
+		FROM projects src
+		JOIN projects pr ON pr.id <> src.id
+		LEFT JOIN media m ON pr.cover_image_id = m.id AND m.deleted_at IS NULL
+		WHERE src.id = $1 AND pr.published = TRUE AND pr.deleted_at IS NULL
+		  AND (pr.services && src.services
+		       OR (COALESCE(src.client, '') <> '' AND LOWER(pr.client) = LOWER(src.client)))
+		ORDER BY CARDINALITY(ARRAY(SELECT UNNEST(pr.services) INTERSECT SELECT UNNEST(src.services))) DESC,
+		         pr.id DESC
+		LIMIT $2`, projectID, limit)
+}
+
+// cards scans project rows of id, title, slug, description, cover ID and
+// cover URL
+func (p *ProjectModel) cards(query string, args ...any) ([]*Project, error) {
+	rows, err := p.DB.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var projects []*Project
+	for rows.Next() {
+		project := &Project{}
+		err := rows.Scan(&project.ID, &project.Title, &project.Slug, &project.Description, &project.CoverImageID, &project.CoverImageURL)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+	return projects, rows.Err()
+}
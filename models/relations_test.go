@@ -0,0 +1,99 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProjectModel_GalleryLinks(t *testing.T) {
+	db := setupTestDB(t)
+	projects := &ProjectModel{DB: db}
+	galleries := &GalleryModel{DB: db}
+
+	projects.Create("Lookbook", "", "links-lookbook")
+	project, _ := projects.GetBySlug("links-lookbook")
+	projects.SetPublished(project.ID, true)
+
+	shoot, _ := galleries.CreateAndReturnID("Shoot", "", "links-shoot")
+	draft, _ := galleries.CreateAndReturnID("Outtakes", "", "links-outtakes")
+	galleries.SetPublished(shoot, true)
+
+	if err := projects.SetGalleries(project.ID, []int{draft, shoot}); err != nil {
+		t.Fatalf("SetGalleries failed: %v", err)
+	}
+	if err := projects.SetGalleries(9999, []int{shoot}); err == nil {
+		t.Error("Expected error for an invalid project ID, got nil")
+	}
+
+	ids, _ := projects.GetGalleryIDs(project.ID)
+	if len(ids) != 2 || ids[0] != draft {
+		t.Errorf("Expected both galleries in order, got %v", ids)
+	}
+
+	linked, err := projects.GetGalleries(project.ID)
+	if err != nil {
+		t.Fatalf("GetGalleries failed: %v", err)
+	}
+	if len(linked) != 1 || linked[0].ID != shoot {
+		t.Errorf("Expected only the published gallery, got %v", linked)
+	}
+
+	back, err := galleries.GetProjects(shoot)
+	if err != nil {
+		t.Fatalf("GetProjects failed: %v", err)
+	}
+	if len(back) != 1 || back[0].ID != project.ID {
+		t.Errorf("Expected the gallery to list the project, got %v", back)
+	}
+}
+
+func TestProjectModel_GetRelated(t *testing.T) {
+	db := setupTestDB(t)
+	model := &ProjectModel{DB: db}
+
+	ids := map[string]int{}
+	for _, p := range []struct {
+		slug string
+		meta ProjectMeta
+	}{
+		{"rel-source", ProjectMeta{Client: "Acme", Services: []string{"Photography", "Retouching"}}},
+		{"rel-both-services", ProjectMeta{Services: []string{"Photography", "Retouching"}}},
+		{"rel-one-service", ProjectMeta{Services: []string{"Retouching"}}},
+		{"rel-same-client", ProjectMeta{Client: "acme"}},
+		{"rel-unrelated", ProjectMeta{Client: "Globex", Services: []string{"Video"}}},
+	} {
+		model.Create(p.slug, "", p.slug)
+		project, _ := model.GetBySlug(p.slug)
+		model.SetPublished(project.ID, true)
+		model.SetMeta(project.ID, p.meta)
+		ids[p.slug] = project.ID
+	}
+
+	slugs := func(projects []*Project) string {
+		var s []string
+		for _, p := range projects {
+			s = append(s, p.Slug)
+		}
+		return strings.Join(s, ",")
+	}
+
+	related, err := model.GetRelated(ids["rel-source"], 5)
+	if err != nil {
+		t.Fatalf("GetRelated failed: %v", err)
+	}
+	if got, want := slugs(related), "rel-both-services,rel-one-service,rel-same-client"; got != want {
+		t.Errorf("Expected shared-tag fallback %q, got %q", want, got)
+	}
+
+	if err := model.SetRelated(ids["rel-source"], []int{ids["rel-source"]}); err == nil {
+		t.Error("Expected error relating a project to itself, got nil")
+	}
+	if err := model.SetRelated(ids["rel-source"], []int{ids["rel-unrelated"], ids["rel-same-client"]}); err != nil {
+		t.Fatalf("SetRelated failed: %v", err)
+	}
+
+	related, _ = model.GetRelated(ids["rel-source"], 5)
+	if got, want := slugs(related), "rel-unrelated,rel-same-client"; got != want {
+		t.Errorf("Expected hand-picked %q, got %q", want, got)
+	}
+}
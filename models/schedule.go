@@ -0,0 +1,34 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Galleries and projects can publish and unpublish themselves at set times.
+// A scheduled time is cleared once it has been applied, so it fires only once
+// and a manual change made afterwards sticks.
+
+// ErrScheduleOrder is returned for an unpublish time that doesn't come after
+// the publish time, which would leave the item unpublished for good
+var ErrScheduleOrder = errors.New("unpublish time must be after publish time")
+
+// validateSchedule checks the two times are in a workable order
+func validateSchedule(publishAt, unpublishAt *time.Time) error {
+	if publishAt != nil && unpublishAt != nil && !unpublishAt.After(*publishAt) {
+		return ErrScheduleOrder
+	}
+	return nil
+}
+
+// scheduleSet is the shared SET clause for applying due schedules. When both
+// times have passed the unpublish, which is always the later one, wins.
+const scheduleSet = `
+	published = CASE
+		WHEN unpublish_at <= NOW() THEN FALSE
+		WHEN publish_at <= NOW() THEN TRUE
+		ELSE published END,
+	publish_at = CASE WHEN publish_at <= NOW() THEN NULL ELSE publish_at END,
+	unpublish_at = CASE WHEN unpublish_at <= NOW() THEN NULL ELSE unpublish_at END`
+
+const scheduleDue = `publish_at <= NOW() OR unpublish_at <= NOW()`
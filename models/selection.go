@@ -0,0 +1,235 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrSelectionLimit     = errors.New("selection limit reached")
+	ErrSelectionSubmitted = errors.New("selection already submitted")
+)
+
+// Selection is the set of favourites one visitor has picked from a client
+// gallery. It can be changed freely until it is submitted.
+type Selection struct {
+	ID          int
+	GalleryID   int
+	VisitorID   string
+	Name        string
+	Email       string
+	Comment     string
+	SubmittedAt *time.Time
+	CreatedAt   time.Time
+	Items       []*Media // favourited media, in gallery order
+}
+
+// Submitted reports whether the visitor has sent the selection in
+func (s *Selection) Submitted() bool {
+	return s.SubmittedAt != nil
+}
+
+// Has reports whether mediaID is one of the favourites
+func (s *Selection) Has(mediaID int) bool {
+	for _, m := range s.Items {
+		if m.ID == mediaID {
+			return true
+		}
+	}
+	return false
+}
+
+type SelectionModel struct {
+	DB *pgxpool.Pool
+}
+
+// GetForVisitor returns the visitor's selection for a gallery. A visitor
+// who hasn't favourited anything yet gets an empty, unsaved selection.
+func (s *SelectionModel) GetForVisitor(galleryID int, visitorID string) (*Selection, error) {
+	sel := &Selection{GalleryID: galleryID, VisitorID: visitorID}
+	err := s.DB.QueryRow(context.Background(), `
+		SELECT id, COALESCE(name, ''), COALESCE(email, ''), COALESCE(comment, ''), submitted_at, created_at
+		FROM gallery_selections WHERE gallery_id = $1 AND visitor_id = $2`, galleryID, visitorID).
+		Scan(&sel.ID, &sel.Name, &sel.Email, &sel.Comment, &sel.SubmittedAt, &sel.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return sel, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return sel, s.loadItems(sel)
+}
+
+// GetByID fetches a selection with its favourites
+func (s *SelectionModel) GetByID(id int) (*Selection, error) {
+	sel := &Selection{ID: id}
+	err := s.DB.QueryRow(context.Background(), `
+		SELECT gallery_id, visitor_id, COALESCE(name, ''), COALESCE(email, ''), COALESCE(comment, ''), submitted_at, created_at
+		FROM gallery_selections WHERE id = $1`, id).
+		Scan(&sel.GalleryID, &sel.VisitorID, &sel.Name, &sel.Email, &sel.Comment, &sel.SubmittedAt, &sel.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return sel, s.loadItems(sel)
+}
+
+// GetSubmitted returns the submitted selections of a gallery, newest first
+func (s *SelectionModel) GetSubmitted(galleryID int) ([]*Selection, error) {
+	rows, err := s.DB.Query(context.Background(), `
+		SELECT id, gallery_id, visitor_id, COALESCE(name, ''), COALESCE(email, ''), COALESCE(comment, ''), submitted_at, created_at
+		FROM gallery_selections
+		WHERE gallery_id = $1 AND submitted_at IS NOT NULL
+		ORDER BY submitted_at DESC`, galleryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var selections []*Selection
+	for rows.Next() {
+		sel := &Selection{}
+		if err := rows.Scan(&sel.ID, &sel.GalleryID, &sel.VisitorID, &sel.Name, &sel.Email, &sel.Comment, &sel.SubmittedAt, &sel.CreatedAt); err != nil {
+			return nil, err
+		}
+		selections = append(selections, sel)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.loadItems(selections...); err != nil {
+		return nil, err
+	}
+	return selections, nil
+}
+
+// ToggleFavourite adds mediaID to the visitor's selection, or removes it if
+// it's already there, and reports whether it is now a favourite. A limit
+// above zero caps how many favourites a selection can hold.
+func (s *SelectionModel) ToggleFavourite(galleryID int, visitorID string, mediaID, limit int) (bool, error) {
+	ctx := context.Background()
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var inGallery bool
+	err = tx.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM gallery_media WHERE gallery_id = $1 AND media_id = $2)`,
+		galleryID, mediaID).Scan(&inGallery)
+	if err != nil {
+		return false, err
+	}
+	if !inGallery {
+		return false, fmt.Errorf("media %d is not in gallery %d", mediaID, galleryID)
+	}
+
+	// The upsert also locks the selection row, so concurrent toggles can't
+	// both slip under the limit
+	var selectionID int
+	var submittedAt *time.Time
+	err = tx.QueryRow(ctx, `
+		INSERT INTO gallery_selections (gallery_id, visitor_id) VALUES ($1, $2)
+		ON CONFLICT (gallery_id, visitor_id) DO UPDATE SET visitor_id = EXCLUDED.visitor_id
+		RETURNING id, submitted_at`, galleryID, visitorID).Scan(&selectionID, &submittedAt)
+	if err != nil {
+		return false, err
+	}
+	if submittedAt != nil {
+		return false, ErrSelectionSubmitted
+	}
+
+	res, err := tx.Exec(ctx,
+		`DELETE FROM selection_items WHERE selection_id = $1 AND media_id = $2`, selectionID, mediaID)
+	if err != nil {
+		return false, err
+	}
+	if res.RowsAffected() > 0 {
+		return false, tx.Commit(ctx)
+	}
+
+	if limit > 0 {
+		var count int
+		err = tx.QueryRow(ctx,
+			`SELECT COUNT(*) FROM selection_items WHERE selection_id = $1`, selectionID).Scan(&count)
+		if err != nil {
+			return false, err
+		}
+		if count >= limit {
+			return false, ErrSelectionLimit
+		}
+	}
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO selection_items (selection_id, media_id) VALUES ($1, $2)`, selectionID, mediaID)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+// Submit sends the visitor's selection in with their details. Only a
+// selection with at least one favourite can be submitted, and only once.
+func (s *SelectionModel) Submit(galleryID int, visitorID, name, email, comment string) error {
+	res, err := s.DB.Exec(context.Background(), `
+		UPDATE gallery_selections
+		SET name = $3, email = $4, comment = $5, submitted_at = NOW()
+		WHERE gallery_id = $1 AND visitor_id = $2 AND submitted_at IS NULL
+		  AND EXISTS (SELECT 1 FROM selection_items WHERE selection_id = gallery_selections.id)`,
+		galleryID, visitorID, name, email, comment)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("no open selection with favourites found for gallery %d", galleryID)
+	}
+	return nil
+}
+
+// loadItems fills in the favourites of selections with one query
+func (s *SelectionModel) loadItems(selections ...*Selection) error {
+	if len(selections) == 0 {
+		return nil
+	}
+
+	byID := make(map[int]*Selection, len(selections))
+	ids := make([]int, len(selections))
+	for i, sel := range selections {
+		sel.Items = nil
+		byID[sel.ID] = sel
+		ids[i] = sel.ID
+	}
+
+	rows, err := s.DB.Query(context.Background(), `
+		SELECT si.selection_id, m.id, m.file_name, m.full_url, m.thumbnail_url
+		FROM selection_items si
+		JOIN gallery_selections gs ON gs.id = si.selection_id
+		JOIN media m ON m.id = si.media_id
+		LEFT JOIN gallery_media gm ON gm.media_id = m.id AND gm.gallery_id = gs.gallery_id
+		WHERE si.selection_id = ANY($1)
+		ORDER BY gm.position ASC, m.id ASC`, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var selectionID int
+		m := &Media{}
+		if err := rows.Scan(&selectionID, &m.ID, &m.FileName, &m.FullURL, &m.ThumbnailURL); err != nil {
+			return err
+		}
+		sel := byID[selectionID]
+		sel.Items = append(sel.Items, m)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSelectionModel_ToggleAndSubmit(t *testing.T) {
+	db := setupTestDB(t)
+	galleries := &GalleryModel{DB: db}
+	media := &MediaModel{DB: db}
+	model := &SelectionModel{DB: db}
+
+	galleryID, err := galleries.CreateAndReturnID("Proofs", "", "proofs")
+	if err != nil {
+		t.Fatalf("create gallery failed: %v", err)
+	}
+
+	var ids []int
+	for _, name := range []string{"a.jpg", "b.jpg", "c.jpg"} {
+		id, err := media.InsertAndReturnID(name, "full_"+name, "thumb_"+name)
+		if err != nil {
+			t.Fatalf("insert media failed: %v", err)
+		}
+		if err := galleries.Media().Append(galleryID, id); err != nil {
+			t.Fatalf("attach media failed: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	stray, _ := media.InsertAndReturnID("stray.jpg", "full.jpg", "thumb.jpg")
+
+	cases := []struct {
+		name     string
+		mediaID  int
+		wantFav  bool
+		wantErr  error
+		anyError bool
+	}{
+		{name: "✅ favourite first", mediaID: ids[0], wantFav: true},
+		{name: "✅ favourite second", mediaID: ids[1], wantFav: true},
+		{name: "❌ over the limit", mediaID: ids[2], wantErr: ErrSelectionLimit},
+		{name: "✅ unfavourite first", mediaID: ids[0], wantFav: false},
+		{name: "✅ favourite third", mediaID: ids[2], wantFav: true},
+		{name: "❌ media outside gallery", mediaID: stray, anyError: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fav, err := model.ToggleFavourite(galleryID, "visitor-1", tc.mediaID, 2)
+			switch {
+			case tc.wantErr != nil:
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("Expected %v, got %v", tc.wantErr, err)
+				}
+			case tc.anyError:
+				if err == nil {
+					t.Fatal("Expected error, got nil")
+				}
+			case err != nil:
+				t.Fatalf("Unexpected error: %v", err)
+			case fav != tc.wantFav:
+				t.Errorf("Expected favourite %v, got %v", tc.wantFav, fav)
+			}
+		})
+	}
+
+	sel, err := model.GetForVisitor(galleryID, "visitor-1")
+	if err != nil {
+		t.Fatalf("GetForVisitor failed: %v", err)
+	}
+	if len(sel.Items) != 2 || sel.Items[0].ID != ids[1] || sel.Items[1].ID != ids[2] {
+		t.Fatalf("Expected b.jpg and c.jpg in gallery order, got %v", sel.Items)
+	}
+
+	// Another visitor has their own, empty selection and can't submit it
+	other, _ := model.GetForVisitor(galleryID, "visitor-2")
+	if other.ID != 0 || len(other.Items) != 0 {
+		t.Errorf("Expected empty selection for a new visitor, got %+v", other)
+	}
+	if err := model.Submit(galleryID, "visitor-2", "Bob", "", ""); err == nil {
+		t.Error("Expected error submitting an empty selection, got nil")
+	}
+
+	if err := model.Submit(galleryID, "visitor-1", "Ann", "ann@example.com", "Warm edit please"); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	if _, err := model.ToggleFavourite(galleryID, "visitor-1", ids[0], 2); !errors.Is(err, ErrSelectionSubmitted) {
+		t.Errorf("Expected ErrSelectionSubmitted after submitting, got %v", err)
+	}
+
+	model.ToggleFavourite(galleryID, "visitor-2", ids[0], 2)
+	if err := model.Submit(galleryID, "visitor-2", "Bob", "", ""); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+
+	// Newest first, each with only its own favourites
+	submitted, err := model.GetSubmitted(galleryID)
+	if err != nil {
+		t.Fatalf("GetSubmitted failed: %v", err)
+	}
+	if len(submitted) != 2 {
+		t.Fatalf("Expected 2 submitted selections, got %d", len(submitted))
+	}
+	if submitted[0].Name != "Bob" || len(submitted[0].Items) != 1 || submitted[0].Items[0].ID != ids[0] {
+		t.Errorf("Expected Bob's selection of a.jpg, got %+v", submitted[0])
+	}
+	if submitted[1].Name != "Ann" || len(submitted[1].Items) != 2 || submitted[1].Items[0].ID != ids[1] {
+		t.Errorf("Expected Ann's selection of 2 images in gallery order, got %+v", submitted[1])
+	}
+}
@@ -81,6 +81,223 @@ func CreateTablesIfNotExist(db *pgxpool.Pool) error {
 			message TEXT NOT NULL,
 			created_at TIMESTAMP DEFAULT NOW()
 		);`,
+
+		`CREATE TABLE IF NOT EXISTS media_versions (
+			id SERIAL PRIMARY KEY,
+			media_id INTEGER REFERENCES media(id) ON DELETE CASCADE,
+			file_name TEXT NOT NULL,
+			full_url TEXT NOT NULL,
+			thumbnail_url TEXT,
+			original_key TEXT,
+			blurhash TEXT,
+			dominant_color TEXT,
+			created_at TIMESTAMP DEFAULT NOW()
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS gallery_selections (
+			id SERIAL PRIMARY KEY,
+			gallery_id INTEGER REFERENCES galleries(id) ON DELETE CASCADE,
+			visitor_id TEXT NOT NULL,
+			name TEXT,
+			email TEXT,
+			comment TEXT,
+			submitted_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT NOW(),
+			UNIQUE (gallery_id, visitor_id)
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS selection_items (
+			selection_id INTEGER REFERENCES gallery_selections(id) ON DELETE CASCADE,
+			media_id INTEGER REFERENCES media(id) ON DELETE CASCADE,
+			created_at TIMESTAMP DEFAULT NOW(),
+			PRIMARY KEY (selection_id, media_id)
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS share_links (
+			id SERIAL PRIMARY KEY,
+			token TEXT UNIQUE NOT NULL,
+			gallery_id INTEGER REFERENCES galleries(id) ON DELETE CASCADE,
+			project_id INTEGER REFERENCES projects(id) ON DELETE CASCADE,
+			label TEXT,
+			expires_at TIMESTAMPTZ,
+			max_views INTEGER NOT NULL DEFAULT 0,
+			view_count INTEGER NOT NULL DEFAULT 0,
+			revoked_at TIMESTAMPTZ,
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			CHECK ((gallery_id IS NULL) <> (project_id IS NULL))
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS categories (
+			id SERIAL PRIMARY KEY,
+			name TEXT NOT NULL,
+			slug TEXT UNIQUE NOT NULL,
+			description TEXT,
+			position INTEGER DEFAULT 0,
+			created_at TIMESTAMP DEFAULT NOW()
+		);`,
+
+		// Seed the starting categories on first run only. The marker setting
+		// keeps categories an admin deleted from coming back on restart.
+		`WITH marker AS (
+			INSERT INTO settings (key, value) VALUES ('categories_seeded', 'true')
+			ON CONFLICT (key) DO NOTHING
+			RETURNING key
+		)
+		INSERT INTO categories (name, slug, position)
+		SELECT defaults.* FROM (VALUES
+			('Portraits', 'portraits', 0),
+			('Branding', 'branding', 1),
+			('Fashion', 'fashion', 2),
+			('Events', 'events', 3)
+		) AS defaults (name, slug, position)
+		WHERE EXISTS (SELECT 1 FROM marker) AND NOT EXISTS (SELECT 1 FROM categories);`,
+
+		`CREATE TABLE IF NOT EXISTS gallery_categories (
+			gallery_id INTEGER REFERENCES galleries(id) ON DELETE CASCADE,
+			category_id INTEGER REFERENCES categories(id) ON DELETE CASCADE,
+			PRIMARY KEY (gallery_id, category_id)
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS slug_history (
+			id SERIAL PRIMARY KEY,
+			gallery_id INTEGER REFERENCES galleries(id) ON DELETE CASCADE,
+			project_id INTEGER REFERENCES projects(id) ON DELETE CASCADE,
+			slug TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT NOW(),
+			CHECK ((gallery_id IS NULL) <> (project_id IS NULL))
+		);`,
+
+		// An old slug redirects to one gallery and one project at most
+		`CREATE UNIQUE INDEX IF NOT EXISTS slug_history_gallery_slug ON slug_history (slug) WHERE gallery_id IS NOT NULL;`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS slug_history_project_slug ON slug_history (slug) WHERE project_id IS NOT NULL;`,
+
+		`CREATE TABLE IF NOT EXISTS project_credits (
+			id SERIAL PRIMARY KEY,
+			project_id INTEGER REFERENCES projects(id) ON DELETE CASCADE,
+			role TEXT NOT NULL DEFAULT '',
+			name TEXT NOT NULL,
+			position INTEGER NOT NULL DEFAULT 0
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS project_links (
+			id SERIAL PRIMARY KEY,
+			project_id INTEGER REFERENCES projects(id) ON DELETE CASCADE,
+			label TEXT NOT NULL,
+			url TEXT NOT NULL,
+			position INTEGER NOT NULL DEFAULT 0
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS project_galleries (
+			project_id INTEGER REFERENCES projects(id) ON DELETE CASCADE,
+			gallery_id INTEGER REFERENCES galleries(id) ON DELETE CASCADE,
+			position INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (project_id, gallery_id)
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS related_projects (
+			project_id INTEGER REFERENCES projects(id) ON DELETE CASCADE,
+			related_id INTEGER REFERENCES projects(id) ON DELETE CASCADE,
+			position INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (project_id, related_id),
+			CHECK (project_id <> related_id)
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS outbox (
+			id SERIAL PRIMARY KEY,
+			from_addr TEXT NOT NULL,
+			to_addr TEXT NOT NULL,
+			subject TEXT NOT NULL DEFAULT '',
+			html_body TEXT NOT NULL,
+			status TEXT NOT NULL DEFAULT 'pending',
+			attempts INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT,
+			next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			sent_at TIMESTAMPTZ,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		`CREATE INDEX IF NOT EXISTS outbox_due_idx ON outbox (status, next_attempt_at);`,
+
+		`CREATE TABLE IF NOT EXISTS contact_messages (
+			id SERIAL PRIMARY KEY,
+			contact_id INTEGER NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+			user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			subject TEXT NOT NULL DEFAULT '',
+			body TEXT NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT NOW()
+		);`,
+
+		// Columns added after the initial schema
+		`ALTER TABLE media ADD COLUMN IF NOT EXISTS original_key TEXT;`,
+		`ALTER TABLE galleries ADD COLUMN IF NOT EXISTS watermark_opt_out BOOLEAN DEFAULT FALSE;`,
+		`ALTER TABLE media ADD COLUMN IF NOT EXISTS blurhash TEXT;`,
+		`ALTER TABLE media ADD COLUMN IF NOT EXISTS dominant_color TEXT;`,
+		`ALTER TABLE galleries ADD COLUMN IF NOT EXISTS client_access BOOLEAN DEFAULT FALSE;`,
+		`ALTER TABLE galleries ADD COLUMN IF NOT EXISTS password_hash TEXT;`,
+		`ALTER TABLE galleries ADD COLUMN IF NOT EXISTS selection_limit INTEGER DEFAULT 0;`,
+		`ALTER TABLE galleries ADD COLUMN IF NOT EXISTS publish_at TIMESTAMPTZ;`,
+		`ALTER TABLE galleries ADD COLUMN IF NOT EXISTS unpublish_at TIMESTAMPTZ;`,
+		`ALTER TABLE projects ADD COLUMN IF NOT EXISTS publish_at TIMESTAMPTZ;`,
+		`ALTER TABLE projects ADD COLUMN IF NOT EXISTS unpublish_at TIMESTAMPTZ;`,
+		`ALTER TABLE galleries ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;`,
+		`ALTER TABLE projects ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;`,
+		`ALTER TABLE media ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;`,
+		`ALTER TABLE galleries ADD COLUMN IF NOT EXISTS parent_id INTEGER REFERENCES galleries(id) ON DELETE SET NULL;`,
+		`ALTER TABLE projects ADD COLUMN IF NOT EXISTS body TEXT;`,
+		`ALTER TABLE projects ADD COLUMN IF NOT EXISTS body_html TEXT;`,
+		`ALTER TABLE projects ADD COLUMN IF NOT EXISTS body_media_stamp TEXT;`,
+		`ALTER TABLE projects ADD COLUMN IF NOT EXISTS client TEXT;`,
+		`ALTER TABLE projects ADD COLUMN IF NOT EXISTS shoot_date DATE;`,
+		`ALTER TABLE projects ADD COLUMN IF NOT EXISTS location TEXT;`,
+		`ALTER TABLE projects ADD COLUMN IF NOT EXISTS services TEXT[] DEFAULT '{}';`,
+		`ALTER TABLE galleries ADD COLUMN IF NOT EXISTS position INTEGER NOT NULL DEFAULT 0;`,
+		`ALTER TABLE projects ADD COLUMN IF NOT EXISTS position INTEGER NOT NULL DEFAULT 0;`,
+		`ALTER TABLE contacts ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'new';`,
+		`ALTER TABLE contacts ADD COLUMN IF NOT EXISTS assigned_to INTEGER REFERENCES users(id) ON DELETE SET NULL;`,
+		`ALTER TABLE contacts ADD COLUMN IF NOT EXISTS notes TEXT NOT NULL DEFAULT '';`,
+		`ALTER TABLE contacts ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP DEFAULT NOW();`,
+		`ALTER TABLE outbox ADD COLUMN IF NOT EXISTS text_body TEXT;`,
+		`ALTER TABLE outbox ADD COLUMN IF NOT EXISTS kind TEXT;`,
+		`CREATE INDEX IF NOT EXISTS outbox_recent_idx ON outbox (kind, LOWER(to_addr), created_at);`,
+		`ALTER TABLE contacts ADD COLUMN IF NOT EXISTS spam_reason TEXT;`,
+		`ALTER TABLE contact_messages ADD COLUMN IF NOT EXISTS email_id INTEGER REFERENCES outbox(id) ON DELETE SET NULL;`,
+
+		// Share link times were zoneless, so expiry was compared against NOW()
+		// in whatever zone the app happened to write them. Older values are
+		// read in the database's zone.
+		`DO $$ BEGIN
+			IF EXISTS (SELECT 1 FROM information_schema.columns
+			           WHERE table_name = 'share_links' AND column_name = 'expires_at'
+			             AND data_type = 'timestamp without time zone') THEN
+				ALTER TABLE share_links
+					ALTER COLUMN expires_at TYPE TIMESTAMPTZ,
+					ALTER COLUMN revoked_at TYPE TIMESTAMPTZ,
+					ALTER COLUMN created_at TYPE TIMESTAMPTZ;
+			END IF;
+		END $$;`,
+
+		// Media positions are dense and unique within a gallery or project;
+		// see MediaCollection. Renumber older rows before enforcing it. The
+		// constraint is deferred so a reorder can pass through duplicates.
+		`UPDATE gallery_media t SET position = r.pos
+		FROM (SELECT gallery_id, media_id,
+		             ROW_NUMBER() OVER (PARTITION BY gallery_id ORDER BY position, media_id) - 1 AS pos
+		      FROM gallery_media) r
+		WHERE t.gallery_id = r.gallery_id AND t.media_id = r.media_id AND t.position IS DISTINCT FROM r.pos;`,
+		`UPDATE project_media t SET position = r.pos
+		FROM (SELECT project_id, media_id,
+		             ROW_NUMBER() OVER (PARTITION BY project_id ORDER BY position, media_id) - 1 AS pos
+		      FROM project_media) r
+		WHERE t.project_id = r.project_id AND t.media_id = r.media_id AND t.position IS DISTINCT FROM r.pos;`,
+		`DO $$ BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'gallery_media_position_key') THEN
+				ALTER TABLE gallery_media ADD CONSTRAINT gallery_media_position_key
+					UNIQUE (gallery_id, position) DEFERRABLE INITIALLY DEFERRED;
+			END IF;
+			IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'project_media_position_key') THEN
+				ALTER TABLE project_media ADD CONSTRAINT project_media_position_key
+					UNIQUE (project_id, position) DEFERRABLE INITIALLY DEFERRED;
+			END IF;
+		END $$;`,
 	}
 
 	for _, stmt := range statements {
@@ -22,8 +22,13 @@ func setupTestDB(t *testing.T) *pgxpool.Pool {
 		t.Fatalf("❌ Failed to connect to DB: %v", err)
 	}
 
+	// Bring the schema up to date so new columns exist before truncating
+	if err := CreateTablesIfNotExist(db); err != nil {
+		t.Fatalf("❌ Failed to migrate test DB: %v", err)
+	}
+
 	_, err = db.Exec(context.Background(), `
-        TRUNCATE users, media, gallery_media, project_media RESTART IDENTITY CASCADE;
+        TRUNCATE users, media, gallery_media, project_media, categories, settings, contacts, outbox RESTART IDENTITY CASCADE;
     `)
 	if err != nil {
 		t.Fatalf("❌ Failed to truncate test tables: %v", err)
@@ -0,0 +1,209 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ShareLink lets someone without an account view an unpublished gallery or
+// project. Links can expire, be limited to a number of views, or be revoked.
+type ShareLink struct {
+	ID        int
+	Token     string
+	GalleryID *int
+	ProjectID *int
+	Label     string
+	ExpiresAt *time.Time
+	MaxViews  int // 0 is unlimited
+	ViewCount int
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+// Status describes whether the link still works
+func (l *ShareLink) Status() string {
+	switch {
+	case l.RevokedAt != nil:
+		return "revoked"
+	case l.ExpiresAt != nil && !l.ExpiresAt.After(time.Now()):
+		return "expired"
+	case l.MaxViews > 0 && l.ViewCount >= l.MaxViews:
+		return "used up"
+	default:
+		return "active"
+	}
+}
+
+type ShareLinkModel struct {
+	DB *pgxpool.Pool
+}
+
+// Create issues a new share link for a gallery or a project; exactly one of
+// galleryID and projectID must be set. A nil expiresAt never expires.
+func (s *ShareLinkModel) Create(galleryID, projectID int, label string, expiresAt *time.Time, maxViews int) (*ShareLink, error) {
+	if (galleryID > 0) == (projectID > 0) {
+		return nil, fmt.Errorf("share link needs either a gallery or a project")
+	}
+	if maxViews < 0 {
+		return nil, fmt.Errorf("max views cannot be negative")
+	}
+
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+
+	link := &ShareLink{
+		Token:     hex.EncodeToString(buf),
+		Label:     label,
+		ExpiresAt: expiresAt,
+		MaxViews:  maxViews,
+	}
+	if galleryID > 0 {
+		link.GalleryID = &galleryID
+	} else {
+		link.ProjectID = &projectID
+	}
+
+	err := s.DB.QueryRow(context.Background(), `
+		INSERT INTO share_links (token, gallery_id, project_id, label, expires_at, max_views)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`,
+		link.Token, link.GalleryID, link.ProjectID, label, expiresAt, maxViews).Scan(&link.ID, &link.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// GetForGallery lists a gallery's share links, newest first
+func (s *ShareLinkModel) GetForGallery(galleryID int) ([]*ShareLink, error) {
+	return s.list(`WHERE gallery_id = $1`, galleryID)
+}
+
+// GetForProject lists a project's share links, newest first
+func (s *ShareLinkModel) GetForProject(projectID int) ([]*ShareLink, error) {
+	return s.list(`WHERE project_id = $1`, projectID)
+}
+
+func (s *ShareLinkModel) GetByID(id int) (*ShareLink, error) {
+	links, err := s.list(`WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(links) == 0 {
+		return nil, fmt.Errorf("no share link found with ID %d", id)
+	}
+	return links[0], nil
+}
+
+// Revoke stops a share link from working
+func (s *ShareLinkModel) Revoke(id int) error {
+	res, err := s.DB.Exec(context.Background(),
+		`UPDATE share_links SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("no active share link found with ID %d", id)
+	}
+	return nil
+}
+
+// shareLinkOwner is the share_links column of each kind of shared content
+var shareLinkOwner = map[string]string{
+	"gallery": "gallery_id",
+	"project": "project_id",
+}
+
+// RedeemForGallery counts a view of a gallery through token and returns the
+// link, or nil if the link didn't allow it
+func (s *ShareLinkModel) RedeemForGallery(token string, galleryID int) (*ShareLink, error) {
+	return s.Redeem("gallery", galleryID, token)
+}
+
+// RedeemForProject counts a view of a project through token and returns the
+// link, or nil if the link didn't allow it
+func (s *ShareLinkModel) RedeemForProject(token string, projectID int) (*ShareLink, error) {
+	return s.Redeem("project", projectID, token)
+}
+
+// Redeem counts a view through token of the gallery or project id, where
+// kind is "gallery" or "project", and returns the link with its ID and
+// expiry set, or nil if the link didn't allow it. It checks and counts in one
+// statement, so concurrent views can't push a link past its limit.
+func (s *ShareLinkModel) Redeem(kind string, id int, token string) (*ShareLink, error) {
+	column, ok := shareLinkOwner[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown share link kind %q", kind)
+	}
+	if token == "" {
+		return nil, nil
+	}
+
+	link := &ShareLink{Token: token}
+	err := s.DB.QueryRow(context.Background(), `
+		UPDATE share_links SET view_count = view_count + 1
+		WHERE token = $1 AND `+column+` = $2
+		  AND revoked_at IS NULL
+		  AND (expires_at IS NULL OR expires_at > NOW())
+		  AND (max_views = 0 OR view_count < max_views)
+		RETURNING id, expires_at`, token, id).Scan(&link.ID, &link.ExpiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// IsActive reports whether an already redeemed link still opens the gallery
+// or project id: it must not be revoked or expired. It doesn't count a view,
+// so visitors who used one keep it even once the link is used up.
+func (s *ShareLinkModel) IsActive(kind string, id, linkID int) (bool, error) {
+	column, ok := shareLinkOwner[kind]
+	if !ok {
+		return false, fmt.Errorf("unknown share link kind %q", kind)
+	}
+
+	var active bool
+	err := s.DB.QueryRow(context.Background(), `
+		SELECT EXISTS (
+			SELECT 1 FROM share_links
+			WHERE id = $1 AND `+column+` = $2
+			  AND revoked_at IS NULL
+			  AND (expires_at IS NULL OR expires_at > NOW())
+		)`, linkID, id).Scan(&active)
+	return active, err
+}
+
+func (s *ShareLinkModel) list(where string, arg any) ([]*ShareLink, error) {
+	rows, err := s.DB.Query(context.Background(), `
+		SELECT id, token, gallery_id, project_id, COALESCE(label, ''), expires_at, max_views, view_count, revoked_at, created_at
+		FROM share_links `+where+`
+		ORDER BY created_at DESC, id DESC`, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []*ShareLink
+	for rows.Next() {
+		l := &ShareLink{}
+		err := rows.Scan(&l.ID, &l.Token, &l.GalleryID, &l.ProjectID, &l.Label, &l.ExpiresAt,
+			&l.MaxViews, &l.ViewCount, &l.RevokedAt, &l.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
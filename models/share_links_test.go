@@ -0,0 +1,106 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestShareLinkModel_Redeem(t *testing.T) {
+	db := setupTestDB(t)
+	galleries := &GalleryModel{DB: db}
+	model := &ShareLinkModel{DB: db}
+
+	galleryID, err := galleries.CreateAndReturnID("Draft", "", "draft")
+	if err != nil {
+		t.Fatalf("create gallery failed: %v", err)
+	}
+	otherID, _ := galleries.CreateAndReturnID("Other", "", "other")
+
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	open, err := model.Create(galleryID, 0, "open", nil, 0)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	limited, _ := model.Create(galleryID, 0, "two views", &future, 2)
+	expired, _ := model.Create(galleryID, 0, "expired", &past, 0)
+	revoked, _ := model.Create(galleryID, 0, "revoked", nil, 0)
+	if err := model.Revoke(revoked.ID); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+
+	cases := []struct {
+		name      string
+		token     string
+		galleryID int
+		want      bool
+	}{
+		{"✅ open link", open.Token, galleryID, true},
+		{"✅ limited link, first view", limited.Token, galleryID, true},
+		{"✅ limited link, second view", limited.Token, galleryID, true},
+		{"❌ limited link, used up", limited.Token, galleryID, false},
+		{"❌ expired link", expired.Token, galleryID, false},
+		{"❌ revoked link", revoked.Token, galleryID, false},
+		{"❌ link for another gallery", open.Token, otherID, false},
+		{"❌ unknown token", "nope", galleryID, false},
+		{"❌ empty token", "", galleryID, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			link, err := model.RedeemForGallery(tc.token, tc.galleryID)
+			if err != nil {
+				t.Fatalf("RedeemForGallery failed: %v", err)
+			}
+			if (link != nil) != tc.want {
+				t.Errorf("Expected %v, got %+v", tc.want, link)
+			}
+		})
+	}
+
+	// Visitors who already redeemed a link keep it until it's revoked or
+	// expires, without counting more views
+	active := []struct {
+		name      string
+		link      *ShareLink
+		galleryID int
+		want      bool
+	}{
+		{"✅ open link", open, galleryID, true},
+		{"✅ used up link", limited, galleryID, true},
+		{"❌ expired link", expired, galleryID, false},
+		{"❌ revoked link", revoked, galleryID, false},
+		{"❌ link for another gallery", open, otherID, false},
+	}
+	for _, tc := range active {
+		t.Run("IsActive "+tc.name, func(t *testing.T) {
+			ok, err := model.IsActive("gallery", tc.galleryID, tc.link.ID)
+			if err != nil {
+				t.Fatalf("IsActive failed: %v", err)
+			}
+			if ok != tc.want {
+				t.Errorf("Expected %v, got %v", tc.want, ok)
+			}
+		})
+	}
+
+	links, err := model.GetForGallery(galleryID)
+	if err != nil {
+		t.Fatalf("GetForGallery failed: %v", err)
+	}
+	statuses := map[string]string{}
+	for _, l := range links {
+		statuses[l.Label] = l.Status()
+	}
+	want := map[string]string{"open": "active", "two views": "used up", "expired": "expired", "revoked": "revoked"}
+	for label, status := range want {
+		if statuses[label] != status {
+			t.Errorf("Expected %q to be %q, got %q", label, status, statuses[label])
+		}
+	}
+
+	if _, err := model.Create(galleryID, 1, "both", nil, 0); err == nil {
+		t.Error("Expected error for a link to both a gallery and a project, got nil")
+	}
+}
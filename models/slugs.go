@@ -0,0 +1,97 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// querier is the part of a pool or transaction the slug helpers need
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// maxSlugSuffix bounds the search for a free slug
+const maxSlugSuffix = 1000
+
+// historyColumn is the slug_history column that points back at table
+var historyColumn = map[string]string{
+	"galleries": "gallery_id",
+	"projects":  "project_id",
+}
+
+// uniqueSlug returns base if it's free in table, or base with the first free
+// "-2", "-3"... suffix. Trashed rows still hold their slug, and another row's
+// old slugs stay reserved so their redirects keep working. ownerID is the row
+// being renamed, which may keep or take back its own slugs; use 0 for new rows.
+func uniqueSlug(ctx context.Context, q querier, table, base string, ownerID int) (string, error) {
+	col := historyColumn[table]
+	slug := base
+	for n := 2; n <= maxSlugSuffix; n++ {
+		var taken bool
+		err := q.QueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM `+table+` WHERE slug = $1 AND id <> $2)
+			    OR EXISTS (SELECT 1 FROM slug_history WHERE slug = $1 AND `+col+` <> $2)`,
+			slug, ownerID).Scan(&taken)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return slug, nil
+		}
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+	return "", fmt.Errorf("no free slug for %q", base)
+}
+
+// renameSlug gives row id of table a free slug based on base and records the
+// slug it had before, so old links can be redirected
+func renameSlug(ctx context.Context, tx pgx.Tx, table string, id int, base string) error {
+	col := historyColumn[table]
+
+	var oldSlug string
+	err := tx.QueryRow(ctx,
+		`SELECT slug FROM `+table+` WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id).Scan(&oldSlug)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("no %s found with ID %d", tableNoun(table), id)
+	}
+	if err != nil {
+		return err
+	}
+
+	slug, err := uniqueSlug(ctx, tx, table, base, id)
+	if err != nil {
+		return err
+	}
+	if slug == oldSlug {
+		return nil
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE `+table+` SET slug = $1 WHERE id = $2`, slug, id); err != nil {
+		return err
+	}
+
+	// A slug taken back is current again, not history
+	_, err = tx.Exec(ctx, `DELETE FROM slug_history WHERE `+col+` = $1 AND slug = $2`, id, slug)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO slug_history (`+col+`, slug) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`, id, oldSlug)
+	return err
+}
+
+// currentSlug follows an old slug of table to the live row's current slug
+func currentSlug(q querier, table, oldSlug string) (string, error) {
+	col := historyColumn[table]
+
+	var slug string
+	err := q.QueryRow(context.Background(), `
+		SELECT t.slug FROM slug_history h
+		JOIN `+table+` t ON t.id = h.`+col+`
+		WHERE h.slug = $1 AND t.deleted_at IS NULL`, oldSlug).Scan(&slug)
+	return slug, err
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestGalleryModel_SlugHistory(t *testing.T) {
+	db := setupTestDB(t)
+	model := &GalleryModel{DB: db}
+
+	id, _ := model.CreateAndReturnID("Smith Wedding", "", "smith-wedding")
+	otherID, _ := model.CreateAndReturnID("Jones Wedding", "", "jones-wedding")
+
+	if err := model.Update(id, "Smith & Co Wedding", "", "smith-co-wedding"); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	cases := []struct {
+		name     string
+		oldSlug  string
+		wantSlug string
+		wantErr  error
+	}{
+		{"✅ old slug points at the new one", "smith-wedding", "smith-co-wedding", nil},
+		{"❌ current slug has no history", "smith-co-wedding", "", pgx.ErrNoRows},
+		{"❌ unknown slug", "never-existed", "", pgx.ErrNoRows},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := model.CurrentSlug(tc.oldSlug)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("CurrentSlug() error = %v, want %v", err, tc.wantErr)
+			}
+			if got != tc.wantSlug {
+				t.Errorf("Expected %q, got %q", tc.wantSlug, got)
+			}
+		})
+	}
+
+	// Another gallery can't take over a slug that still redirects
+	if err := model.Update(otherID, "Smith Wedding", "", "smith-wedding"); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	other, _ := model.GetByID(otherID)
+	if other.Slug != "smith-wedding-2" {
+		t.Errorf("Expected smith-wedding-2, got %q", other.Slug)
+	}
+
+	// Renaming back reclaims the old slug and redirects the newer one
+	if err := model.Update(id, "Smith Wedding", "", "smith-wedding"); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if g, _ := model.GetByID(id); g.Slug != "smith-wedding" {
+		t.Errorf("Expected smith-wedding, got %q", g.Slug)
+	}
+	if got, err := model.CurrentSlug("smith-co-wedding"); err != nil || got != "smith-wedding" {
+		t.Errorf("Expected smith-co-wedding to redirect to smith-wedding, got %q (%v)", got, err)
+	}
+
+	// Trashed galleries don't redirect
+	model.Delete(id)
+	if _, err := model.CurrentSlug("smith-co-wedding"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("Expected no redirect for a trashed gallery, got %v", err)
+	}
+}
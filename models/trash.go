@@ -0,0 +1,109 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Galleries, projects and media are soft deleted: Delete stamps deleted_at
+// and every read skips stamped rows. Join rows are left alone, so a restored
+// item comes back with its links, positions and cover. Items are removed for
+// good by Purge, or by PurgeTrashedBefore once the retention period is over.
+
+// TrashedItem is a soft-deleted gallery, project or media item
+type TrashedItem struct {
+	ID           int
+	Title        string // media items use their file name
+	ThumbnailURL *string
+	DeletedAt    time.Time
+}
+
+// StoredFile is an S3 object pair backing a media item or one of its
+// versions, returned by purges so the caller can remove it from storage
+type StoredFile struct {
+	FileName    string
+	OriginalKey string
+}
+
+// trashRow soft deletes a row of table
+func trashRow(db *pgxpool.Pool, table string, id int) error {
+	res, err := db.Exec(context.Background(),
+		`UPDATE `+table+` SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL`, id)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("no %s found with ID %d", tableNoun(table), id)
+	}
+	return nil
+}
+
+// restoreRow takes a row of table back out of the trash
+func restoreRow(db *pgxpool.Pool, table string, id int) error {
+	res, err := db.Exec(context.Background(),
+		`UPDATE `+table+` SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL`, id)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("no trashed %s found with ID %d", tableNoun(table), id)
+	}
+	return nil
+}
+
+// purgeRow permanently deletes a trashed row of table
+func purgeRow(db *pgxpool.Pool, table string, id int) error {
+	res, err := db.Exec(context.Background(),
+		`DELETE FROM `+table+` WHERE id = $1 AND deleted_at IS NOT NULL`, id)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("no trashed %s found with ID %d", tableNoun(table), id)
+	}
+	return nil
+}
+
+// purgeRowsBefore permanently deletes rows of table trashed before cutoff
+func purgeRowsBefore(db *pgxpool.Pool, table string, cutoff time.Time) (int64, error) {
+	res, err := db.Exec(context.Background(),
+		`DELETE FROM `+table+` WHERE deleted_at < $1`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
+
+// trashedRows lists the trashed rows of table, most recently deleted first.
+// The query must select id, title, thumbnail URL and deleted_at.
+func trashedRows(db *pgxpool.Pool, query string) ([]*TrashedItem, error) {
+	rows, err := db.Query(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*TrashedItem
+	for rows.Next() {
+		item := &TrashedItem{}
+		if err := rows.Scan(&item.ID, &item.Title, &item.ThumbnailURL, &item.DeletedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func tableNoun(table string) string {
+	switch table {
+	case "galleries":
+		return "gallery"
+	case "projects":
+		return "project"
+	default:
+		return table
+	}
+}
@@ -0,0 +1,110 @@
+// ===========================
+// 🌫️ BlurHash Placeholders
+// ===========================
+// Paints <canvas data-blurhash="..."> elements with a decoded BlurHash and
+// fades in the real image (img[data-lqip]) once it has loaded.
+
+const BLURHASH_CHARS =
+  "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~";
+
+function decode83(str) {
+  let value = 0;
+  for (const c of str) {
+    value = value * 83 + BLURHASH_CHARS.indexOf(c);
+  }
+  return value;
+}
+
+function sRGBToLinear(v) {
+  const f = v / 255;
+  return f <= 0.04045 ? f / 12.92 : Math.pow((f + 0.055) / 1.055, 2.4);
+}
+
+function linearToSRGB(v) {
+  const f = Math.max(0, Math.min(1, v));
+  return f <= 0.0031308
+    ? Math.round(f * 12.92 * 255)
+    : Math.round((1.055 * Math.pow(f, 1 / 2.4) - 0.055) * 255);
+}
+
+function signPow(v, exp) {
+  return Math.sign(v) * Math.pow(Math.abs(v), exp);
+}
+
+function decodeBlurHash(hash, width, height) {
+  const sizeFlag = decode83(hash[0]);
+  const numY = Math.floor(sizeFlag / 9) + 1;
+  const numX = (sizeFlag % 9) + 1;
+  const maxValue = (decode83(hash[1]) + 1) / 166;
+
+  const colors = [];
+  const dc = decode83(hash.substring(2, 6));
+  colors.push([
+    sRGBToLinear(dc >> 16),
+    sRGBToLinear((dc >> 8) & 255),
+    sRGBToLinear(dc & 255),
+  ]);
+  for (let i = 1; i < numX * numY; i++) {
+    const ac = decode83(hash.substring(4 + i * 2, 6 + i * 2));
+    colors.push([
+      signPow((Math.floor(ac / 361) - 9) / 9, 2) * maxValue,
+      signPow(((Math.floor(ac / 19) % 19) - 9) / 9, 2) * maxValue,
+      signPow(((ac % 19) - 9) / 9, 2) * maxValue,
+    ]);
+  }
+
+  const pixels = new Uint8ClampedArray(width * height * 4);
+  for (let y = 0; y < height; y++) {
+    for (let x = 0; x < width; x++) {
+      let r = 0,
+        g = 0,
+        b = 0;
+      for (let j = 0; j < numY; j++) {
+        for (let i = 0; i < numX; i++) {
+          const basis =
+            Math.cos((Math.PI * x * i) / width) *
+            Math.cos((Math.PI * y * j) / height);
+          const color = colors[i + j * numX];
+          r += color[0] * basis;
+          g += color[1] * basis;
+          b += color[2] * basis;
+        }
+      }
+      const idx = 4 * (x + y * width);
+      pixels[idx] = linearToSRGB(r);
+      pixels[idx + 1] = linearToSRGB(g);
+      pixels[idx + 2] = linearToSRGB(b);
+      pixels[idx + 3] = 255;
+    }
+  }
+  return pixels;
+}
+
+function initPlaceholders(root = document) {
+  root.querySelectorAll("canvas[data-blurhash]").forEach((canvas) => {
+    if (canvas._blurhashPainted) return;
+    try {
+      const { width, height } = canvas;
+      const pixels = decodeBlurHash(canvas.dataset.blurhash, width, height);
+      const ctx = canvas.getContext("2d");
+      ctx.putImageData(new ImageData(pixels, width, height), 0, 0);
+      canvas._blurhashPainted = true;
+    } catch (e) {
+      console.warn("⚠️ Invalid BlurHash:", canvas.dataset.blurhash);
+    }
+  });
+
+  root.querySelectorAll("img[data-lqip]").forEach((img) => {
+    if (img.complete) return;
+    img.style.opacity = "0";
+    img.style.transition = "opacity 0.4s ease-in";
+    img.addEventListener("load", () => (img.style.opacity = "1"), {
+      once: true,
+    });
+  });
+}
+
+document.addEventListener("DOMContentLoaded", () => initPlaceholders());
+document.addEventListener("htmx:afterSwap", (evt) =>
+  initPlaceholders(evt.detail.target),
+);
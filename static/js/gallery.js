@@ -62,3 +62,21 @@ document.addEventListener("DOMContentLoaded", initLightbox);
 document.body.addEventListener("htmx:afterSwap", () => {
   initLightbox();
 });
+
+// 🎞️ Home hero slideshow: cross-fade through the slides every few seconds
+function initSlideshows() {
+  document.querySelectorAll("[data-slideshow]").forEach((show) => {
+    const slides = Array.from(show.querySelectorAll("[data-slide]"));
+    if (slides.length < 2 || show.dataset.running) return;
+    show.dataset.running = "true";
+
+    let current = 0;
+    setInterval(() => {
+      slides[current].classList.add("opacity-0");
+      current = (current + 1) % slides.length;
+      slides[current].classList.remove("opacity-0");
+    }, 5000);
+  });
+}
+
+document.addEventListener("DOMContentLoaded", initSlideshows);
@@ -88,6 +88,43 @@ document.addEventListener("htmx:afterSwap", () => {
   }, 50);
 });
 
+// ==============================
+// ↕️ Sortable Gallery/Project Lists
+// ==============================
+
+// Admin lists marked with data-sortable-list post their new order, in the
+// same { order: [...] } shape as the media grid, to the URL in the attribute
+function initSortableLists() {
+  document.querySelectorAll("[data-sortable-list]").forEach((list) => {
+    if (list._sortableInstance) {
+      list._sortableInstance.destroy();
+    }
+
+    list._sortableInstance = Sortable.create(list, {
+      animation: 150,
+      handle: ".drag-handle",
+      draggable: ".sortable-row",
+
+      onEnd: function() {
+        const ids = [...list.querySelectorAll(".sortable-row")].map((el) =>
+          Number(el.dataset.id),
+        );
+
+        fetch(list.dataset.sortableList, {
+          method: "POST",
+          headers: {
+            "Content-Type": "application/json",
+            "HX-Request": "true",
+          },
+          body: JSON.stringify({ order: ids }),
+        });
+      },
+    });
+  });
+}
+
+document.addEventListener("DOMContentLoaded", initSortableLists);
+
 // ===========================
 // 🧭 Sidebar Mobile Handling
 // ===========================
@@ -214,6 +251,12 @@ document.addEventListener("htmx:afterOnLoad", function(evt) {
       subtitle: "This media was removed from the gallery or project",
       path: "/admin/toast",
     },
+    "show-toast-regenerated": {
+      variant: "success",
+      heading: "Images Regenerated",
+      subtitle: "Public renditions were rebuilt with the current watermark",
+      path: "/admin/toast",
+    },
     "show-toast-contact": {
       variant: "success",
       heading: "Message Sent!",
@@ -459,21 +502,18 @@ function previewFiles(event) {
   }
 }
 
+// Files are sent in chunks so large uploads survive slow or dropped
+// connections. The upload ID is remembered per file, so picking the same
+// file again after a disconnect or reload resumes where it left off.
+const UPLOAD_RETRIES = 5;
+
 function startUpload() {
   const projectId = document.getElementById("upload-project-id")?.value;
   const galleryId = document.getElementById("upload-gallery-id")?.value;
 
-  //console.log("projectId:", projectId, "galleryId:", galleryId);
+  let uploadsRemaining = Object.keys(selectedFiles).length;
 
   Object.entries(selectedFiles).forEach(([fileId, file]) => {
-    const formData = new FormData();
-    formData.append("files[]", file);
-
-    let uploadsRemaining = Object.keys(selectedFiles).length;
-
-    if (projectId) formData.append("project_id", projectId);
-    if (galleryId) formData.append("gallery_id", galleryId);
-
     // Show progress bar
     const progressContainer = document.getElementById(
       `progress-container-${fileId}`,
@@ -485,34 +525,21 @@ function startUpload() {
     const status = document.getElementById(`status-${fileId}`);
     if (status) status.innerText = "Uploading...";
 
-    const xhr = new XMLHttpRequest();
-    xhr.open("POST", "/admin/media/upload", true);
-
-    // Update progress bar
-    xhr.upload.onprogress = function(e) {
-      if (e.lengthComputable) {
-        const percent = (e.loaded / e.total) * 100;
-        const progressBar = document.getElementById(`progress-${fileId}`);
-        if (progressBar) {
-          progressBar.style.width = `${percent.toFixed(0)}%`;
-        }
-      }
-    };
+    uploadFileInChunks(fileId, file, projectId, galleryId)
+      .then((html) => {
+        if (status) status.innerText = "Completed";
 
-    // On upload complete
-    xhr.onload = function() {
-      if (status) {
-        if (xhr.status === 200) {
-          status.innerText = "Completed";
-
-          //console.log("🚀 Response HTML:", xhr.responseText);
-          const sortable = document.querySelector(".sortable");
-          if (sortable && xhr.responseText.trim() !== "") {
-            sortable.insertAdjacentHTML("beforeend", xhr.responseText);
-          }
-        } else {
-          status.innerText = "Failed";
+        const sortable = document.querySelector(".sortable");
+        if (sortable && html.trim() !== "") {
+          sortable.insertAdjacentHTML("beforeend", html);
         }
+      })
+      .catch((err) => {
+        if (status) {
+          status.innerText = err.name === "AbortError" ? "Cancelled" : "Failed";
+        }
+      })
+      .finally(() => {
         // Decrement and check if all are done
         uploadsRemaining--;
         if (uploadsRemaining === 0) {
@@ -520,22 +547,134 @@ function startUpload() {
             closeModal();
           }, 500); // small delay feels smoother
         }
-      }
-    };
+      });
+  });
+}
+
+async function uploadFileInChunks(fileId, file, projectId, galleryId) {
+  const resumeKey = `upload:${file.name}:${file.size}:${file.lastModified}:${projectId || ""}:${galleryId || ""}`;
+  const upload = await findOrCreateUpload(resumeKey, file, projectId, galleryId);
+  uploadControllers[fileId] = upload;
+
+  let offset = upload.offset;
+  let failures = 0;
+
+  while (true) {
+    if (upload.cancelled) throw new DOMException("Upload cancelled", "AbortError");
+
+    const chunk = file.slice(offset, offset + upload.chunkSize);
+    let res;
+    try {
+      res = await sendChunk(upload, offset, chunk, (loaded) => {
+        setUploadProgress(fileId, ((offset + loaded) / file.size) * 100);
+      });
+    } catch (err) {
+      if (err.name === "AbortError" || ++failures > UPLOAD_RETRIES) throw err;
+      // Connection dropped: wait, then ask the server where to resume
+      await new Promise((r) => setTimeout(r, 1000 * 2 ** failures));
+      offset = await uploadOffset(upload.id).catch(() => offset);
+      continue;
+    }
+
+    const serverOffset = parseInt(res.getResponseHeader("Upload-Offset"), 10);
+
+    if (res.status === 200) {
+      localStorage.removeItem(resumeKey);
+      delete uploadControllers[fileId];
+      setUploadProgress(fileId, 100);
+      return res.responseText;
+    }
+    if (res.status === 204 || res.status === 409) {
+      failures = 0;
+      offset = serverOffset;
+      continue;
+    }
+    if (res.status >= 500 && ++failures <= UPLOAD_RETRIES) {
+      // The server keeps the bytes it has, so retry from where it got to;
+      // resending the final offset retries processing a finished upload
+      await new Promise((r) => setTimeout(r, 1000 * 2 ** failures));
+      offset = await uploadOffset(upload.id).catch(() => offset);
+      continue;
+    }
+    if ([404, 410, 422].includes(res.status)) localStorage.removeItem(resumeKey);
+    throw new Error(`Upload failed with status ${res.status}`);
+  }
+}
+
+async function findOrCreateUpload(resumeKey, file, projectId, galleryId) {
+  const savedId = localStorage.getItem(resumeKey);
+  if (savedId) {
+    const offset = await uploadOffset(savedId).catch(() => null);
+    // A finished upload that failed to process resumes at its end
+    if (offset !== null && offset <= file.size) {
+      return { id: savedId, offset, chunkSize: 5 * 1024 * 1024 };
+    }
+    localStorage.removeItem(resumeKey);
+  }
 
-    //for (let [key, value] of formData.entries()) {
-    //  console.log("🧾 FormData:", key, value);
-    //}
-    xhr.send(formData);
+  const formData = new FormData();
+  formData.append("filename", file.name);
+  formData.append("size", file.size);
+  formData.append("content_type", file.type);
+  if (projectId) formData.append("project_id", projectId);
+  if (galleryId) formData.append("gallery_id", galleryId);
+
+  const res = await fetch("/admin/media/uploads", {
+    method: "POST",
+    body: formData,
+  });
+  if (!res.ok) throw new Error(await res.text());
+
+  const data = await res.json();
+  localStorage.setItem(resumeKey, data.id);
+  return { id: data.id, offset: data.offset, chunkSize: data.chunk_size };
+}
+
+async function uploadOffset(id) {
+  const res = await fetch(`/admin/media/uploads/${id}`, { method: "HEAD" });
+  if (!res.ok) throw new Error(`Upload ${id} not found`);
+  return parseInt(res.headers.get("Upload-Offset"), 10);
+}
+
+// XHR rather than fetch so the progress bar moves within a chunk
+function sendChunk(upload, offset, chunk, onProgress) {
+  return new Promise((resolve, reject) => {
+    const xhr = new XMLHttpRequest();
+    upload.xhr = xhr;
+
+    xhr.open("PATCH", `/admin/media/uploads/${upload.id}`, true);
+    xhr.setRequestHeader("Upload-Offset", offset);
+    xhr.setRequestHeader("Content-Type", "application/offset+octet-stream");
+
+    xhr.upload.onprogress = (e) => onProgress(e.loaded);
+    xhr.onload = () => resolve(xhr);
+    xhr.onerror = () => reject(new Error("Network error"));
+    xhr.onabort = () => reject(new DOMException("Upload cancelled", "AbortError"));
+
+    xhr.send(chunk);
   });
 }
 
+function setUploadProgress(fileId, percent) {
+  const progressBar = document.getElementById(`progress-${fileId}`);
+  if (progressBar) {
+    progressBar.style.width = `${Math.min(percent, 100).toFixed(0)}%`;
+  }
+}
+
 function cancelUpload(fileId) {
-  const xhr = uploadControllers[fileId];
-  if (xhr) {
-    xhr.abort();
+  const upload = uploadControllers[fileId];
+  if (upload) {
+    upload.cancelled = true;
+    upload.xhr?.abort();
+    fetch(`/admin/media/uploads/${upload.id}`, { method: "DELETE" });
     delete uploadControllers[fileId];
+    return;
   }
+
+  // Not started yet: just drop it from the selection
+  delete selectedFiles[fileId];
+  document.getElementById(`file-${fileId}`)?.remove();
 }
 
 document.addEventListener("htmx:afterOnLoad", function(evt) {
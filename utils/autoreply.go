@@ -0,0 +1,60 @@
+package utils
+
+import "strings"
+
+// AutoReply is the confirmation emailed to people who send the contact
+// form. Its subject and body are plain text edited in the admin settings,
+// with placeholders filled in per enquiry:
+//
+//	{first_name}     the enquirer's first name
+//	{subject}        the subject they gave
+//	{response_time}  how soon they can expect an answer
+type AutoReply struct {
+	Subject      string
+	Body         string
+	ResponseTime string
+}
+
+// Used when the setting is left empty
+const (
+	defaultAutoReplySubject      = "Thanks for getting in touch"
+	defaultAutoReplyBody         = "Hi {first_name},\n\nThanks for your message about \"{subject}\". I'll get back to you within {response_time}.\n\nThis is an automatic confirmation, but you can reply to it if you need to add anything."
+	defaultAutoReplyResponseTime = "two business days"
+)
+
+// AutoReplyFromSettings builds an AutoReply from the admin settings map.
+// It returns nil when auto-replies are switched off.
+func AutoReplyFromSettings(settings map[string]string) *AutoReply {
+	if settings["autoreply_enabled"] != "true" {
+		return nil
+	}
+
+	a := &AutoReply{
+		Subject:      strings.TrimSpace(settings["autoreply_subject"]),
+		Body:         strings.TrimSpace(settings["autoreply_body"]),
+		ResponseTime: strings.TrimSpace(settings["autoreply_response_time"]),
+	}
+	if a.Subject == "" {
+		a.Subject = defaultAutoReplySubject
+	}
+	if a.Body == "" {
+		a.Body = defaultAutoReplyBody
+	}
+	if a.ResponseTime == "" {
+		a.ResponseTime = defaultAutoReplyResponseTime
+	}
+	return a
+}
+
+// Fill returns the subject and body with the placeholders filled in. The
+// values are inserted as plain text, never parsed as templates.
+func (a *AutoReply) Fill(firstName, subject string) (string, string) {
+	r := strings.NewReplacer(
+		"{first_name}", strings.TrimSpace(firstName),
+		"{subject}", strings.TrimSpace(subject),
+		"{response_time}", a.ResponseTime,
+	)
+	// Header values can't hold line breaks
+	filledSubject := strings.Join(strings.Fields(r.Replace(a.Subject)), " ")
+	return filledSubject, r.Replace(a.Body)
+}
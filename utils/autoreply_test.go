@@ -0,0 +1,54 @@
+package utils
+
+import "testing"
+
+func TestAutoReply(t *testing.T) {
+	cases := []struct {
+		name        string
+		settings    map[string]string
+		firstName   string
+		subject     string
+		wantNil     bool
+		wantSubject string
+		wantBody    string
+	}{
+		{"✅ disabled", map[string]string{"autoreply_subject": "Hi"}, "Ana", "Wedding", true, "", ""},
+		{"✅ custom template", map[string]string{
+			"autoreply_enabled":       "true",
+			"autoreply_subject":       "Re: {subject}",
+			"autoreply_body":          "Hi {first_name}, expect an answer within {response_time}.",
+			"autoreply_response_time": "a week",
+		}, "Ana", "Wedding", false, "Re: Wedding", "Hi Ana, expect an answer within a week."},
+		{"✅ defaults", map[string]string{"autoreply_enabled": "true"}, "Ana", "Wedding", false,
+			"Thanks for getting in touch",
+			"Hi Ana,\n\nThanks for your message about \"Wedding\". I'll get back to you within two business days.\n\nThis is an automatic confirmation, but you can reply to it if you need to add anything."},
+		{"✅ placeholders in values aren't expanded", map[string]string{
+			"autoreply_enabled": "true",
+			"autoreply_body":    "{first_name}: {subject}",
+		}, "{subject}", "{{ .Secret }}", false, "Thanks for getting in touch", "{subject}: {{ .Secret }}"},
+		{"✅ no line breaks in the subject", map[string]string{
+			"autoreply_enabled": "true",
+			"autoreply_subject": "About {subject}",
+		}, "Ana", "Hello\r\nBcc: victim@example.com", false, "About Hello Bcc: victim@example.com", ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reply := AutoReplyFromSettings(tc.settings)
+			if (reply == nil) != tc.wantNil {
+				t.Fatalf("Expected nil %v, got %+v", tc.wantNil, reply)
+			}
+			if reply == nil {
+				return
+			}
+
+			subject, body := reply.Fill(tc.firstName, tc.subject)
+			if subject != tc.wantSubject {
+				t.Errorf("Expected subject %q, got %q", tc.wantSubject, subject)
+			}
+			if tc.wantBody != "" && body != tc.wantBody {
+				t.Errorf("Expected body %q, got %q", tc.wantBody, body)
+			}
+		})
+	}
+}
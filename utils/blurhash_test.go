@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// halves is a 32x32 image, black on the left and white on the right. It's
+// already the size BlurHash works at, so no resampling blurs the edge.
+func halves() *image.NRGBA {
+	img := solid(32, 32, color.Black)
+	draw.Draw(img, image.Rect(16, 0, 32, 32), image.NewUniform(color.White), image.Point{}, draw.Src)
+	return img
+}
+
+func TestBlurHash(t *testing.T) {
+	// Expected hashes were worked out with the reference algorithm from
+	// https://github.com/woltapp/blurhash
+	cases := []struct {
+		name    string
+		img     image.Image
+		x, y    int
+		want    string
+		wantErr bool
+	}{
+		{"✅ solid white, one component", solid(64, 48, color.White), 1, 1, "00TSUA", false},
+		{"✅ solid white, 4x3", solid(64, 48, color.White), 4, 3, "LDTSUA_3fQ_3~qoffQoffQfQfQfQ", false},
+		{"✅ black and white halves", halves(), 2, 1, "1~Lqe900", false},
+		{"✅ black and white halves, 4x3", halves(), 4, 3, "L~Lqe900Rj-;ofWBayj[fQfQfQfQ", false},
+		{"❌ no components", solid(8, 8, color.White), 0, 3, "", true},
+		{"❌ too many components", solid(8, 8, color.White), 4, 10, "", true},
+		{"❌ empty image", image.NewNRGBA(image.Rect(0, 0, 0, 0)), 4, 3, "", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := BlurHash(tc.img, tc.x, tc.y)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("BlurHash error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("Expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestDominantColor(t *testing.T) {
+	cases := []struct {
+		name string
+		img  image.Image
+		want string
+	}{
+		{"✅ solid colour", solid(40, 30, color.NRGBA{0x33, 0x66, 0x99, 0xff}), "#336699"},
+		{"✅ white", solid(10, 10, color.White), "#ffffff"},
+		{"✅ halves average out", halves(), "#808080"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := DominantColor(tc.img); got != tc.want {
+				t.Errorf("Expected %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
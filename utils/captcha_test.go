@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// stubSiteVerify answers like a siteverify endpoint: token "good" passes
+// with score 0.9, "meh" passes with score 0.3, anything else fails
+func stubSiteVerify(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("secret") != "shh" {
+			t.Errorf("Expected the secret to be sent, got %q", r.FormValue("secret"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.FormValue("response") {
+		case "good":
+			w.Write([]byte(`{"success": true, "score": 0.9}`))
+		case "meh":
+			w.Write([]byte(`{"success": true, "score": 0.3}`))
+		case "noscore":
+			w.Write([]byte(`{"success": true}`))
+		default:
+			w.Write([]byte(`{"success": false, "error-codes": ["invalid-input-response"]}`))
+		}
+	}))
+}
+
+func TestSiteVerifier_Verify(t *testing.T) {
+	stub := stubSiteVerify(t)
+	defer stub.Close()
+
+	recaptcha := NewRecaptchaVerifier("key", "shh", 0.5)
+	recaptcha.URL = stub.URL
+	turnstile := NewTurnstileVerifier("key", "shh")
+	turnstile.URL = stub.URL
+
+	cases := []struct {
+		name     string
+		verifier *SiteVerifier
+		token    string
+		wantErr  bool
+	}{
+		{"✅ good token", recaptcha, "good", false},
+		{"❌ low score", recaptcha, "meh", true},
+		{"✅ low score without a minimum", turnstile, "meh", false},
+		{"✅ no score returned", recaptcha, "noscore", false},
+		{"❌ bad token", turnstile, "bad", true},
+		{"❌ no token", recaptcha, "", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.verifier.Verify(context.Background(), tc.token, "203.0.113.7")
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Verify error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrCaptchaFailed) {
+				t.Errorf("Expected ErrCaptchaFailed, got %v", err)
+			}
+		})
+	}
+
+	// An unreachable service is an error, but not a failed captcha
+	down := NewHCaptchaVerifier("key", "shh")
+	down.URL = "http://127.0.0.1:1"
+	if err := down.Verify(context.Background(), "good", ""); err == nil || errors.Is(err, ErrCaptchaFailed) {
+		t.Errorf("Expected a request error, got %v", err)
+	}
+}
+
+func TestNewCaptchaVerifierFromEnv(t *testing.T) {
+	cases := []struct {
+		name      string
+		env       map[string]string
+		wantName  string
+		wantField string
+		wantURL   string
+		wantErr   bool
+	}{
+		{"✅ reCAPTCHA by default", map[string]string{"RECAPTCHA_SECRET": "shh"}, "recaptcha", "g-recaptcha-response", RecaptchaVerifyURL, false},
+		{"✅ hCaptcha with a local stub", map[string]string{"CAPTCHA_PROVIDER": "hcaptcha", "CAPTCHA_VERIFY_URL": "http://localhost:9000/verify"}, "hcaptcha", "h-captcha-response", "http://localhost:9000/verify", false},
+		{"✅ Turnstile", map[string]string{"CAPTCHA_PROVIDER": "Turnstile"}, "turnstile", "cf-turnstile-response", TurnstileVerifyURL, false},
+		{"✅ none", map[string]string{"CAPTCHA_PROVIDER": "none"}, "none", "", "", false},
+		{"❌ unknown provider", map[string]string{"CAPTCHA_PROVIDER": "magic"}, "", "", "", true},
+		{"❌ bad score", map[string]string{"CAPTCHA_MIN_SCORE": "high"}, "", "", "", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, key := range []string{"CAPTCHA_PROVIDER", "CAPTCHA_SITE_KEY", "CAPTCHA_SECRET", "CAPTCHA_VERIFY_URL", "CAPTCHA_MIN_SCORE", "RECAPTCHA_SITE_KEY", "RECAPTCHA_SECRET"} {
+				t.Setenv(key, tc.env[key])
+			}
+
+			v, err := NewCaptchaVerifierFromEnv()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if v.Provider() != tc.wantName || v.TokenField() != tc.wantField {
+				t.Errorf("Expected %s reading %q, got %s reading %q", tc.wantName, tc.wantField, v.Provider(), v.TokenField())
+			}
+			if sv, ok := v.(*SiteVerifier); ok && sv.URL != tc.wantURL {
+				t.Errorf("Expected URL %q, got %q", tc.wantURL, sv.URL)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Transactional emails live in templates/emails. Each email is a pair of
+// files, <name>.html and <name>.txt, defining a "content" block for the
+// HTML and plain-text parts. Files starting with an underscore are shared
+// by every email: _layout.html and _layout.txt define the "layout" each part
+// is rendered through, and others hold partials such as the footer.
+
+// EmailTemplates is the set of named transactional emails
+type EmailTemplates struct {
+	html map[string]*htmltemplate.Template
+	text map[string]*texttemplate.Template
+}
+
+// LoadEmailTemplates parses every email in dir of fsys. An email missing
+// either part is an error, so a broken template stops startup rather than
+// a send.
+func LoadEmailTemplates(fsys fs.FS, dir string) (*EmailTemplates, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var sharedHTML, sharedText []string
+	names := map[string]bool{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		file := path.Join(dir, e.Name())
+		ext := path.Ext(e.Name())
+		base := strings.TrimSuffix(e.Name(), ext)
+		if ext != ".html" && ext != ".txt" {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(base, "_") && ext == ".html":
+			sharedHTML = append(sharedHTML, file)
+		case strings.HasPrefix(base, "_"):
+			sharedText = append(sharedText, file)
+		default:
+			names[base] = true
+		}
+	}
+
+	t := &EmailTemplates{
+		html: make(map[string]*htmltemplate.Template, len(names)),
+		text: make(map[string]*texttemplate.Template, len(names)),
+	}
+	for name := range names {
+		htmlFiles := append([]string{path.Join(dir, name+".html")}, sharedHTML...)
+		h, err := htmltemplate.New(name).ParseFS(fsys, htmlFiles...)
+		if err != nil {
+			return nil, fmt.Errorf("email %q: %w", name, err)
+		}
+
+		textFiles := append([]string{path.Join(dir, name+".txt")}, sharedText...)
+		x, err := texttemplate.New(name).ParseFS(fsys, textFiles...)
+		if err != nil {
+			return nil, fmt.Errorf("email %q: %w", name, err)
+		}
+
+		t.html[name] = h
+		t.text[name] = x
+	}
+	return t, nil
+}
+
+// Names returns the emails in the set
+func (t *EmailTemplates) Names() []string {
+	names := make([]string, 0, len(t.html))
+	for name := range t.html {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Render executes both parts of the named email with data
+func (t *EmailTemplates) Render(name string, data interface{}) (html, text string, err error) {
+	h, ok := t.html[name]
+	if !ok {
+		return "", "", fmt.Errorf("unknown email template %q", name)
+	}
+
+	var hb, tb bytes.Buffer
+	if err := h.ExecuteTemplate(&hb, "layout", data); err != nil {
+		return "", "", fmt.Errorf("failed to render email %q: %w", name, err)
+	}
+	if err := t.text[name].ExecuteTemplate(&tb, "layout", data); err != nil {
+		return "", "", fmt.Errorf("failed to render email %q: %w", name, err)
+	}
+	return hb.String(), strings.TrimSpace(tb.String()) + "\n", nil
+}
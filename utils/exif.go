@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"io"
+	"time"
+
+	"github.com/rwcarlsen/goexif/exif"
+)
+
+// ExifDate returns the date a photo was taken, read from its EXIF data.
+// ok is false when the file has no usable EXIF date.
+func ExifDate(r io.Reader) (t time.Time, ok bool) {
+	x, err := exif.Decode(r)
+	if err != nil {
+		return time.Time{}, false
+	}
+	t, err = x.DateTime()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
@@ -0,0 +1,213 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"io"
+	"strings"
+
+	"github.com/disintegration/imaging"
+	"github.com/minio/minio-go/v7"
+)
+
+// Originals are stored privately under OriginalsPrefix; only the derived
+// renditions under UploadsPrefix are public-read.
+const (
+	OriginalsPrefix = "Originals/"
+	UploadsPrefix   = "Uploads/"
+)
+
+// ErrUnreadableImage is returned for files that can't be decoded, as
+// opposed to failures storing them that are worth retrying
+var ErrUnreadableImage = errors.New("not a readable image")
+
+// ImageStore keeps media files in an S3 bucket: the private originals, and
+// the watermarked public renditions made from them
+type ImageStore struct {
+	Client   *minio.Client
+	Bucket   string
+	Endpoint string // host the bucket is served from, for public URLs
+}
+
+// URL returns the public URL for an object key in the bucket
+func (s *ImageStore) URL(key string) string {
+	return "https://" + s.Endpoint + "/" + s.Bucket + "/" + key
+}
+
+// LoadWatermark builds the watermark from the admin settings, fetching the
+// logo when one has been uploaded. A nil watermark means watermarking is
+// switched off.
+func (s *ImageStore) LoadWatermark(ctx context.Context, settings map[string]string) (*Watermark, error) {
+	wm := WatermarkFromSettings(settings)
+	if wm == nil {
+		return nil, nil
+	}
+
+	if key := settings["watermark_logo_key"]; key != "" {
+		obj, err := s.Client.GetObject(ctx, s.Bucket, key, minio.GetObjectOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("fetching watermark logo: %w", err)
+		}
+		defer obj.Close()
+
+		logo, err := imaging.Decode(obj)
+		if err != nil {
+			return nil, fmt.Errorf("decoding watermark logo: %w", err)
+		}
+		wm.Logo = logo
+	}
+
+	return wm, nil
+}
+
+// StoreOriginal uploads the untouched original without a public ACL
+func (s *ImageStore) StoreOriginal(ctx context.Context, r io.Reader, size int64, contentType, base string) (string, error) {
+	key := OriginalsPrefix + base
+	_, err := s.Client.PutObject(ctx, s.Bucket, key, r, size, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		return "", fmt.Errorf("uploading original %s: %w", key, err)
+	}
+	return key, nil
+}
+
+// PublishDerivatives renders the public full-size and thumbnail renditions
+// of img, stamps the watermark on both, and uploads them as public-read.
+func (s *ImageStore) PublishDerivatives(ctx context.Context, img image.Image, base string, wm *Watermark) (fullURL, thumbURL string, err error) {
+	display := ApplyWatermark(imaging.Fit(img, DisplayMaxSize, DisplayMaxSize, imaging.Lanczos), wm)
+	thumb := ApplyWatermark(imaging.Resize(img, ThumbWidth, 0, imaging.Lanczos), wm)
+
+	fileKey := UploadsPrefix + base
+	thumbKey := UploadsPrefix + "thumb_" + base
+
+	if err := s.putPublicJPEG(ctx, fileKey, display); err != nil {
+		return "", "", err
+	}
+	if err := s.putPublicJPEG(ctx, thumbKey, thumb); err != nil {
+		return "", "", err
+	}
+
+	return s.URL(fileKey), s.URL(thumbKey), nil
+}
+
+// PublishAnimated renders the public full-size rendition of an animated GIF
+// with every frame watermarked, alongside a watermarked still thumbnail of
+// its first frame, img.
+func (s *ImageStore) PublishAnimated(ctx context.Context, r io.Reader, img image.Image, base string, wm *Watermark) (fullURL, thumbURL string, err error) {
+	g, err := gif.DecodeAll(r)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrUnreadableImage, err)
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, WatermarkGIF(g, DisplayMaxSize, wm)); err != nil {
+		return "", "", fmt.Errorf("encoding animation: %w", err)
+	}
+
+	fileKey := UploadsPrefix + base
+	thumbKey := UploadsPrefix + "thumb_" + base
+
+	_, err = s.Client.PutObject(ctx, s.Bucket, fileKey, bytes.NewReader(buf.Bytes()), int64(buf.Len()), minio.PutObjectOptions{
+		ContentType:  "image/gif",
+		UserMetadata: map[string]string{"x-amz-acl": "public-read"},
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("uploading %s: %w", fileKey, err)
+	}
+	thumb := ApplyWatermark(imaging.Resize(img, ThumbWidth, 0, imaging.Lanczos), wm)
+	if err := s.putPublicJPEG(ctx, thumbKey, thumb); err != nil {
+		return "", "", err
+	}
+
+	return s.URL(fileKey), s.URL(thumbKey), nil
+}
+
+// Regenerate re-renders the public renditions of a media file from its
+// private original and returns the original's key. A legacy upload with no
+// originalKey only exists as a public object, so it's first copied into the
+// private originals area and the copy becomes the original; the public file
+// is then overwritten with a watermarked rendition. The caller records the
+// returned key once this succeeds.
+func (s *ImageStore) Regenerate(ctx context.Context, fileName, originalKey string, wm *Watermark) (string, error) {
+	key := originalKey
+	if key == "" {
+		key = OriginalsPrefix + fileName
+		if err := s.copyLegacyOriginal(ctx, fileName, key); err != nil {
+			return "", err
+		}
+	}
+
+	obj, err := s.Client.GetObject(ctx, s.Bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return "", fmt.Errorf("fetching original %s: %w", key, err)
+	}
+	defer obj.Close()
+
+	img, err := imaging.Decode(obj, imaging.AutoOrientation(true))
+	if err != nil {
+		return "", fmt.Errorf("decoding original %s: %w", key, err)
+	}
+
+	if IsAnimatedFormat(fileName) {
+		if _, err := obj.Seek(0, io.SeekStart); err != nil {
+			return "", fmt.Errorf("rewinding original %s: %w", key, err)
+		}
+		_, _, err = s.PublishAnimated(ctx, obj, img, fileName, wm)
+	} else {
+		_, _, err = s.PublishDerivatives(ctx, img, fileName, wm)
+	}
+	if err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// copyLegacyOriginal copies the public file of a legacy upload to key. A
+// copy left there by an earlier, interrupted run is kept: by then the public
+// file may already be a watermarked rendition.
+func (s *ImageStore) copyLegacyOriginal(ctx context.Context, fileName, key string) error {
+	_, err := s.Client.StatObject(ctx, s.Bucket, key, minio.StatObjectOptions{})
+	if err == nil {
+		return nil
+	}
+	if minio.ToErrorResponse(err).Code != "NoSuchKey" {
+		return fmt.Errorf("checking for original %s: %w", key, err)
+	}
+
+	_, err = s.Client.CopyObject(ctx,
+		minio.CopyDestOptions{Bucket: s.Bucket, Object: key},
+		minio.CopySrcOptions{Bucket: s.Bucket, Object: UploadsPrefix + fileName},
+	)
+	if err != nil {
+		return fmt.Errorf("copying legacy original %s: %w", fileName, err)
+	}
+	return nil
+}
+
+func (s *ImageStore) putPublicJPEG(ctx context.Context, key string, img image.Image) error {
+	var buf bytes.Buffer
+	if err := imaging.Encode(&buf, img, imaging.JPEG, imaging.JPEGQuality(90)); err != nil {
+		return fmt.Errorf("encoding %s: %w", key, err)
+	}
+
+	_, err := s.Client.PutObject(ctx, s.Bucket, key, bytes.NewReader(buf.Bytes()), int64(buf.Len()), minio.PutObjectOptions{
+		ContentType:  "image/jpeg",
+		UserMetadata: map[string]string{"x-amz-acl": "public-read"},
+	})
+	if err != nil {
+		return fmt.Errorf("uploading %s: %w", key, err)
+	}
+	return nil
+}
+
+// IsAnimatedFormat reports whether a file would lose its animation if it
+// were re-encoded as a JPEG rendition. These are published as GIFs with
+// every frame watermarked instead.
+func IsAnimatedFormat(fileName string) bool {
+	return strings.HasSuffix(strings.ToLower(fileName), ".gif")
+}
@@ -2,59 +2,255 @@ package utils
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
-	"html/template"
-	ikmgo "ikm"
+	"io"
+	"net"
+	"net/mail"
+	"net/smtp"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"gopkg.in/gomail.v2"
 )
 
-// SendEmail uses GoMail to send an HTML email using a provided template.
-func SendEmail(from, to, subject, tmplPath string, data interface{}) error {
+// Message is an email ready to send. Text is the plain-text part shown by
+// clients that don't render HTML; either part may be empty, not both.
+type Message struct {
+	From    string
+	To      string
+	ReplyTo string
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// Mailer sends email. Send gives up when ctx is done.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// smtpTimeout bounds a send when ctx has no deadline of its own
+const smtpTimeout = time.Minute
 
-	t, err := template.ParseFS(ikmgo.EmbeddedFiles, "templates/emails/contact_email.html")
+// SMTPMailer sends email through an SMTP server. Each send opens its own
+// connection with a deadline, so a server that accepts the connection and
+// then stalls can't hold the sender up.
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+
+	// SSL connects over TLS from the start, as port 465 expects; otherwise
+	// STARTTLS is used when the server offers it
+	SSL bool
+}
+
+func NewSMTPMailer(host string, port int, user, pass string) *SMTPMailer {
+	return &SMTPMailer{Host: host, Port: port, Username: user, Password: pass, SSL: port == 465}
+}
+
+// NewSMTPMailerFromEnv builds an SMTPMailer from SMTP_HOST, SMTP_PORT,
+// SMTP_USER and SMTP_PASS. The port defaults to 587.
+func NewSMTPMailerFromEnv() (*SMTPMailer, error) {
+	port := 587
+	if v := os.Getenv("SMTP_PORT"); v != "" {
+		var err error
+		port, err = strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SMTP_PORT %q: %w", v, err)
+		}
+	}
+	return NewSMTPMailer(os.Getenv("SMTP_HOST"), port, os.Getenv("SMTP_USER"), os.Getenv("SMTP_PASS")), nil
+}
+
+// Send delivers msg as multipart/alternative, plain text first so clients
+// prefer the HTML part
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if msg.HTML == "" && msg.Text == "" {
+		return errors.New("email has no body")
+	}
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender %q: %w", msg.From, err)
+	}
+	to, err := mail.ParseAddress(msg.To)
 	if err != nil {
-		return fmt.Errorf("failed to parse email template: %w", err)
+		return fmt.Errorf("invalid recipient %q: %w", msg.To, err)
 	}
 
-	// 2) Execute the template with 'data'
-	var body bytes.Buffer
-	if err := t.Execute(&body, data); err != nil {
-		return fmt.Errorf("failed to execute email template: %w", err)
+	gm := gomail.NewMessage()
+	gm.SetHeader("From", msg.From)
+	gm.SetHeader("To", msg.To)
+	if msg.ReplyTo != "" {
+		gm.SetHeader("Reply-To", msg.ReplyTo)
 	}
+	gm.SetHeader("Subject", msg.Subject)
 
-	// 3) Create a new GoMail message
-	m := gomail.NewMessage()
-	m.SetHeader("From", from)
-	m.SetHeader("To", to)
-	m.SetHeader("Subject", subject)
-	m.SetBody("text/html", body.String())
+	switch {
+	case msg.Text == "":
+		gm.SetBody("text/html", msg.HTML)
+	case msg.HTML == "":
+		gm.SetBody("text/plain", msg.Text)
+	default:
+		gm.SetBody("text/plain", msg.Text)
+		gm.AddAlternative("text/html", msg.HTML)
+	}
+
+	if err := m.send(ctx, from.Address, to.Address, gm); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+func (m *SMTPMailer) send(ctx context.Context, from, to string, msg io.WriterTo) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, smtpTimeout)
+		defer cancel()
+	}
+	deadline, _ := ctx.Deadline()
 
-	// 4) Parse the SMTP port (which is a string in .env) into an integer
-	portStr := os.Getenv("SMTP_PORT")
-	port, err := strconv.Atoi(portStr)
+	dialer := net.Dialer{Timeout: 10 * time.Second}
+	raw, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(m.Host, strconv.Itoa(m.Port)))
 	if err != nil {
-		return fmt.Errorf("invalid SMTP_PORT %q: %w", portStr, err)
+		return err
 	}
+	defer raw.Close()
 
-	// 5) Build a new GoMail dialer with your SMTP settings
-	dialer := gomail.NewDialer(
-		os.Getenv("SMTP_HOST"), // e.g. "smtp.gmail.com"
-		port,                   // e.g. 587
-		os.Getenv("SMTP_USER"), // your email address or username
-		os.Getenv("SMTP_PASS"), // your password or app-specific password
-	)
+	// Every read and write fails once the deadline passes, and cancelling
+	// ctx closes the connection at once
+	raw.SetDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { raw.Close() })
+	defer stop()
 
-	// Optional: If you need SSL/TLS, you can configure:
-	// dialer.SSL = true
-	// or dialer.TLSConfig = &tls.Config{InsecureSkipVerify: true}
+	conn := raw
+	tlsConfig := &tls.Config{ServerName: m.Host}
+	if m.SSL {
+		conn = tls.Client(raw, tlsConfig)
+	}
 
-	// 6) Dial and send the message
-	if err := dialer.DialAndSend(m); err != nil {
-		return fmt.Errorf("failed to send email: %w", err)
+	c, err := smtp.NewClient(conn, m.Host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if !m.SSL {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(tlsConfig); err != nil {
+				return err
+			}
+		}
+	}
+	if m.Username != "" {
+		if ok, mechs := c.Extension("AUTH"); ok {
+			if err := c.Auth(m.auth(mechs)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := msg.WriteTo(w); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	// The server has accepted the email; a failed goodbye doesn't unsend it
+	c.Quit()
+	return nil
+}
+
+// auth picks an authentication mechanism the server offers, preferring
+// CRAM-MD5 and falling back to LOGIN only for servers without PLAIN
+func (m *SMTPMailer) auth(mechs string) smtp.Auth {
+	switch {
+	case strings.Contains(mechs, "CRAM-MD5"):
+		return smtp.CRAMMD5Auth(m.Username, m.Password)
+	case strings.Contains(mechs, "LOGIN") && !strings.Contains(mechs, "PLAIN"):
+		return &loginAuth{username: m.Username, password: m.Password, host: m.Host}
+	default:
+		return smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+}
+
+// loginAuth is the LOGIN mechanism, which net/smtp doesn't provide
+type loginAuth struct {
+	username, password, host string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && server.Name != "localhost" && server.Name != "127.0.0.1" && server.Name != "::1" {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	switch {
+	case bytes.EqualFold(fromServer, []byte("Username:")):
+		return []byte(a.username), nil
+	case bytes.EqualFold(fromServer, []byte("Password:")):
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected server challenge: %s", fromServer)
 	}
+}
 
+// CaptureMailer keeps sent email in memory instead of sending it, for tests
+// and local development. Setting Err makes every send fail with it.
+type CaptureMailer struct {
+	mu   sync.Mutex
+	sent []Message
+	Err  error
+}
+
+func (c *CaptureMailer) Send(ctx context.Context, msg Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.Err != nil {
+		return c.Err
+	}
+	c.sent = append(c.sent, msg)
 	return nil
 }
+
+// Sent returns the messages sent so far, oldest first
+func (c *CaptureMailer) Sent() []Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Message(nil), c.sent...)
+}
+
+// Reset forgets the messages sent so far
+func (c *CaptureMailer) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent = nil
+}
@@ -0,0 +1,217 @@
+package utils
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	ikmgo "ikm"
+	"net"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestEmailTemplates_Render(t *testing.T) {
+	emails, err := LoadEmailTemplates(ikmgo.EmbeddedFiles, "templates/emails")
+	if err != nil {
+		t.Fatalf("LoadEmailTemplates failed: %v", err)
+	}
+
+	type contact struct {
+		FirstName string
+		Message   string
+		CreatedAt time.Time
+	}
+
+	cases := []struct {
+		name     string
+		template string
+		data     interface{}
+		want     []string // in both parts
+		wantErr  bool
+	}{
+		{"✅ contact notification", "contact_notification", map[string]string{
+			"FirstName": "Ana", "LastName": "Diaz", "Email": "ana@example.com", "Subject": "Wedding", "Message": "Are you free?",
+		}, []string{"Ana Diaz", "ana@example.com", "Wedding", "Are you free?", "contact form"}, false},
+		{"✅ contact reply", "contact_reply", map[string]interface{}{
+			"Contact": contact{FirstName: "Ana", Message: "Are you free?", CreatedAt: time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)},
+			"Body":    "Yes I am",
+		}, []string{"Hi Ana", "Yes I am", "1 May 2025", "Are you free?"}, false},
+		{"✅ auto-reply", "auto_reply", map[string]string{"Body": "Thanks Ana"}, []string{"Thanks Ana", "contact form"}, false},
+		{"❌ unknown template", "nope", nil, nil, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			html, text, err := emails.Render(tc.template, tc.data)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Render error = %v, wantErr %v", err, tc.wantErr)
+			}
+			for _, s := range tc.want {
+				if !strings.Contains(html, s) {
+					t.Errorf("Expected HTML part to contain %q", s)
+				}
+				if !strings.Contains(text, s) {
+					t.Errorf("Expected text part to contain %q", s)
+				}
+			}
+			if !tc.wantErr && strings.Contains(text, "<") {
+				t.Errorf("Expected no markup in the text part, got %q", text)
+			}
+		})
+	}
+
+	// HTML is escaped, plain text isn't
+	html, text, _ := emails.Render("contact_notification", map[string]string{"Message": "<b>hi</b> & bye"})
+	if !strings.Contains(html, "&lt;b&gt;hi&lt;/b&gt; &amp; bye") {
+		t.Errorf("Expected the HTML part to escape the message, got %q", html)
+	}
+	if !strings.Contains(text, "<b>hi</b> & bye") {
+		t.Errorf("Expected the text part to keep the message as is, got %q", text)
+	}
+}
+
+func TestLoadEmailTemplates_NeedsBothParts(t *testing.T) {
+	fsys := fstest.MapFS{
+		"emails/_layout.html": {Data: []byte(`{{ define "layout" }}{{ template "content" . }}{{ end }}`)},
+		"emails/_layout.txt":  {Data: []byte(`{{ define "layout" }}{{ template "content" . }}{{ end }}`)},
+		"emails/welcome.html": {Data: []byte(`{{ define "content" }}<p>Hi</p>{{ end }}`)},
+	}
+	if _, err := LoadEmailTemplates(fsys, "emails"); err == nil {
+		t.Error("Expected an error for an email without a text part")
+	}
+
+	fsys["emails/welcome.txt"] = &fstest.MapFile{Data: []byte(`{{ define "content" }}Hi{{ end }}`)}
+	emails, err := LoadEmailTemplates(fsys, "emails")
+	if err != nil {
+		t.Fatalf("LoadEmailTemplates failed: %v", err)
+	}
+	if names := emails.Names(); len(names) != 1 || names[0] != "welcome" {
+		t.Errorf("Expected only welcome, got %v", names)
+	}
+}
+
+func TestCaptureMailer(t *testing.T) {
+	m := &CaptureMailer{}
+	var _ Mailer = m
+
+	m.Send(context.Background(), Message{To: "a@example.com", Subject: "One"})
+	m.Send(context.Background(), Message{To: "b@example.com", Subject: "Two"})
+	if sent := m.Sent(); len(sent) != 2 || sent[1].Subject != "Two" {
+		t.Errorf("Expected both messages in order, got %+v", sent)
+	}
+
+	m.Err = errors.New("smtp down")
+	if err := m.Send(context.Background(), Message{To: "c@example.com"}); err == nil {
+		t.Error("Expected the configured error")
+	}
+	if len(m.Sent()) != 2 {
+		t.Errorf("Expected a failed send not to be captured")
+	}
+
+	m.Reset()
+	if len(m.Sent()) != 0 {
+		t.Errorf("Expected Reset to forget sent messages")
+	}
+}
+
+// fakeSMTPServer accepts one connection and speaks just enough SMTP to take
+// a message, which it sends on received. With stall set it greets nobody
+// and just holds the connection open.
+func fakeSMTPServer(t *testing.T, stall bool) (host string, port int, received chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	received = make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if stall {
+			conn.Read(make([]byte, 1)) // until the client hangs up
+			return
+		}
+
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		reply("220 localhost ready")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case cmd == "DATA":
+				reply("354 go ahead")
+				var body strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					body.WriteString(l)
+				}
+				received <- body.String()
+				reply("250 queued")
+			case cmd == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, received
+}
+
+func TestSMTPMailer_Send(t *testing.T) {
+	host, port, received := fakeSMTPServer(t, false)
+	m := NewSMTPMailer(host, port, "", "")
+
+	msg := Message{From: "Site <site@example.com>", To: "ana@example.com", Subject: "Hello", HTML: "<p>Hi</p>", Text: "Hi"}
+	if err := m.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	body := <-received
+	for _, want := range []string{"Subject: Hello", "text/plain", "text/html"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected the message to contain %q, got %q", want, body)
+		}
+	}
+}
+
+func TestSMTPMailer_SendStalledServer(t *testing.T) {
+	host, port, _ := fakeSMTPServer(t, true)
+	m := NewSMTPMailer(host, port, "", "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := m.Send(ctx, Message{From: "site@example.com", To: "ana@example.com", Text: "Hi"})
+	if err == nil {
+		t.Fatal("Expected a stalled server to fail the send")
+	}
+	if took := time.Since(start); took > 2*time.Second {
+		t.Errorf("Expected Send to give up at the deadline, took %v", took)
+	}
+}
+
+func TestSMTPMailer_SendInvalidAddress(t *testing.T) {
+	m := NewSMTPMailer("127.0.0.1", 1, "", "")
+	err := m.Send(context.Background(), Message{From: "site@example.com", To: "not an address", Text: "Hi"})
+	if err == nil || !strings.Contains(err.Error(), "invalid recipient") {
+		t.Errorf("Expected an invalid recipient error, got %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// Figure is a media item that a Markdown body embeds with [media:ID]
+type Figure struct {
+	ThumbnailURL string
+	FullURL      string
+	Alt          string
+}
+
+// mediaRef matches [media:42] or [media:42 An optional caption]
+var mediaRef = regexp.MustCompile(`\[media:(\d+)(?:[ \t]+([^\]\n]*))?\]`)
+
+// figureParagraph matches a rendered paragraph holding only a media reference
+var figureParagraph = regexp.MustCompile(`<p>\[media:(\d+)(?:[ \t]+([^\]<]*))?\]</p>`)
+
+var markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// Raw HTML is already left out by goldmark; the policy also strips unsafe
+// links and attributes from what Markdown itself produces
+var markdownPolicy = bluemonday.UGCPolicy()
+
+// MediaRefs returns the media IDs that src references, in order of first use
+func MediaRefs(src string) []int {
+	seen := make(map[int]bool)
+	var ids []int
+	for _, m := range mediaRef.FindAllStringSubmatch(src, -1) {
+		id, err := strconv.Atoi(m[1])
+		if err != nil || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// RenderMarkdown turns src into sanitized HTML. A paragraph that is just a
+// media reference becomes a responsive figure, captioned with any text after
+// the ID; references to media missing from figures are dropped.
+func RenderMarkdown(src string, figures map[int]Figure) (string, error) {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(src), &buf); err != nil {
+		return "", err
+	}
+
+	safe := markdownPolicy.Sanitize(buf.String())
+
+	// Figures are added after sanitizing since they're built here, with every
+	// value escaped, and use attributes the policy would drop
+	return figureParagraph.ReplaceAllStringFunc(safe, func(p string) string {
+		m := figureParagraph.FindStringSubmatch(p)
+		id, _ := strconv.Atoi(m[1])
+		fig, ok := figures[id]
+		if !ok {
+			return ""
+		}
+		// The caption was escaped by the Markdown renderer
+		return figureHTML(fig, m[2])
+	}), nil
+}
+
+func figureHTML(fig Figure, caption string) string {
+	alt := html.EscapeString(fig.Alt)
+	if caption != "" {
+		alt = caption
+	}
+
+	img := fmt.Sprintf(`<img src="%s" srcset="%s %dw, %s %dw" sizes="(min-width: 768px) 768px, 100vw" alt="%s" data-full="%s" loading="lazy" class="w-full h-auto">`,
+		html.EscapeString(fig.ThumbnailURL),
+		html.EscapeString(fig.ThumbnailURL), ThumbWidth,
+		html.EscapeString(fig.FullURL), DisplayMaxSize,
+		alt,
+		html.EscapeString(fig.FullURL))
+
+	if caption == "" {
+		return `<figure>` + img + `</figure>`
+	}
+	return `<figure>` + img + `<figcaption>` + caption + `</figcaption></figure>`
+}
@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The spam filter scores a contact form submission from signals that need
+// no outside service: a honeypot field only bots fill in, how quickly the
+// form came back, links in the message and blocklisted terms. Submissions
+// scoring at or over the threshold are kept as spam rather than rejected,
+// so a false positive can still be found in the admin.
+
+// Submission is what the spam filter looks at
+type Submission struct {
+	Name    string
+	Email   string
+	Subject string
+	Message string
+
+	// Honeypot is the value of a field hidden from people
+	Honeypot string
+	// FillTime is how long the form was open before it was sent; it's
+	// unknown when the form didn't carry a valid timestamp
+	FillTime      time.Duration
+	FillTimeKnown bool
+}
+
+// Verdict is the spam filter's assessment of a submission
+type Verdict struct {
+	Score   int
+	Reasons []string
+	Spam    bool
+}
+
+// Reason joins the reasons for the score into one line
+func (v Verdict) Reason() string {
+	return strings.Join(v.Reasons, "; ")
+}
+
+// SpamFilter scores submissions; see SpamFilterFromSettings for the defaults
+type SpamFilter struct {
+	Threshold   int
+	MinFillTime time.Duration
+	MaxLinks    int // links allowed before each extra one counts
+	Blocklist   []string
+}
+
+// How much each signal adds to the score
+const (
+	spamHoneypotScore  = 10
+	spamTooFastScore   = 5
+	spamNoTimeScore    = 2
+	spamLinkScore      = 2
+	spamBlocklistScore = 3
+)
+
+// Terms blocked unless the admin sets their own list
+var defaultSpamBlocklist = []string{
+	"backlinks", "casino", "crypto", "seo services", "viagra", "web traffic",
+}
+
+var linkPattern = regexp.MustCompile(`(?i)(https?://|www\.)\S+`)
+
+// SpamFilterFromSettings builds a SpamFilter from the admin settings map.
+// spam_blocklist holds one term per line, replacing the default terms when
+// it isn't empty; spam_max_links and spam_threshold override the defaults
+// of 2 links and a score of 5.
+func SpamFilterFromSettings(settings map[string]string) *SpamFilter {
+	f := &SpamFilter{
+		Threshold:   5,
+		MinFillTime: 3 * time.Second,
+		MaxLinks:    2,
+		Blocklist:   defaultSpamBlocklist,
+	}
+
+	var blocklist []string
+	for _, line := range strings.Split(settings["spam_blocklist"], "\n") {
+		if term := strings.ToLower(strings.TrimSpace(line)); term != "" {
+			blocklist = append(blocklist, term)
+		}
+	}
+	if len(blocklist) > 0 {
+		f.Blocklist = blocklist
+	}
+	if v, err := strconv.Atoi(settings["spam_max_links"]); err == nil && v >= 0 {
+		f.MaxLinks = v
+	}
+	if v, err := strconv.Atoi(settings["spam_threshold"]); err == nil && v > 0 {
+		f.Threshold = v
+	}
+	return f
+}
+
+// Check scores a submission
+func (f *SpamFilter) Check(s Submission) Verdict {
+	var v Verdict
+	add := func(score int, reason string) {
+		v.Score += score
+		v.Reasons = append(v.Reasons, reason)
+	}
+
+	if strings.TrimSpace(s.Honeypot) != "" {
+		add(spamHoneypotScore, "hidden field filled in")
+	}
+
+	switch {
+	case !s.FillTimeKnown:
+		add(spamNoTimeScore, "no valid form timestamp")
+	case s.FillTime < f.MinFillTime:
+		add(spamTooFastScore, fmt.Sprintf("sent %.1fs after the form opened", s.FillTime.Seconds()))
+	}
+
+	text := strings.Join([]string{s.Name, s.Email, s.Subject, s.Message}, "\n")
+	if links := len(linkPattern.FindAllString(text, -1)); links > f.MaxLinks {
+		add(spamLinkScore*(links-f.MaxLinks), fmt.Sprintf("%d links", links))
+	}
+
+	lower := strings.ToLower(text)
+	for _, term := range f.Blocklist {
+		if strings.Contains(lower, term) {
+			add(spamBlocklistScore, fmt.Sprintf("blocked term %q", term))
+		}
+	}
+
+	v.Spam = v.Score >= f.Threshold
+	return v
+}
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSpamFilter_Check(t *testing.T) {
+	filter := SpamFilterFromSettings(map[string]string{})
+	human := Submission{
+		Name:          "Ana Diaz",
+		Email:         "ana@example.com",
+		Subject:       "Wedding in May",
+		Message:       "Hi! Are you free on 3 May? Our venue is https://example.com/venue",
+		FillTime:      45 * time.Second,
+		FillTimeKnown: true,
+	}
+
+	cases := []struct {
+		name      string
+		edit      func(s *Submission)
+		wantScore int
+		wantSpam  bool
+	}{
+		{"✅ ordinary enquiry", func(s *Submission) {}, 0, false},
+		{"❌ honeypot filled in", func(s *Submission) { s.Honeypot = "http://spam.example" }, 10, true},
+		{"❌ sent too fast", func(s *Submission) { s.FillTime = time.Second }, 5, true},
+		{"✅ no timestamp alone isn't spam", func(s *Submission) { s.FillTimeKnown = false }, 2, false},
+		{"✅ a couple of links", func(s *Submission) { s.Message += " and www.example.org" }, 0, false},
+		{"❌ many links", func(s *Submission) {
+			s.Message = "http://a.example http://b.example http://c.example www.d.example https://e.example"
+		}, 6, true},
+		{"✅ one blocked term", func(s *Submission) { s.Subject = "Casino night photos" }, 3, false},
+		{"❌ blocked terms add up", func(s *Submission) { s.Message = "Cheap BACKLINKS and SEO services" }, 6, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := human
+			tc.edit(&s)
+			v := filter.Check(s)
+			if v.Score != tc.wantScore || v.Spam != tc.wantSpam {
+				t.Errorf("Expected score %d (spam %v), got %d (spam %v): %s", tc.wantScore, tc.wantSpam, v.Score, v.Spam, v.Reason())
+			}
+		})
+	}
+}
+
+func TestSpamFilterFromSettings(t *testing.T) {
+	f := SpamFilterFromSettings(map[string]string{
+		"spam_blocklist": "  Wholesale \n\nreplica watches\n",
+		"spam_max_links": "0",
+		"spam_threshold": "3",
+	})
+
+	if len(f.Blocklist) != 2 || f.Blocklist[0] != "wholesale" || f.Blocklist[1] != "replica watches" {
+		t.Errorf("Unexpected blocklist %q", f.Blocklist)
+	}
+	if f.MaxLinks != 0 || f.Threshold != 3 {
+		t.Errorf("Expected 0 links and threshold 3, got %d and %d", f.MaxLinks, f.Threshold)
+	}
+
+	v := f.Check(Submission{Message: "Wholesale prices", FillTime: time.Minute, FillTimeKnown: true})
+	if !v.Spam {
+		t.Errorf("Expected custom terms and threshold to apply, got %+v", v)
+	}
+
+	// An empty list falls back to the built-in terms
+	if f := SpamFilterFromSettings(map[string]string{"spam_blocklist": "  \n"}); len(f.Blocklist) != len(defaultSpamBlocklist) {
+		t.Errorf("Expected the default blocklist, got %q", f.Blocklist)
+	}
+}
@@ -0,0 +1,191 @@
+package utils
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"strconv"
+	"strings"
+
+	"github.com/disintegration/imaging"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Sizes of the public renditions made of every upload: the long edge of the
+// full-size one and the width of the thumbnail. Markdown figures use them
+// for srcset, so they describe what's actually in storage.
+const (
+	DisplayMaxSize = 2400
+	ThumbWidth     = 500
+)
+
+// Watermark describes the mark stamped onto public image derivatives.
+// Either Text or Logo is used; a Logo takes precedence when both are set.
+type Watermark struct {
+	Text     string
+	Logo     image.Image
+	Position string  // top-left, top-right, bottom-left, bottom-right, center
+	Opacity  float64 // 0..1
+	Scale    float64 // fraction of the image width the mark spans
+}
+
+// WatermarkFromSettings builds a Watermark from the admin settings map.
+// It returns nil when watermarking is switched off. The logo image itself
+// lives in S3 and has to be loaded by the caller.
+func WatermarkFromSettings(settings map[string]string) *Watermark {
+	if settings["watermark_enabled"] != "true" {
+		return nil
+	}
+
+	wm := &Watermark{
+		Text:     strings.TrimSpace(settings["watermark_text"]),
+		Position: settings["watermark_position"],
+		Opacity:  0.5,
+		Scale:    0.25,
+	}
+
+	if v, err := strconv.Atoi(settings["watermark_opacity"]); err == nil && v > 0 && v <= 100 {
+		wm.Opacity = float64(v) / 100
+	}
+	if v, err := strconv.Atoi(settings["watermark_scale"]); err == nil && v > 0 && v <= 100 {
+		wm.Scale = float64(v) / 100
+	}
+	if wm.Position == "" {
+		wm.Position = "bottom-right"
+	}
+
+	return wm
+}
+
+// ApplyWatermark stamps wm onto img and returns the result. A nil or empty
+// watermark returns img untouched.
+func ApplyWatermark(img image.Image, wm *Watermark) image.Image {
+	if wm == nil {
+		return img
+	}
+
+	var mark image.Image
+	filter := imaging.Lanczos
+	if wm.Logo != nil {
+		mark = wm.Logo
+	} else if wm.Text != "" {
+		mark = textMark(wm.Text)
+		// The bitmap font looks best scaled without smoothing
+		filter = imaging.NearestNeighbor
+	} else {
+		return img
+	}
+
+	b := img.Bounds()
+	width := int(float64(b.Dx()) * wm.Scale)
+	if width < 1 {
+		return img
+	}
+	mark = imaging.Resize(mark, width, 0, filter)
+
+	margin := b.Dx() / 40
+	mb := mark.Bounds()
+
+	var pos image.Point
+	switch wm.Position {
+	case "top-left":
+		pos = image.Pt(margin, margin)
+	case "top-right":
+		pos = image.Pt(b.Dx()-mb.Dx()-margin, margin)
+	case "bottom-left":
+		pos = image.Pt(margin, b.Dy()-mb.Dy()-margin)
+	case "center":
+		pos = image.Pt((b.Dx()-mb.Dx())/2, (b.Dy()-mb.Dy())/2)
+	default:
+		pos = image.Pt(b.Dx()-mb.Dx()-margin, b.Dy()-mb.Dy()-margin)
+	}
+
+	return imaging.Overlay(img, mark, pos, wm.Opacity)
+}
+
+// WatermarkGIF renders every frame of an animated GIF fitted within maxSize
+// and stamped with wm, keeping the frame delays and loop count. Frames are
+// composited onto a canvas first so each output frame is a whole picture;
+// otherwise a frame that only redraws part of the image could cover the
+// watermark or leave a stale copy of it behind.
+func WatermarkGIF(g *gif.GIF, maxSize int, wm *Watermark) *gif.GIF {
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if bounds.Empty() && len(g.Image) > 0 {
+		bounds = g.Image[0].Bounds()
+	}
+	canvas := image.NewNRGBA(bounds)
+
+	out := &gif.GIF{LoopCount: g.LoopCount}
+	for i, frame := range g.Image {
+		var disposal byte
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		var previous *image.NRGBA
+		if disposal == gif.DisposalPrevious {
+			previous = imaging.Clone(canvas)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		rendered := ApplyWatermark(imaging.Fit(canvas, maxSize, maxSize, imaging.Lanczos), wm)
+
+		var delay int
+		if i < len(g.Delay) {
+			delay = g.Delay[i]
+		}
+		out.Image = append(out.Image, paletted(rendered, frame.Palette))
+		out.Delay = append(out.Delay, delay)
+		// Every output frame is whole, so each one replaces the last
+		out.Disposal = append(out.Disposal, gif.DisposalBackground)
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+
+	return out
+}
+
+// paletted maps img onto the frame's own palette, with white and black
+// added when there's room so a text watermark keeps its contrast.
+func paletted(img image.Image, p color.Palette) *image.Paletted {
+	pal := append(color.Palette{}, p...)
+	for _, c := range []color.Color{color.White, color.Black} {
+		if len(pal) < 256 {
+			pal = append(pal, c)
+		}
+	}
+
+	b := img.Bounds()
+	dst := image.NewPaletted(image.Rect(0, 0, b.Dx(), b.Dy()), pal)
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	return dst
+}
+
+// textMark renders text in white with a dark drop shadow so it stays
+// readable on both light and dark photos.
+func textMark(text string) image.Image {
+	face := basicfont.Face7x13
+	d := &font.Drawer{Face: face}
+	width := d.MeasureString(text).Ceil() + 1
+	height := face.Height + 1
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	d.Dst = dst
+
+	d.Src = image.NewUniform(color.NRGBA{0, 0, 0, 160})
+	d.Dot = fixed.P(1, face.Ascent+1)
+	d.DrawString(text)
+
+	d.Src = image.NewUniform(color.White)
+	d.Dot = fixed.P(0, face.Ascent)
+	d.DrawString(text)
+
+	return dst
+}
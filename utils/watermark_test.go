@@ -0,0 +1,196 @@
+package utils
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"io"
+	"testing"
+)
+
+func solid(w, h int, c color.Color) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+	return img
+}
+
+func TestWatermarkFromSettings(t *testing.T) {
+	cases := []struct {
+		name         string
+		settings     map[string]string
+		wantNil      bool
+		wantPosition string
+		wantOpacity  float64
+		wantScale    float64
+	}{
+		{"✅ disabled", map[string]string{"watermark_text": "© IKM"}, true, "", 0, 0},
+		{"✅ defaults", map[string]string{"watermark_enabled": "true"}, false, "bottom-right", 0.5, 0.25},
+		{"✅ custom", map[string]string{
+			"watermark_enabled":  "true",
+			"watermark_position": "top-left",
+			"watermark_opacity":  "80",
+			"watermark_scale":    "10",
+		}, false, "top-left", 0.8, 0.1},
+		{"✅ out of range values fall back", map[string]string{
+			"watermark_enabled": "true",
+			"watermark_opacity": "150",
+			"watermark_scale":   "0",
+		}, false, "bottom-right", 0.5, 0.25},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			wm := WatermarkFromSettings(tc.settings)
+			if (wm == nil) != tc.wantNil {
+				t.Fatalf("Expected nil %v, got %+v", tc.wantNil, wm)
+			}
+			if wm == nil {
+				return
+			}
+			if wm.Position != tc.wantPosition || wm.Opacity != tc.wantOpacity || wm.Scale != tc.wantScale {
+				t.Errorf("Expected %s at %.2f scaled %.2f, got %s at %.2f scaled %.2f",
+					tc.wantPosition, tc.wantOpacity, tc.wantScale, wm.Position, wm.Opacity, wm.Scale)
+			}
+		})
+	}
+}
+
+func TestApplyWatermark_Logo(t *testing.T) {
+	red := color.NRGBA{255, 0, 0, 255}
+	logo := solid(100, 50, red)
+
+	// On a 400x200 image a 0.25 scale logo is 100x50, with a 10px margin
+	cases := []struct {
+		name     string
+		position string
+		opacity  float64
+		inside   image.Point
+		outside  image.Point
+		wantRed  uint8
+	}{
+		{"✅ bottom-right", "bottom-right", 1, image.Pt(340, 165), image.Pt(60, 35), 255},
+		{"✅ top-left", "top-left", 1, image.Pt(60, 35), image.Pt(340, 165), 255},
+		{"✅ top-right", "top-right", 1, image.Pt(340, 35), image.Pt(60, 165), 255},
+		{"✅ bottom-left", "bottom-left", 1, image.Pt(60, 165), image.Pt(340, 35), 255},
+		{"✅ center", "center", 1, image.Pt(200, 100), image.Pt(60, 35), 255},
+		{"✅ half opacity", "bottom-right", 0.5, image.Pt(340, 165), image.Pt(60, 35), 128},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			wm := &Watermark{Logo: logo, Position: tc.position, Opacity: tc.opacity, Scale: 0.25}
+			out := image.NewNRGBA(image.Rect(0, 0, 400, 200))
+			draw.Draw(out, out.Bounds(), ApplyWatermark(solid(400, 200, color.Black), wm), image.Point{}, draw.Src)
+
+			got := out.NRGBAAt(tc.inside.X, tc.inside.Y)
+			if diff := int(got.R) - int(tc.wantRed); diff < -2 || diff > 2 || got.G != 0 {
+				t.Errorf("Expected red %d at %v, got %v", tc.wantRed, tc.inside, got)
+			}
+			if got := out.NRGBAAt(tc.outside.X, tc.outside.Y); got.R != 0 {
+				t.Errorf("Expected no mark at %v, got %v", tc.outside, got)
+			}
+		})
+	}
+}
+
+func TestApplyWatermark_Scale(t *testing.T) {
+	logo := solid(100, 50, color.White)
+	for _, scale := range []float64{0.1, 0.5} {
+		wm := &Watermark{Logo: logo, Position: "top-left", Opacity: 1, Scale: scale}
+		out := ApplyWatermark(solid(400, 200, color.Black), wm)
+
+		// The mark starts at the 10px margin and spans scale of the width
+		width := 0
+		for x := 10; x < 400; x++ {
+			if r, _, _, _ := out.At(x, 12).RGBA(); r>>8 < 128 {
+				break
+			}
+			width++
+		}
+		if want := int(400 * scale); width < want-1 || width > want+1 {
+			t.Errorf("Expected a %dpx wide mark at scale %.1f, got %dpx", want, scale, width)
+		}
+	}
+}
+
+func TestApplyWatermark_Text(t *testing.T) {
+	base := solid(400, 200, color.Black)
+
+	if out := ApplyWatermark(base, nil); out != image.Image(base) {
+		t.Error("Expected a nil watermark to leave the image untouched")
+	}
+	if out := ApplyWatermark(base, &Watermark{Opacity: 1, Scale: 0.25}); out != image.Image(base) {
+		t.Error("Expected an empty watermark to leave the image untouched")
+	}
+
+	out := ApplyWatermark(base, &Watermark{Text: "© IKM", Position: "top-left", Opacity: 1, Scale: 0.5})
+
+	// The text should light up pixels in the top-left quarter and nowhere else
+	var marked, stray int
+	b := out.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if r, _, _, _ := out.At(x, y).RGBA(); r>>8 > 200 {
+				if x < 220 && y < 100 {
+					marked++
+				} else {
+					stray++
+				}
+			}
+		}
+	}
+	if marked == 0 || stray != 0 {
+		t.Errorf("Expected text in the top-left only, got %d marked and %d stray pixels", marked, stray)
+	}
+}
+
+func TestWatermarkGIF(t *testing.T) {
+	transparent := color.NRGBA{}
+	pal := color.Palette{color.Black, color.NRGBA{255, 0, 0, 255}, color.NRGBA{0, 0, 255, 255}, transparent}
+
+	first := image.NewPaletted(image.Rect(0, 0, 40, 20), pal)
+	draw.Draw(first, first.Bounds(), image.NewUniform(color.Black), image.Point{}, draw.Src)
+
+	// The second frame only redraws a blue square in the corner
+	second := image.NewPaletted(image.Rect(0, 0, 10, 10), pal)
+	draw.Draw(second, second.Bounds(), image.NewUniform(pal[2]), image.Point{}, draw.Src)
+
+	g := &gif.GIF{
+		Image:     []*image.Paletted{first, second},
+		Delay:     []int{10, 20},
+		Disposal:  []byte{gif.DisposalNone, gif.DisposalNone},
+		LoopCount: 0,
+		Config:    image.Config{Width: 40, Height: 20},
+	}
+
+	wm := &Watermark{Logo: solid(100, 50, pal[1]), Position: "bottom-right", Opacity: 1, Scale: 0.25}
+	out := WatermarkGIF(g, 2400, wm)
+
+	if len(out.Image) != 2 || out.Delay[0] != 10 || out.Delay[1] != 20 {
+		t.Fatalf("Expected 2 frames with their delays, got %d frames %v", len(out.Image), out.Delay)
+	}
+	for i, frame := range out.Image {
+		if frame.Bounds() != image.Rect(0, 0, 40, 20) {
+			t.Errorf("Frame %d: expected a whole 40x20 frame, got %v", i, frame.Bounds())
+		}
+		if got := frame.At(34, 16); got != pal[1] {
+			t.Errorf("Frame %d: expected the watermark, got %v", i, got)
+		}
+	}
+	if got := out.Image[1].At(5, 5); got != pal[2] {
+		t.Errorf("Expected the second frame's square, got %v", got)
+	}
+	if got := out.Image[1].At(20, 10); got != pal[0] {
+		t.Errorf("Expected the first frame to show through, got %v", got)
+	}
+
+	if err := gif.EncodeAll(io.Discard, out); err != nil {
+		t.Errorf("Expected the result to encode, got %v", err)
+	}
+
+	small := WatermarkGIF(g, 20, nil)
+	if b := small.Image[0].Bounds(); b.Dx() != 20 || b.Dy() != 10 {
+		t.Errorf("Expected frames fitted to 20x10, got %v", b)
+	}
+}